@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"serverwatcher/service"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -19,28 +21,69 @@ func init() {
 		log.Println("failed to load persisted data:", err)
 	}
 
+	sqlStore, err := service.OpenSQLite("serverwatcher.db")
+	if err != nil {
+		log.Fatalf("failed to open sqlite: %v", err)
+	}
+	if err := sqlStore.Migrate(); err != nil {
+		log.Fatalf("failed to migrate sqlite: %v", err)
+	}
+	store.SetSQLStore(sqlStore)
+	sqlStore.StartRetention(service.RetentionFromEnv())
+	sqlStore.StartRollups()
+
 	services := store.GetAllServices()
 	log.Printf("Loaded %d services from file", len(services))
 
 	for _, svc := range services {
-		store.RestartChecker(svc)
+		if svc.Active {
+			store.RestartChecker(svc)
+		}
 	}
 
-	var notifs []notify.Notifier
+	var notifs []notify.Channel
 	if v := os.Getenv("SLACK_WEBHOOK_URL"); v != "" {
-		notifs = append(notifs, notify.Slack{WebhookURL: v})
+		notifs = append(notifs, notify.Channel{Name: "slack", Notifier: notify.Slack{WebhookURL: v}})
 	}
 	if v := os.Getenv("ALERT_WEBHOOK_URL"); v != "" {
-		notifs = append(notifs, notify.Webhook{URL: v})
+		notifs = append(notifs, notify.Channel{Name: "webhook", Notifier: notify.Webhook{URL: v, Secret: os.Getenv("ALERT_WEBHOOK_SECRET")}})
 	}
-	store.SetNotifiers(notifs)
-
-	sqlStore, err := service.OpenSQLite("serverwatcher.db")
-	if err != nil {
-		log.Fatalf("failed to open sqlite: %v", err)
+	if tok, chat := os.Getenv("TELEGRAM_BOT_TOKEN"), os.Getenv("TELEGRAM_CHAT_ID"); tok != "" && chat != "" {
+		tg := notify.Telegram{Token: tok, ChatID: chat, ParseMode: os.Getenv("TELEGRAM_PARSE_MODE")}
+		tg.ThreadID, _ = strconv.Atoi(os.Getenv("TELEGRAM_THREAD_ID"))
+		notifs = append(notifs, notify.Channel{Name: "telegram", Notifier: tg})
 	}
-	defer sqlStore.DB.Close()
-
+	if host, to := os.Getenv("SMTP_HOST"), os.Getenv("ALERT_EMAIL_TO"); host != "" && to != "" {
+		m := notify.Email{
+			Host:     host,
+			Security: os.Getenv("SMTP_SECURITY"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("ALERT_EMAIL_FROM"),
+			To:       notify.SplitList(to),
+		}
+		m.Port, _ = strconv.Atoi(os.Getenv("SMTP_PORT"))
+		notifs = append(notifs, notify.Channel{Name: "email", Notifier: m})
+	}
+	if v := os.Getenv("DISCORD_WEBHOOK_URL"); v != "" {
+		notifs = append(notifs, notify.Channel{Name: "discord", Notifier: notify.Discord{WebhookURL: v}})
+	}
+	if v := os.Getenv("TEAMS_WEBHOOK_URL"); v != "" {
+		notifs = append(notifs, notify.Channel{Name: "teams", Notifier: notify.Teams{WebhookURL: v}})
+	}
+	if v := os.Getenv("PAGERDUTY_ROUTING_KEY"); v != "" {
+		notifs = append(notifs, notify.Channel{Name: "pagerduty", Notifier: notify.PagerDuty{RoutingKey: v}})
+	}
+	if v := os.Getenv("OPSGENIE_API_KEY"); v != "" {
+		notifs = append(notifs, notify.Channel{Name: "opsgenie", Notifier: notify.Opsgenie{APIKey: v, BaseURL: os.Getenv("OPSGENIE_API_URL")}})
+	}
+	store.SetNotifiers(notifs)
+	store.SetDashboardURL(os.Getenv("SERVERWATCHER_DASHBOARD_URL"))
+	store.StartDeliveries()
+	store.StartReminders()
+	store.StartDigests()
+	store.StartEscalations()
+	store.StartBurnAlerts()
 }
 
 func PingHandler(w http.ResponseWriter, r *http.Request) {
@@ -56,12 +99,13 @@ func StatusHandler(w http.ResponseWriter, r *http.Request) {
 
 func AddServiceHandler(w http.ResponseWriter, r *http.Request) {
 	var data struct {
-		Name           string `json:"name"`
-		URL            string `json:"url"`
-		Interval       int    `json:"interval"`
-		TimeoutMs      int    `json:"timeoutMs"`
-		Retries        int    `json:"retries"`
-		RetryBackoffMs int    `json:"retryBackoffMs"`
+		Name           string   `json:"name"`
+		URL            string   `json:"url"`
+		Interval       int      `json:"interval"`
+		TimeoutMs      int      `json:"timeoutMs"`
+		Retries        int      `json:"retries"`
+		RetryBackoffMs int      `json:"retryBackoffMs"`
+		Tags           []string `json:"tags"` // e.g. ["team:payments","env:prod"]
 	}
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
 		http.Error(w, "invalid data", http.StatusBadRequest)
@@ -76,12 +120,46 @@ func AddServiceHandler(w http.ResponseWriter, r *http.Request) {
 		data.Retries,
 		data.RetryBackoffMs,
 	)
+	if len(data.Tags) > 0 {
+		_ = store.SetServiceTags(id, data.Tags)
+	}
 
 	_ = store.SaveToFile()
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]int{"id": id})
 }
 
+// POST /services/pause?id=1 stops checks until /services/resume?id=1.
+// Paused time is left out of SLOs.
+func PauseServiceHandler(w http.ResponseWriter, r *http.Request) {
+	setServiceActive(w, r, store.PauseService)
+}
+
+func ResumeServiceHandler(w http.ResponseWriter, r *http.Request) {
+	setServiceActive(w, r, store.ResumeService)
+}
+
+func setServiceActive(w http.ResponseWriter, r *http.Request, set func(int) error) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	if !store.HasService(id) {
+		http.Error(w, "service not found", http.StatusNotFound)
+		return
+	}
+	if err := set(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func DeleteServiceHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Query().Get("id")
 	id, err := strconv.Atoi(idStr)
@@ -95,42 +173,180 @@ func DeleteServiceHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// GET /services/history?id=1
+//
+//	&from=2025-08-01T00:00:00Z&to=2025-08-08T00:00:00Z  time range (RFC3339)
+//	&status=FAIL                                        only failures (or OK)
+//	&limit=500&cursor=...                               paging, next cursor in X-Next-Cursor
+//	&step=5m                                            aggregated points instead of checks
+//
+// Without any of these it returns the recent in-memory samples, as before.
 func ServiceHistoryHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	idStr := r.URL.Query().Get("id")
+	qs := r.URL.Query()
+	idStr := qs.Get("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		http.Error(w, "invalid id", 400)
 		return
 	}
-	history, ok := store.GetHistory(id)
-	if !ok {
+	if !store.HasService(id) {
 		http.Error(w, "service not found", 404)
 		return
 	}
+
+	ranged := false
+	for _, k := range []string{"from", "to", "status", "limit", "cursor", "step"} {
+		if qs.Has(k) {
+			ranged = true
+		}
+	}
+	if !ranged {
+		history, _ := store.GetHistory(id)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(history)
+		return
+	}
+
+	q := service.HistoryQuery{ServiceID: id, Cursor: qs.Get("cursor")}
+	if q.From, err = parseTimeParam(qs.Get("from")); err != nil {
+		http.Error(w, "invalid from (must be RFC3339)", http.StatusBadRequest)
+		return
+	}
+	if q.To, err = parseTimeParam(qs.Get("to")); err != nil {
+		http.Error(w, "invalid to (must be RFC3339)", http.StatusBadRequest)
+		return
+	}
+	if q.From.IsZero() {
+		q.From = time.Now().UTC().Add(-24 * time.Hour)
+	}
+	if !q.To.IsZero() && !q.To.After(q.From) {
+		http.Error(w, "to must be after from", http.StatusBadRequest)
+		return
+	}
+	switch st := strings.ToUpper(qs.Get("status")); st {
+	case "", "OK", "FAIL":
+		q.Status = st
+	default:
+		http.Error(w, "invalid status (OK or FAIL)", http.StatusBadRequest)
+		return
+	}
+	if ls := qs.Get("limit"); ls != "" {
+		l, err := strconv.Atoi(ls)
+		if err != nil || l <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		q.Limit = l
+	}
+
+	if ss := qs.Get("step"); ss != "" {
+		if q.Step, err = parseStep(ss); err != nil {
+			http.Error(w, "invalid step (e.g. 30s, 5m, 1h, 1d)", http.StatusBadRequest)
+			return
+		}
+		if q.Status != "" || q.Cursor != "" {
+			http.Error(w, "step cannot be combined with status or cursor", http.StatusBadRequest)
+			return
+		}
+		points, err := store.QueryHistoryPoints(q)
+		if err == service.ErrTooManyPoints || err == service.ErrNotRetained {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "failed to load history", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(points)
+		return
+	}
+
+	items, next, err := store.QueryHistory(q)
+	if err == service.ErrInvalidCursor {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to load history", http.StatusInternalServerError)
+		return
+	}
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(history)
+	_ = json.NewEncoder(w).Encode(items)
+}
+
+// parseTimeParam accepts RFC3339 / RFC3339Nano; empty gives the zero time.
+func parseTimeParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339Nano, v)
+}
+
+// parseStep is time.ParseDuration plus a "d" (day) suffix.
+func parseStep(v string) (time.Duration, error) {
+	if strings.HasSuffix(v, "d") {
+		n, err := strconv.Atoi(strings.TrimSuffix(v, "d"))
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid step %q", v)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < time.Second {
+		return 0, fmt.Errorf("invalid step %q", v)
+	}
+	return d, nil
 }
 
 func UpdateServiceHandler(w http.ResponseWriter, r *http.Request) {
 	var data struct {
-		ID             int    `json:"id"`
-		Name           string `json:"name"`
-		URL            string `json:"url"`
-		Interval       int    `json:"interval"`
-		TimeoutMs      int    `json:"timeoutMs"`
-		Retries        int    `json:"retries"`
-		RetryBackoffMs int    `json:"retryBackoffMs"`
+		ID             int       `json:"id"`
+		Name           string    `json:"name"`
+		URL            string    `json:"url"`
+		Interval       int       `json:"interval"`
+		TimeoutMs      int       `json:"timeoutMs"`
+		Retries        int       `json:"retries"`
+		RetryBackoffMs int       `json:"retryBackoffMs"`
+		Severity       *string   `json:"severity"` // optional default incident severity; "" clears it
+		Tags           *[]string `json:"tags"`     // optional; [] clears them
 	}
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
 		http.Error(w, "invalid data", 400)
 		return
 	}
+	if data.Severity != nil && *data.Severity != "" {
+		sev, ok := service.ParseSeverity(*data.Severity)
+		if !ok {
+			http.Error(w, "invalid severity (SEV1..SEV4)", 400)
+			return
+		}
+		data.Severity = &sev
+	}
 	err := store.UpdateService(data.ID, data.Name, data.URL, data.Interval, data.TimeoutMs, data.Retries, data.RetryBackoffMs)
 	if err != nil {
 		http.Error(w, err.Error(), 400)
 		return
 	}
+	if data.Severity != nil {
+		if err := store.SetServiceSeverity(data.ID, *data.Severity); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+	}
+	if data.Tags != nil {
+		if err := store.SetServiceTags(data.ID, *data.Tags); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+	}
 	store.SaveToFile()
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -286,30 +502,26 @@ func PolicyHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// GET /incidents/open
-// Response: array of service.Incident (only open), no name/url added.
+// GET /incidents/open?severity=SEV1,SEV2
+// Response: array of service.Incident (only open, automatic and declared),
+// no name/url added. severity is optional.
 func OpenIncidentsHandler(w http.ResponseWriter, r *http.Request) {
-	// CORS headers if you don't wrap globally
-	// w.Header().Set("Access-Control-Allow-Origin", "*")
-	// w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	// w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
 
-	out := make([]*service.Incident, 0, 8)
-	for _, svc := range store.GetAllServices() {
-		incs, ok := store.GetIncidents(svc.ID)
-		if !ok || len(incs) == 0 {
-			continue
-		}
-		last := incs[len(incs)-1]
-		if last.EndedAt == nil {
-			out = append(out, last)
+	var sevs []string
+	if v := r.URL.Query().Get("severity"); v != "" {
+		for _, part := range strings.Split(v, ",") {
+			sev, ok := service.ParseSeverity(part)
+			if !ok {
+				http.Error(w, "invalid severity (SEV1..SEV4)", http.StatusBadRequest)
+				return
+			}
+			sevs = append(sevs, sev)
 		}
 	}
-	_ = json.NewEncoder(w).Encode(out)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(store.OpenIncidents(sevs))
 }
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"serverwatcher/service"
+	"time"
+)
+
+// GET /admin/backup?history=true
+// Streams a gzipped JSON archive of the whole instance.
+func BackupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	b, err := store.Snapshot(r.URL.Query().Get("history") == "true")
+	if err != nil {
+		log.Println("backup failed:", err)
+		http.Error(w, "backup failed", http.StatusInternalServerError)
+		return
+	}
+	name := fmt.Sprintf("serverwatcher-backup-%s.json.gz", b.CreatedAt.Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	if err := service.WriteBackup(w, b); err != nil {
+		log.Println("backup write failed:", err)
+	}
+}
+
+// POST /admin/restore?dryRun=true
+// Body: archive from /admin/backup (gzipped or plain JSON).
+// Response: service.RestorePlan
+func RestoreHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	const maxArchive = 1 << 30
+	b, err := service.ReadBackup(http.MaxBytesReader(w, r.Body, maxArchive))
+	if err != nil {
+		http.Error(w, "invalid archive: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	start := time.Now()
+	plan, err := store.Restore(b, r.URL.Query().Get("dryRun") == "true")
+	if err != nil {
+		log.Println("restore failed:", err)
+		http.Error(w, "restore failed", http.StatusInternalServerError)
+		return
+	}
+	if !plan.DryRun {
+		log.Printf("restored backup from %s: %d services, %d incidents in %s",
+			b.CreatedAt.Format(time.RFC3339), len(plan.ServicesCreated), plan.Incidents, time.Since(start))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(plan)
+}
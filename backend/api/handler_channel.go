@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"serverwatcher/notify"
+	"serverwatcher/service"
+	"strconv"
+)
+
+type channelInput struct {
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	Settings  map[string]string `json:"settings"`
+	Templates map[string]string `json:"templates"`
+	TimeoutMs int               `json:"timeoutMs"`
+	Disabled  bool              `json:"disabled"`
+}
+
+func (in channelInput) channel() service.NotificationChannel {
+	return service.NotificationChannel{
+		Name:      in.Name,
+		Type:      in.Type,
+		Settings:  in.Settings,
+		Templates: in.Templates,
+		TimeoutMs: in.TimeoutMs,
+		Disabled:  in.Disabled,
+	}
+}
+
+func writeChannel(w http.ResponseWriter, c service.NotificationChannel, err error) {
+	if errors.Is(err, service.ErrChannelNotFound) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrChannelInUse) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(c)
+}
+
+// GET /notifications/channels        -> all channels
+// GET /notifications/channels?id=3   -> one channel
+func ListChannelsHandler(w http.ResponseWriter, r *http.Request) {
+	if idStr := r.URL.Query().Get("id"); idStr != "" {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		c, err := store.GetChannel(id)
+		writeChannel(w, c, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"types":    notify.Types(),
+		"channels": store.ListChannels(),
+	})
+}
+
+// POST /notifications/channels/add
+func CreateChannelHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var in channelInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	c, err := store.CreateChannel(in.channel())
+	if err == nil {
+		w.WriteHeader(http.StatusCreated)
+	}
+	writeChannel(w, c, err)
+}
+
+// PUT /notifications/channels/update?id=3
+func UpdateChannelHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	var in channelInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	c, err := store.UpdateChannel(id, in.channel())
+	writeChannel(w, c, err)
+}
+
+// DELETE /notifications/channels/delete?id=3
+// 409 while routes, escalation steps, schedules or the digest still use it;
+// renaming it through update is refused the same way.
+func DeleteChannelHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	err = store.DeleteChannel(id)
+	switch {
+	case errors.Is(err, service.ErrChannelNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /notifications/channels/test?id=3 sends a sample alert right away.
+func TestChannelHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	err = store.TestChannel(r.Context(), id)
+	switch {
+	case errors.Is(err, service.ErrChannelNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+	case err != nil:
+		http.Error(w, "test failed: "+err.Error(), http.StatusBadGateway)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// POST /notifications/templates/preview renders a message against a sample
+// event. Body: {"type":"DOWN","template":"{{.ServiceName}} down @oncall"}
+// or {"type":"UP","channelId":3} to preview a channel's saved templates.
+func PreviewTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var in struct {
+		Type      notify.EventType `json:"type"`
+		Template  string           `json:"template"`
+		ChannelID int              `json:"channelId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if in.Type == "" {
+		in.Type = notify.EventDown
+	}
+	e, err := store.PreviewTemplate(in.ChannelID, in.Type, in.Template)
+	if errors.Is(err, service.ErrChannelNotFound) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"title": e.Title(),
+		"text":  e.Text(),
+		"event": e,
+	})
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"serverwatcher/service"
+	"strconv"
+)
+
+func deliveryLimit(r *http.Request) int {
+	limit := 100
+	if ls := r.URL.Query().Get("limit"); ls != "" {
+		if l, err := strconv.Atoi(ls); err == nil && l > 0 && l <= 1000 {
+			limit = l
+		}
+	}
+	return limit
+}
+
+func writeDeliveries(w http.ResponseWriter, out []service.Delivery, err error) {
+	if err == service.ErrNoDeliveryQueue {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to load deliveries", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// GET /notifications/queue?limit=100
+func PendingDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	out, err := store.PendingDeliveries(deliveryLimit(r))
+	writeDeliveries(w, out, err)
+}
+
+// GET /notifications/dead?limit=100
+func DeadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	out, err := store.DeadLetters(deliveryLimit(r))
+	writeDeliveries(w, out, err)
+}
+
+// POST /notifications/dead/replay?id=3
+func ReplayDeadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	deadLetterAction(w, r, http.MethodPost, store.ReplayDeadLetter)
+}
+
+// DELETE /notifications/dead/delete?id=3
+func DeleteDeadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	deadLetterAction(w, r, http.MethodDelete, store.DeleteDeadLetter)
+}
+
+func deadLetterAction(w http.ResponseWriter, r *http.Request, method string, fn func(int64) error) {
+	if r.Method != method {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	switch err := fn(id); err {
+	case nil:
+		w.WriteHeader(http.StatusNoContent)
+	case service.ErrDeliveryNotFound:
+		http.Error(w, "not found", http.StatusNotFound)
+	case service.ErrNoDeliveryQueue:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, "failed", http.StatusInternalServerError)
+	}
+}
+
+// GET /notifications/log?incidentId=12&serviceId=3&channel=slack&limit=100
+// Every delivery attempt, newest first. Without SQLite only the most
+// recent attempts since startup are kept.
+func DeliveryLogHandler(w http.ResponseWriter, r *http.Request) {
+	q := service.DeliveryLogQuery{
+		Channel: r.URL.Query().Get("channel"),
+		Limit:   deliveryLimit(r),
+	}
+	for key, dst := range map[string]*int{"incidentId": &q.IncidentID, "serviceId": &q.ServiceID} {
+		if v := r.URL.Query().Get(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, "invalid "+key, http.StatusBadRequest)
+				return
+			}
+			*dst = n
+		}
+	}
+	out, err := store.DeliveryLog(q)
+	if err != nil {
+		http.Error(w, "failed to load delivery log", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"serverwatcher/service"
+	"time"
+)
+
+// GET /notifications/digest         -> digest config
+// PUT /notifications/digest/update  body: DigestConfig
+func DigestConfigHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(store.GetDigestConfig())
+
+	case http.MethodPut:
+		var c service.DigestConfig
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if err := store.SetDigestConfig(c); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(store.GetDigestConfig())
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET /notifications/digest/preview -> the last completed period's digest
+func DigestPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	d := store.PreviewDigest(time.Now())
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"digest": d, "text": d.Text()})
+}
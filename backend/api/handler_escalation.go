@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"serverwatcher/service"
+	"strconv"
+)
+
+func writeEscalation(w http.ResponseWriter, p service.EscalationPolicy, err error) {
+	if errors.Is(err, service.ErrEscalationNotFound) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// GET /escalations
+func ListEscalationsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(store.ListEscalationPolicies())
+}
+
+// POST /escalations/add
+// Body: {"name":"api","serviceIds":[1],"repeat":1,
+//
+//	"steps":[{"afterMin":0,"channels":["slack"]},{"afterMin":15,"channels":["pagerduty"]}]}
+func CreateEscalationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var in service.EscalationPolicy
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	p, err := store.CreateEscalationPolicy(in)
+	if err == nil {
+		w.WriteHeader(http.StatusCreated)
+	}
+	writeEscalation(w, p, err)
+}
+
+// PUT /escalations/update?id=2
+func UpdateEscalationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	var in service.EscalationPolicy
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	p, err := store.UpdateEscalationPolicy(id, in)
+	writeEscalation(w, p, err)
+}
+
+// DELETE /escalations/delete?id=2
+func DeleteEscalationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	if err := store.DeleteEscalationPolicy(id); err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"serverwatcher/service"
+	"strconv"
+)
+
+func incidentID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+func writeIncident(w http.ResponseWriter, inc service.Incident, err error) {
+	switch {
+	case errors.Is(err, service.ErrIncidentNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrIncidentResolved):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(inc)
+}
+
+// GET /incidents/get?id=12
+func GetIncidentHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := incidentID(w, r)
+	if !ok {
+		return
+	}
+	inc, err := store.GetIncident(id)
+	writeIncident(w, inc, err)
+}
+
+// POST /incidents/ack?id=12  body: {"by":"alice"}
+func AckIncidentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ok := incidentID(w, r)
+	if !ok {
+		return
+	}
+	var in struct {
+		By string `json:"by"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+	}
+	inc, err := store.AcknowledgeIncident(id, in.By)
+	writeIncident(w, inc, err)
+}
+
+// POST /incidents/assign?id=12  body: {"assignee":"bob"}
+func AssignIncidentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ok := incidentID(w, r)
+	if !ok {
+		return
+	}
+	var in struct {
+		Assignee string `json:"assignee"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	inc, err := store.AssignIncident(id, in.Assignee)
+	writeIncident(w, inc, err)
+}
+
+// POST /incidents/notes/add?id=12  body: {"author":"alice","text":"rolled back deploy"}
+func AddIncidentNoteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ok := incidentID(w, r)
+	if !ok {
+		return
+	}
+	var in struct {
+		Author string `json:"author"`
+		Text   string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	inc, err := store.AddIncidentNote(id, in.Author, in.Text)
+	writeIncident(w, inc, err)
+}
+
+// POST /incidents/declare
+// Body: {"serviceId":3,"severity":"SEV2","title":"Checkout errors","impact":"10% of payments fail","by":"alice"}
+// or {"group":"team:payments",...} for every service with that tag.
+func DeclareIncidentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var in service.IncidentDeclaration
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	inc, err := store.DeclareIncident(in)
+	if err == nil {
+		w.WriteHeader(http.StatusCreated)
+	}
+	writeIncident(w, inc, err)
+}
+
+// POST /incidents/resolve?id=12  body: {"by":"alice"} (declared incidents only)
+func ResolveIncidentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ok := incidentID(w, r)
+	if !ok {
+		return
+	}
+	var in struct {
+		By string `json:"by"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+	}
+	inc, err := store.ResolveIncident(id, in.By)
+	if errors.Is(err, service.ErrIncidentNotManual) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeIncident(w, inc, err)
+}
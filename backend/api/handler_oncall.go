@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"serverwatcher/service"
+	"strconv"
+	"time"
+)
+
+func writeSchedule(w http.ResponseWriter, sc service.Schedule, err error) {
+	if errors.Is(err, service.ErrScheduleNotFound) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(sc)
+}
+
+// GET /schedules        -> all schedules
+// GET /schedules?id=2   -> one schedule
+func ListSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	if idStr := r.URL.Query().Get("id"); idStr != "" {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		sc, err := store.GetSchedule(id)
+		writeSchedule(w, sc, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(store.ListSchedules())
+}
+
+// POST /schedules/add
+// Body: {"name":"backend","timezone":"Europe/Berlin","rotation":"weekly",
+//
+//	"startDate":"2026-01-05","handoff":"09:00","users":["ana","ben"],
+//	"contacts":{"ana":["ana-sms"],"ben":["ben-sms"],"cara":["cara-sms"]}}
+//
+// Every user, and anyone taking an override, needs contacts.
+func CreateScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var in service.Schedule
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	sc, err := store.CreateSchedule(in)
+	if err == nil {
+		w.WriteHeader(http.StatusCreated)
+	}
+	writeSchedule(w, sc, err)
+}
+
+// PUT /schedules/update?id=2
+func UpdateScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	var in service.Schedule
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	sc, err := store.UpdateSchedule(id, in)
+	if errors.Is(err, service.ErrScheduleInUse) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeSchedule(w, sc, err)
+}
+
+// DELETE /schedules/delete?id=2
+func DeleteScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	err = store.DeleteSchedule(id)
+	switch {
+	case errors.Is(err, service.ErrScheduleNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /schedules/override?id=2
+// Body: {"user":"cara","start":"2026-03-01T18:00:00Z","end":"2026-03-02T09:00:00Z"}
+func AddScheduleOverrideHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	var in service.ScheduleOverride
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	sc, err := store.AddScheduleOverride(id, in)
+	writeSchedule(w, sc, err)
+}
+
+// GET /oncall?team=backend&at=2026-03-01T20:00:00Z
+// team is optional (all schedules); at defaults to now.
+func OnCallHandler(w http.ResponseWriter, r *http.Request) {
+	at := time.Now()
+	if v := r.URL.Query().Get("at"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "invalid at (RFC3339)", http.StatusBadRequest)
+			return
+		}
+		at = t
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(store.OnCallAt(r.URL.Query().Get("team"), at))
+}
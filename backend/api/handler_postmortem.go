@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"serverwatcher/service"
+)
+
+func writePostmortem(w http.ResponseWriter, pm service.Postmortem, err error) {
+	switch {
+	case errors.Is(err, service.ErrIncidentNotFound), errors.Is(err, service.ErrPostmortemNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrIncidentOpen), errors.Is(err, service.ErrPostmortemExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(pm)
+}
+
+// GET /incidents/postmortem?id=12
+func GetPostmortemHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := incidentID(w, r)
+	if !ok {
+		return
+	}
+	pm, err := store.GetPostmortem(id)
+	writePostmortem(w, pm, err)
+}
+
+// GET  /incidents/postmortem/draft?id=12 -> generated draft, not saved
+// POST /incidents/postmortem/draft?id=12 -> attach the draft to the incident
+func PostmortemDraftHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := incidentID(w, r)
+	if !ok {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		pm, err := store.DraftPostmortem(id)
+		writePostmortem(w, pm, err)
+	case http.MethodPost:
+		pm, err := store.CreatePostmortem(id)
+		if err == nil {
+			w.WriteHeader(http.StatusCreated)
+		}
+		writePostmortem(w, pm, err)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// PUT /incidents/postmortem/update?id=12
+// Body: {"status":"review","summary":"...","rootCause":"...",
+//
+//	"actionItems":[{"text":"add readiness probe","owner":"bob","due":"2026-11-01"}],
+//	"timeline":[{"at":"2026-10-19T10:02:00Z","text":"deploy 4711 rolled out"}]}
+func UpdatePostmortemHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ok := incidentID(w, r)
+	if !ok {
+		return
+	}
+	var in service.Postmortem
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	pm, err := store.UpdatePostmortem(id, in)
+	writePostmortem(w, pm, err)
+}
+
+// GET /incidents/postmortem/export?id=12 -> Markdown document
+func ExportPostmortemHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := incidentID(w, r)
+	if !ok {
+		return
+	}
+	md, err := store.ExportPostmortem(id)
+	if err != nil {
+		writePostmortem(w, service.Postmortem{}, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="postmortem-%d.md"`, id))
+	_, _ = w.Write([]byte(md))
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"serverwatcher/notify"
+	"serverwatcher/service"
+	"time"
+)
+
+// GET /routing  -> rules in evaluation order
+// PUT /routing/update  body: [RouteRule, ...] replaces all rules
+func RoutingHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(store.GetRoutes())
+
+	case http.MethodPut:
+		var rules []service.RouteRule
+		if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if err := store.SetRoutes(rules); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(store.GetRoutes())
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// POST /routing/test shows where a hypothetical event would go.
+// Body: {"serviceId":3,"tags":["team:payments"],"severity":"critical","type":"DOWN","at":"2025-01-01T22:00:00Z"}
+// Tags default to the service's tags; at defaults to now.
+func TestRouteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var in struct {
+		ServiceID int              `json:"serviceId"`
+		Tags      []string         `json:"tags"`
+		Severity  notify.Severity  `json:"severity"`
+		Type      notify.EventType `json:"type"`
+		At        string           `json:"at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	e := notify.Event{
+		ServiceID:  in.ServiceID,
+		Tags:       in.Tags,
+		Severity:   in.Severity,
+		Type:       in.Type,
+		OccurredAt: time.Now().UTC(),
+	}
+	if e.Severity == "" {
+		e.Severity = notify.SeverityCritical
+	}
+	if e.Type == "" {
+		e.Type = notify.EventDown
+	}
+	if !e.Severity.Valid() || !e.Type.Valid() {
+		http.Error(w, "invalid severity or type", http.StatusBadRequest)
+		return
+	}
+	if in.At != "" {
+		t, err := time.Parse(time.RFC3339, in.At)
+		if err != nil {
+			http.Error(w, "invalid at", http.StatusBadRequest)
+			return
+		}
+		e.OccurredAt = t
+	}
+	if svc := store.GetService(in.ServiceID); svc != nil {
+		e.ServiceName = svc.Name
+		if e.Tags == nil {
+			e.Tags = svc.Tags
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(store.Route(e))
+}
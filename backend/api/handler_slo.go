@@ -3,44 +3,137 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"serverwatcher/service"
 	"strconv"
+	"time"
 )
 
+// GET /services/slo?id=1&hours=720&sli=events
+// sli defaults to the service's SLIKind ("time" unless set).
 func ServiceSLOHandler(w http.ResponseWriter, r *http.Request) {
-	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	if !store.HasService(id) {
+		http.Error(w, "service not found", http.StatusNotFound)
+		return
+	}
 	hours := 720 // default 30d
 	if s := r.URL.Query().Get("hours"); s != "" {
 		if v, err := strconv.Atoi(s); err == nil && v > 0 {
 			hours = v
 		}
 	}
+	var kind service.SLIKind
+	if s := r.URL.Query().Get("sli"); s != "" {
+		k, ok := service.ParseSLIKind(s)
+		if !ok {
+			http.Error(w, "invalid sli (time or events)", http.StatusBadRequest)
+			return
+		}
+		kind = k
+	}
 
-	a := store.ComputeAnalytics(id, hours)  // you already have analytics
-	target := store.GetServiceSLOTarget(id) // returns default if unset (e.g., 99.9)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store.ComputeSLO(id, hours, kind))
+}
 
-	// error budget math
-	// budget = allowed-downtime = (1 - target/100) * window_seconds
-	windowSec := hours * 3600
-	allowedDown := float64(windowSec) * (1 - target/100.0)
+// PUT /services/slo/update
+// {"id":1,"sloTargetPercent":99.9,"sliKind":"events","latencySloMs":800,"latencySloTarget":99}
+// Fields left out keep their current value; latencySloMs 0 disables the
+// latency SLO.
+func UpdateServiceSLOHandler(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		ID int `json:"id"`
+		service.SLOUpdate
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid data", http.StatusBadRequest)
+		return
+	}
+	if !store.HasService(in.ID) {
+		http.Error(w, "service not found", http.StatusNotFound)
+		return
+	}
+	if err := store.UpdateServiceSLO(in.ID, in.SLOUpdate); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_ = store.SaveToFile()
+	w.WriteHeader(http.StatusNoContent)
+}
 
-	// observed down (approx): failCount * intervalSec (rough)
-	observedDown := store.EstimateDowntimeSeconds(id, hours)
+// GET /services/slo/alerts?id=1&openOnly=true
+// id is optional; without it alerts of every service are listed.
+func ServiceBurnAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	id := 0
+	if s := r.URL.Query().Get("id"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		id = v
+	}
+	openOnly := r.URL.Query().Get("openOnly") == "true"
 
-	// burn rate = observedDown / allowedDown
-	br := 0.0
-	if allowedDown > 0 {
-		br = observedDown / allowedDown
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store.ListBurnAlerts(id, openOnly))
+}
+
+// GET /services/slo/budget?id=1&period=month&tz=Europe/Berlin&at=2025-08
+// period: month (default) or quarter; at: any date in the period
+// (YYYY-MM, YYYY-MM-DD or RFC3339, default now); tz defaults to UTC.
+func ServiceErrorBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	id, err := strconv.Atoi(qs.Get("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	if !store.HasService(id) {
+		http.Error(w, "service not found", http.StatusNotFound)
+		return
+	}
+	loc := time.UTC
+	if tz := qs.Get("tz"); tz != "" {
+		if loc, err = time.LoadLocation(tz); err != nil {
+			http.Error(w, "invalid tz", http.StatusBadRequest)
+			return
+		}
+	}
+	at := time.Now().In(loc)
+	if v := qs.Get("at"); v != "" {
+		if at, err = parseDateIn(v, loc); err != nil {
+			http.Error(w, "invalid at (YYYY-MM, YYYY-MM-DD or RFC3339)", http.StatusBadRequest)
+			return
+		}
 	}
+	period := qs.Get("period")
+	if period == "" {
+		period = service.PeriodMonth
+	}
+
+	rep, err := store.ErrorBudgetReport(id, period, at)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rep)
+}
 
-	out := map[string]any{
-		"serviceId":           id,
-		"target":              target,
-		"windowHours":         hours,
-		"analytics":           a,
-		"allowedDowntimeSec":  int(allowedDown),
-		"observedDowntimeSec": int(observedDown),
-		"burnRate":            br, // 1.0 == consuming budget at expected pace
-		"breached":            a.UptimePercent < target,
+func parseDateIn(v string, loc *time.Location) (time.Time, error) {
+	for _, layout := range []string{"2006-01", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+			return t, nil
+		}
+	}
+	t, err := parseTimeParam(v)
+	if err != nil {
+		return time.Time{}, err
 	}
-	json.NewEncoder(w).Encode(out)
+	return t.In(loc), nil
 }
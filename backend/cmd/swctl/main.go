@@ -0,0 +1,131 @@
+// Command swctl talks to a running serverwatcher instance.
+//
+//	swctl backup  [-server URL] [-history] [-o FILE]
+//	swctl restore [-server URL] [-dry-run] FILE
+//
+// The API key is read from SERVERWATCHER_API_KEY.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: swctl backup [-server URL] [-history] [-o FILE]")
+	fmt.Fprintln(os.Stderr, "       swctl restore [-server URL] [-dry-run] FILE")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "backup":
+		err = backup(os.Args[2:])
+	case "restore":
+		err = restore(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "swctl:", err)
+		os.Exit(1)
+	}
+}
+
+func backup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	server := fs.String("server", "http://localhost:8080", "serverwatcher base URL")
+	history := fs.Bool("history", false, "include check history")
+	out := fs.String("o", "", "output file (default serverwatcher-backup-<time>.json.gz)")
+	fs.Parse(args)
+
+	url := *server + "/admin/backup"
+	if *history {
+		url += "?history=true"
+	}
+	resp, err := do(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	name := *out
+	if name == "" {
+		name = fmt.Sprintf("serverwatcher-backup-%s.json.gz", time.Now().UTC().Format("20060102-150405"))
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, resp.Body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s (%d bytes)\n", name, n)
+	return nil
+}
+
+func restore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	server := fs.String("server", "http://localhost:8080", "serverwatcher base URL")
+	dryRun := fs.Bool("dry-run", false, "only show what would be restored")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	b, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	url := *server + "/admin/restore"
+	if *dryRun {
+		url += "?dryRun=true"
+	}
+	resp, err := do(http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var plan map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&plan); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(plan)
+}
+
+func do(method, url string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if k := os.Getenv("SERVERWATCHER_API_KEY"); k != "" {
+		req.Header.Set("X-API-Key", k)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: %d %s", method, url, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return resp, nil
+}
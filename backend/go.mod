@@ -2,4 +2,4 @@ module serverwatcher
 
 go 1.24.4
 
-require github.com/mattn/go-sqlite3 v1.14.32 // indirect
+require github.com/mattn/go-sqlite3 v1.14.32
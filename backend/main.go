@@ -14,6 +14,7 @@ func withCORS(next http.HandlerFunc) http.HandlerFunc {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, PUT, OPTIONS")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Next-Cursor")
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusNoContent)
 			return
@@ -49,6 +50,10 @@ func main() {
 
 	http.HandleFunc("/services/incidents", withCORS(api.ServiceIncidentHandler))
 	http.HandleFunc("/services/analytics", withCORS(api.ServiceAnalyticsHandler))
+	http.HandleFunc("/services/slo", withCORS(api.ServiceSLOHandler))
+	http.HandleFunc("/services/slo/alerts", withCORS(api.ServiceBurnAlertsHandler))
+	http.HandleFunc("/services/slo/budget", withCORS(api.ServiceErrorBudgetHandler))
+	http.HandleFunc("/silences", withCORS(api.ListSilencesHandler))
 	http.HandleFunc("/incidents/open", withCORS(api.OpenIncidentsHandler))
 	http.HandleFunc("/policy", withCORS(api.PolicyHandler)) // GET allowed w/o key
 
@@ -56,8 +61,64 @@ func main() {
 	http.HandleFunc("/services/add", withCORS(requireAPIKey(api.AddServiceHandler)))
 	http.HandleFunc("/services/update", withCORS(requireAPIKey(api.UpdateServiceHandler)))
 	http.HandleFunc("/services/delete", withCORS(requireAPIKey(api.DeleteServiceHandler)))
+	http.HandleFunc("/services/pause", withCORS(requireAPIKey(api.PauseServiceHandler)))
+	http.HandleFunc("/services/resume", withCORS(requireAPIKey(api.ResumeServiceHandler)))
+	http.HandleFunc("/services/slo/update", withCORS(requireAPIKey(api.UpdateServiceSLOHandler)))
+	http.HandleFunc("/silences/add", withCORS(requireAPIKey(api.CreateSilenceHandler)))
+	http.HandleFunc("/silences/delete", withCORS(requireAPIKey(api.DeleteSilenceHandler)))
+	http.HandleFunc("/incidents/declare", withCORS(requireAPIKey(api.DeclareIncidentHandler)))
+	http.HandleFunc("/incidents/resolve", withCORS(requireAPIKey(api.ResolveIncidentHandler)))
+	http.HandleFunc("/incidents/ack", withCORS(requireAPIKey(api.AckIncidentHandler)))
+	http.HandleFunc("/incidents/assign", withCORS(requireAPIKey(api.AssignIncidentHandler)))
+	http.HandleFunc("/incidents/notes/add", withCORS(requireAPIKey(api.AddIncidentNoteHandler)))
+	http.HandleFunc("/incidents/get", withCORS(requireAPIKey(api.GetIncidentHandler))) // notes, impact, postmortem
+	http.HandleFunc("/incidents/postmortem", withCORS(requireAPIKey(api.GetPostmortemHandler)))
+	http.HandleFunc("/incidents/postmortem/draft", withCORS(requireAPIKey(api.PostmortemDraftHandler)))
+	http.HandleFunc("/incidents/postmortem/update", withCORS(requireAPIKey(api.UpdatePostmortemHandler)))
+	http.HandleFunc("/incidents/postmortem/export", withCORS(requireAPIKey(api.ExportPostmortemHandler)))
 	// Policy updates protected
 	http.HandleFunc("/policy/update", withCORS(requireAPIKey(api.PolicyHandler))) // PUT handled in PolicyHandler
 
+	// Notification channels (protected; settings may hold secrets)
+	http.HandleFunc("/notifications/channels", withCORS(requireAPIKey(api.ListChannelsHandler)))
+	http.HandleFunc("/notifications/channels/add", withCORS(requireAPIKey(api.CreateChannelHandler)))
+	http.HandleFunc("/notifications/channels/update", withCORS(requireAPIKey(api.UpdateChannelHandler)))
+	http.HandleFunc("/notifications/channels/delete", withCORS(requireAPIKey(api.DeleteChannelHandler)))
+	http.HandleFunc("/notifications/channels/test", withCORS(requireAPIKey(api.TestChannelHandler)))
+	http.HandleFunc("/notifications/templates/preview", withCORS(requireAPIKey(api.PreviewTemplateHandler)))
+	http.HandleFunc("/notifications/digest", withCORS(requireAPIKey(api.DigestConfigHandler)))
+	http.HandleFunc("/notifications/digest/update", withCORS(requireAPIKey(api.DigestConfigHandler)))
+	http.HandleFunc("/notifications/digest/preview", withCORS(requireAPIKey(api.DigestPreviewHandler)))
+
+	// Alert routing (protected)
+	http.HandleFunc("/routing", withCORS(requireAPIKey(api.RoutingHandler)))
+	http.HandleFunc("/routing/update", withCORS(requireAPIKey(api.RoutingHandler)))
+	http.HandleFunc("/routing/test", withCORS(requireAPIKey(api.TestRouteHandler)))
+
+	// Escalation policies (protected)
+	http.HandleFunc("/escalations", withCORS(requireAPIKey(api.ListEscalationsHandler)))
+	http.HandleFunc("/escalations/add", withCORS(requireAPIKey(api.CreateEscalationHandler)))
+	http.HandleFunc("/escalations/update", withCORS(requireAPIKey(api.UpdateEscalationHandler)))
+	http.HandleFunc("/escalations/delete", withCORS(requireAPIKey(api.DeleteEscalationHandler)))
+
+	// On-call schedules (protected)
+	http.HandleFunc("/schedules", withCORS(requireAPIKey(api.ListSchedulesHandler)))
+	http.HandleFunc("/schedules/add", withCORS(requireAPIKey(api.CreateScheduleHandler)))
+	http.HandleFunc("/schedules/update", withCORS(requireAPIKey(api.UpdateScheduleHandler)))
+	http.HandleFunc("/schedules/delete", withCORS(requireAPIKey(api.DeleteScheduleHandler)))
+	http.HandleFunc("/schedules/override", withCORS(requireAPIKey(api.AddScheduleOverrideHandler)))
+	http.HandleFunc("/oncall", withCORS(requireAPIKey(api.OnCallHandler)))
+
+	// Notification delivery queue (protected)
+	http.HandleFunc("/notifications/queue", withCORS(requireAPIKey(api.PendingDeliveriesHandler)))
+	http.HandleFunc("/notifications/dead", withCORS(requireAPIKey(api.DeadLettersHandler)))
+	http.HandleFunc("/notifications/dead/replay", withCORS(requireAPIKey(api.ReplayDeadLetterHandler)))
+	http.HandleFunc("/notifications/dead/delete", withCORS(requireAPIKey(api.DeleteDeadLetterHandler)))
+	http.HandleFunc("/notifications/log", withCORS(requireAPIKey(api.DeliveryLogHandler)))
+
+	// Backup / restore (protected)
+	http.HandleFunc("/admin/backup", withCORS(requireAPIKey(api.BackupHandler)))
+	http.HandleFunc("/admin/restore", withCORS(requireAPIKey(api.RestoreHandler)))
+
 	log.Fatal(http.ListenAndServe(":8080", nil))
 }
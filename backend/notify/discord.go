@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Discord posts an embed to a channel webhook.
+type Discord struct {
+	WebhookURL string
+	Username   string // overrides the webhook's default name
+}
+
+func (d Discord) Notify(ctx context.Context, e Event) error {
+	var fields []map[string]any
+	for _, f := range e.Facts() {
+		fields = append(fields, map[string]any{
+			"name":   f.Name,
+			"value":  truncate(f.Value, 1024),
+			"inline": len(f.Value) < 40,
+		})
+	}
+	embed := map[string]any{
+		"title":     truncate(e.Title(), 256),
+		"color":     hexColor(e.Color()),
+		"fields":    fields,
+		"timestamp": e.OccurredAt.Format(time.RFC3339),
+	}
+	if d := e.Detail(); d != "" {
+		embed["description"] = truncate(d, 4096)
+	}
+	if e.DashboardURL != "" {
+		embed["url"] = e.DashboardURL
+	}
+	body := map[string]any{
+		"embeds":           []any{embed},
+		"allowed_mentions": map[string]any{"parse": []string{}},
+	}
+	if d.Username != "" {
+		body["username"] = d.Username
+	}
+	return postJSON(ctx, "discord", d.WebhookURL, body, nil)
+}
+
+// hexColor turns "#e01e5a" into the integer Discord expects.
+func hexColor(c string) int {
+	n, _ := strconv.ParseInt(strings.TrimPrefix(c, "#"), 16, 32)
+	return int(n)
+}
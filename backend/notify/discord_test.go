@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDiscordEmbed(t *testing.T) {
+	s := newStub(t, http.StatusNoContent)
+	e := downEvent()
+	e.Summary = "@everyone look"
+	e.DashboardURL = "https://sw.example.com/services/3"
+	if err := (Discord{WebhookURL: s.URL, Username: "serverwatcher"}).Notify(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	m := s.only(t).json(t)
+	if m["username"] != "serverwatcher" {
+		t.Errorf("username = %v", m["username"])
+	}
+	// mentions in alert text must not ping anyone
+	if am, _ := m["allowed_mentions"].(map[string]any); am == nil || len(am["parse"].([]any)) != 0 {
+		t.Errorf("allowed_mentions = %v", m["allowed_mentions"])
+	}
+	embeds, _ := m["embeds"].([]any)
+	if len(embeds) != 1 {
+		t.Fatalf("embeds = %v", m["embeds"])
+	}
+	embed := embeds[0].(map[string]any)
+	if embed["title"] != "[DOWN] api" || embed["color"] != float64(0xe01e5a) || embed["url"] != e.DashboardURL ||
+		embed["description"] != "@everyone look" || embed["timestamp"] != "2026-03-01T12:00:00Z" {
+		t.Errorf("embed = %v", embed)
+	}
+	fields, _ := embed["fields"].([]any)
+	var reason map[string]any
+	for _, f := range fields {
+		if f := f.(map[string]any); f["name"] == "Reason" {
+			reason = f
+		}
+	}
+	if reason == nil || reason["value"] != e.Reason {
+		t.Errorf("fields = %v", fields)
+	}
+}
+
+func TestDiscordTruncatesLongFields(t *testing.T) {
+	s := newStub(t, http.StatusNoContent)
+	e := downEvent()
+	e.Reason = strings.Repeat("x", 2000)
+	if err := (Discord{WebhookURL: s.URL}).Notify(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	m := s.only(t).json(t)
+	if _, ok := m["username"]; ok {
+		t.Error("username sent without one configured")
+	}
+	embed := m["embeds"].([]any)[0].(map[string]any)
+	for _, f := range embed["fields"].([]any) {
+		f := f.(map[string]any)
+		if v := f["value"].(string); len(v) > 1024 {
+			t.Errorf("%v is %d bytes", f["name"], len(v))
+		}
+		if f["name"] == "Reason" && f["inline"] != false {
+			t.Error("long field is inline")
+		}
+	}
+}
+
+func TestDiscordError(t *testing.T) {
+	s := newStub(t, http.StatusTooManyRequests)
+	err := Discord{WebhookURL: s.URL}.Notify(context.Background(), downEvent())
+	if err == nil || !strings.HasPrefix(err.Error(), "discord 429") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestHexColor(t *testing.T) {
+	for in, want := range map[string]int{"#2eb67d": 0x2eb67d, "e01e5a": 0xe01e5a, "nope": 0} {
+		if got := hexColor(in); got != want {
+			t.Errorf("hexColor(%q) = %#x, want %#x", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTP transport security modes.
+const (
+	SMTPStartTLS = "starttls" // plain connect, then STARTTLS (required)
+	SMTPTLS      = "tls"      // implicit TLS, usually port 465
+	SMTPNone     = "none"     // no encryption, for local relays and sinks
+)
+
+// Email sends each alert as a multipart HTML + plain-text mail. All mails
+// of one alert (e.g. DOWN and its UP) share References headers, so mail
+// clients show them as one thread.
+type Email struct {
+	Host     string
+	Port     int
+	Security string // SMTPStartTLS (default), SMTPTLS or SMTPNone
+	Username string // empty disables AUTH
+	Password string
+	From     string
+	To       []string
+
+	InsecureSkipVerify bool
+}
+
+func (m Email) Notify(ctx context.Context, e Event) error {
+	if len(m.To) == 0 {
+		return errors.New("email: no recipients")
+	}
+	msg, err := m.message(e)
+	if err != nil {
+		return err
+	}
+	c, err := m.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+	defer c.Close()
+
+	if m.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return fmt.Errorf("email: auth: %w", err)
+		}
+	}
+	if err := c.Mail(addrOnly(m.From)); err != nil {
+		return fmt.Errorf("email: MAIL FROM: %w", err)
+	}
+	for _, to := range m.To {
+		if err := c.Rcpt(addrOnly(to)); err != nil {
+			return fmt.Errorf("email: RCPT TO %s: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("email: DATA: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("email: DATA: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("email: DATA: %w", err)
+	}
+	return c.Quit()
+}
+
+// dial connects and, depending on Security, negotiates TLS. The context
+// deadline bounds the whole SMTP conversation.
+func (m Email) dial(ctx context.Context) (*smtp.Client, error) {
+	port := m.Port
+	if port == 0 {
+		port = 587
+		if m.Security == SMTPTLS {
+			port = 465
+		}
+	}
+	addr := net.JoinHostPort(m.Host, strconv.Itoa(port))
+	tlsConf := &tls.Config{ServerName: m.Host, InsecureSkipVerify: m.InsecureSkipVerify}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	}
+	if m.Security == SMTPTLS {
+		tc := tls.Client(conn, tlsConf)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tc
+	}
+	c, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if m.Security == "" || m.Security == SMTPStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			c.Close()
+			return nil, errors.New("server does not support STARTTLS")
+		}
+		if err := c.StartTLS(tlsConf); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// message renders the full RFC 5322 message.
+func (m Email) message(e Event) ([]byte, error) {
+	var htmlBody bytes.Buffer
+	if err := emailHTML.Execute(&htmlBody, e); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ ctype, content string }{
+		{"text/plain; charset=utf-8", PlainText(e)},
+		{"text/html; charset=utf-8", htmlBody.String()},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.ctype},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := io.WriteString(qw, part.content); err != nil {
+			return nil, err
+		}
+		qw.Close()
+	}
+	mw.Close()
+
+	domain := "serverwatcher"
+	if i := strings.LastIndex(addrOnly(m.From), "@"); i >= 0 {
+		domain = addrOnly(m.From)[i+1:]
+	}
+	thread := fmt.Sprintf("<%s.%d@%s>", e.AlertKey, e.StartedAt.Unix(), domain)
+	msgID := thread
+	if !e.Opening() || e.AlertKey == "" {
+		msgID = fmt.Sprintf("<%s.%s@%s>", e.AlertKey, randomToken(), domain)
+	}
+
+	var h bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&h, "%s: %s\r\n", k, v) }
+	header("From", m.From)
+	header("To", strings.Join(m.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", e.Title()))
+	header("Date", e.OccurredAt.Format(time.RFC1123Z))
+	header("Message-ID", msgID)
+	if msgID != thread {
+		header("In-Reply-To", thread)
+		header("References", thread)
+	}
+	header("MIME-Version", "1.0")
+	header("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+	h.WriteString("\r\n")
+	h.Write(body.Bytes())
+	return h.Bytes(), nil
+}
+
+// addrOnly strips a display name: "Ops <ops@x.io>" -> "ops@x.io".
+func addrOnly(a string) string {
+	if i := strings.LastIndex(a, "<"); i >= 0 {
+		return strings.TrimSuffix(a[i+1:], ">")
+	}
+	return strings.TrimSpace(a)
+}
+
+var emailHTML = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html><body style="font-family:sans-serif">
+<h2 style="border-left:6px solid {{.Color}};padding-left:8px">{{.Title}}</h2>
+<table cellpadding="4">
+{{if .ServiceURL}}<tr><td><b>Service</b></td><td>{{.ServiceURL}}</td></tr>{{end}}
+<tr><td><b>Severity</b></td><td>{{.Severity}}</td></tr>
+{{if .IncidentID}}<tr><td><b>Incident</b></td><td>#{{.IncidentID}}</td></tr>{{end}}
+<tr><td><b>Time</b></td><td>{{.OccurredAt.Format "2006-01-02 15:04:05 MST"}}</td></tr>
+{{if .Reason}}<tr><td><b>Reason</b></td><td>{{.Reason}}</td></tr>{{end}}
+{{if .Resolved}}<tr><td><b>Duration</b></td><td>{{.Duration}}</td></tr>{{end}}
+{{if .Tags}}<tr><td><b>Tags</b></td><td>{{range $i, $t := .Tags}}{{if $i}}, {{end}}{{$t}}{{end}}</td></tr>{{end}}
+</table>
+{{with .Detail}}<p style="white-space:pre-wrap">{{.}}</p>{{end}}
+{{if .DashboardURL}}<p><a href="{{.DashboardURL}}">Open dashboard</a></p>{{end}}
+</body></html>
+`))
@@ -0,0 +1,249 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// smtpMail is one message accepted by smtpStub.
+type smtpMail struct {
+	Auth string // decoded AUTH PLAIN credentials, "\x00user\x00pass"
+	From string
+	To   []string
+	Data string
+}
+
+// smtpStub is a plain-text SMTP server, one conversation at a time. It
+// doesn't offer STARTTLS.
+type smtpStub struct {
+	addr  *net.TCPAddr
+	mu    sync.Mutex
+	mails []smtpMail
+}
+
+func newSMTPStub(t *testing.T) *smtpStub {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &smtpStub{addr: ln.Addr().(*net.TCPAddr)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpStub) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	reply("220 stub ESMTP")
+	var m smtpMail
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			reply("250-stub")
+			reply("250 AUTH PLAIN")
+		case strings.HasPrefix(cmd, "AUTH PLAIN"):
+			b, _ := base64.StdEncoding.DecodeString(strings.TrimSpace(line[len("AUTH PLAIN"):]))
+			m.Auth = string(b)
+			reply("235 ok")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			m.From = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			m.To = append(m.To, strings.Trim(line[len("RCPT TO:"):], "<> "))
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			m.Data = data.String()
+			s.mu.Lock()
+			s.mails = append(s.mails, m)
+			s.mu.Unlock()
+			m = smtpMail{}
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func (s *smtpStub) received() []smtpMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpMail(nil), s.mails...)
+}
+
+func (s *smtpStub) email() Email {
+	return Email{
+		Host:     "127.0.0.1",
+		Port:     s.addr.Port,
+		Security: SMTPNone,
+		From:     "Serverwatcher <alerts@example.com>",
+		To:       []string{"ops@example.com", "Oncall <oncall@example.com>"},
+	}
+}
+
+// parts returns the decoded body parts of a multipart message by type.
+func parts(t *testing.T, msg *mail.Message) map[string]string {
+	t.Helper()
+	mt, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mt != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q (%v)", msg.Header.Get("Content-Type"), err)
+	}
+	out := map[string]string{}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return out
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(p) // quoted-printable is decoded by NextPart
+		ct, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		out[ct] = string(b)
+	}
+}
+
+func TestEmailMessage(t *testing.T) {
+	s := newSMTPStub(t)
+	m := s.email()
+	m.Username, m.Password = "bot", "hunter2"
+	e := downEvent()
+	e.Reason = "<script>alert(1)</script>"
+	if err := m.Notify(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	mails := s.received()
+	if len(mails) != 1 {
+		t.Fatalf("got %d mails", len(mails))
+	}
+	got := mails[0]
+	if got.Auth != "\x00bot\x00hunter2" {
+		t.Errorf("auth = %q", got.Auth)
+	}
+	if got.From != "alerts@example.com" || strings.Join(got.To, ",") != "ops@example.com,oncall@example.com" {
+		t.Errorf("envelope = %q -> %q", got.From, got.To)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(got.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subj, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); subj != "[DOWN] api" {
+		t.Errorf("Subject = %q", subj)
+	}
+	p := parts(t, msg)
+	if !strings.Contains(p["text/plain"], "Reason: <script>alert(1)</script>") {
+		t.Errorf("text part = %q", p["text/plain"])
+	}
+	html := p["text/html"]
+	if strings.Contains(html, "<script>") || !strings.Contains(html, "&lt;script&gt;") {
+		t.Errorf("reason not escaped in HTML part: %q", html)
+	}
+	if !strings.Contains(html, "#12") {
+		t.Errorf("incident missing from HTML part: %q", html)
+	}
+}
+
+func TestEmailThreadsByAlertKey(t *testing.T) {
+	s := newSMTPStub(t)
+	m := s.email()
+	for _, e := range []Event{downEvent(), upEvent()} {
+		if err := m.Notify(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mails := s.received()
+	if len(mails) != 2 {
+		t.Fatalf("got %d mails", len(mails))
+	}
+	down, _ := mail.ReadMessage(strings.NewReader(mails[0].Data))
+	up, _ := mail.ReadMessage(strings.NewReader(mails[1].Data))
+
+	thread := down.Header.Get("Message-ID")
+	if !strings.HasPrefix(thread, "<incident-12.") || !strings.HasSuffix(thread, "@example.com>") {
+		t.Errorf("DOWN Message-ID = %q", thread)
+	}
+	if down.Header.Get("In-Reply-To") != "" {
+		t.Error("opening mail replies to something")
+	}
+	if id := up.Header.Get("Message-ID"); id == thread {
+		t.Error("UP reuses the DOWN Message-ID")
+	}
+	if up.Header.Get("In-Reply-To") != thread || up.Header.Get("References") != thread {
+		t.Errorf("UP In-Reply-To = %q, References = %q, want %q",
+			up.Header.Get("In-Reply-To"), up.Header.Get("References"), thread)
+	}
+}
+
+func TestEmailRequiresStartTLS(t *testing.T) {
+	s := newSMTPStub(t)
+	m := s.email()
+	m.Security = ""
+	err := m.Notify(context.Background(), downEvent())
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("err = %v, want a STARTTLS error", err)
+	}
+	if len(s.received()) != 0 {
+		t.Error("mail sent without STARTTLS")
+	}
+}
+
+func TestEmailNoRecipients(t *testing.T) {
+	m := Email{Host: "127.0.0.1", Port: 1, Security: SMTPNone, From: "a@b.c"}
+	if err := m.Notify(context.Background(), downEvent()); err == nil {
+		t.Error("expected an error without recipients")
+	}
+}
+
+func TestAddrOnly(t *testing.T) {
+	for in, want := range map[string]string{
+		"Ops <ops@x.io>": "ops@x.io",
+		" ops@x.io ":     "ops@x.io",
+		"<ops@x.io>":     "ops@x.io",
+	} {
+		if got := addrOnly(in); got != want {
+			t.Errorf("addrOnly(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
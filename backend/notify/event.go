@@ -0,0 +1,205 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventDown         EventType = "DOWN"
+	EventUp           EventType = "UP"
+	EventDegraded     EventType = "DEGRADED"
+	EventCertExpiring EventType = "CERT_EXPIRING"
+	EventSLOBurn      EventType = "SLO_BURN"
+	EventTest         EventType = "TEST"
+	EventDigest       EventType = "DIGEST"
+	EventAck          EventType = "ACK"
+)
+
+// Valid reports whether t is one of the event types above.
+func (t EventType) Valid() bool {
+	switch t {
+	case EventDown, EventUp, EventDegraded, EventCertExpiring, EventSLOBurn, EventTest, EventDigest, EventAck:
+		return true
+	}
+	return false
+}
+
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityWarning  Severity = "warning"
+	SeverityInfo     Severity = "info"
+)
+
+// Valid reports whether s is one of the severities above.
+func (s Severity) Valid() bool {
+	switch s {
+	case SeverityCritical, SeverityWarning, SeverityInfo:
+		return true
+	}
+	return false
+}
+
+// Event is one alert, rendered by each Notifier in its own format.
+type Event struct {
+	Type     EventType `json:"type"`
+	Severity Severity  `json:"severity"`
+
+	ServiceID   int      `json:"serviceId"`
+	ServiceName string   `json:"serviceName"`
+	ServiceURL  string   `json:"serviceUrl"`
+	Tags        []string `json:"tags,omitempty"`
+
+	IncidentID int        `json:"incidentId,omitempty"`
+	Grouped    []int      `json:"groupedIncidentIds,omitempty"` // incidents folded into an aggregated alert
+	AlertKey   string     `json:"alertKey"`                     // shared by every event of one problem, e.g. "incident-12"
+	StartedAt  time.Time  `json:"startedAt"`                    // when the problem began
+	EndedAt    *time.Time `json:"endedAt,omitempty"`            // set on recovery
+	OccurredAt time.Time  `json:"occurredAt"`                   // when this event fired
+	Reminder   int        `json:"reminder,omitempty"`           // n-th repeat of a still open problem
+	Escalation int        `json:"escalation,omitempty"`         // n-th escalation step of an unacknowledged problem
+
+	Reason       string `json:"reason,omitempty"`  // failure reason, e.g. "status 503 (expected 200)"
+	Summary      string `json:"summary,omitempty"` // extra one-line detail, e.g. burn rates
+	Message      string `json:"message,omitempty"` // channel template output; replaces the default text
+	DashboardURL string `json:"dashboardUrl,omitempty"`
+}
+
+// TestEvent is the sample alert sent by "send test message".
+func TestEvent(channel string, now time.Time) Event {
+	return Event{
+		Type:        EventTest,
+		Severity:    SeverityInfo,
+		ServiceName: "serverwatcher",
+		AlertKey:    "test-" + channel,
+		StartedAt:   now,
+		OccurredAt:  now,
+		Summary:     fmt.Sprintf("Test message for channel %q", channel),
+	}
+}
+
+// Duration is how long the problem lasted (or has lasted so far).
+func (e Event) Duration() time.Duration {
+	if e.EndedAt != nil {
+		return e.EndedAt.Sub(e.StartedAt)
+	}
+	if e.StartedAt.IsZero() {
+		return 0
+	}
+	return e.OccurredAt.Sub(e.StartedAt)
+}
+
+// Resolved reports whether the event closes its problem (UP, or a burn
+// alert going back under threshold).
+func (e Event) Resolved() bool {
+	return e.EndedAt != nil
+}
+
+// HumanDuration rounds to minutes past the first hour, else to seconds.
+func HumanDuration(d time.Duration) string {
+	if d >= time.Hour {
+		return d.Round(time.Minute).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+// Title is the one-line headline, e.g. "[DOWN] api", or for reminders
+// "[DOWN] api (still down after 6h0m0s)".
+func (e Event) Title() string {
+	if e.Escalation > 0 {
+		return fmt.Sprintf("[%s] %s (escalation %d, unacknowledged for %s)", e.Type, e.ServiceName, e.Escalation, HumanDuration(e.Duration()))
+	}
+	if e.Reminder > 0 {
+		return fmt.Sprintf("[%s] %s (still down after %s)", e.Type, e.ServiceName, HumanDuration(e.Duration()))
+	}
+	return fmt.Sprintf("[%s] %s", e.Type, e.ServiceName)
+}
+
+// Opening reports whether the event is the first of its problem (not a
+// reminder, escalation, acknowledgement or recovery).
+func (e Event) Opening() bool {
+	return !e.Resolved() && e.Reminder == 0 && e.Escalation == 0 && e.Type != EventAck
+}
+
+// Detail is the free text shown next to the structured fields: the
+// templated message if there is one, else the summary.
+func (e Event) Detail() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return e.Summary
+}
+
+// Text is the plain-text body for channels without rich formatting.
+func (e Event) Text() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	var b strings.Builder
+	if e.ServiceURL != "" {
+		fmt.Fprintf(&b, "URL: %s\n", e.ServiceURL)
+	}
+	fmt.Fprintf(&b, "Time: %s", e.OccurredAt.Format(time.RFC3339))
+	if e.Reason != "" {
+		fmt.Fprintf(&b, "\nReason: %s", e.Reason)
+	}
+	if e.Summary != "" {
+		fmt.Fprintf(&b, "\n%s", e.Summary)
+	}
+	if e.Type == EventUp {
+		fmt.Fprintf(&b, "\nDowntime: %ds", int(e.Duration().Seconds()))
+	}
+	if e.DashboardURL != "" {
+		fmt.Fprintf(&b, "\n%s", e.DashboardURL)
+	}
+	return b.String()
+}
+
+// Fact is one labelled detail of an event, for channels that lay them
+// out as fields or tables.
+type Fact struct{ Name, Value string }
+
+// Facts lists the event details worth showing besides title and summary.
+func (e Event) Facts() []Fact {
+	var out []Fact
+	if e.ServiceURL != "" {
+		out = append(out, Fact{"Service", e.ServiceURL})
+	}
+	out = append(out, Fact{"Severity", string(e.Severity)})
+	if e.IncidentID > 0 {
+		out = append(out, Fact{"Incident", fmt.Sprintf("#%d", e.IncidentID)})
+	}
+	if e.Reason != "" {
+		out = append(out, Fact{"Reason", e.Reason})
+	}
+	if e.Resolved() {
+		out = append(out, Fact{"Duration", e.Duration().Round(time.Second).String()})
+	}
+	if len(e.Tags) > 0 {
+		out = append(out, Fact{"Tags", strings.Join(e.Tags, ", ")})
+	}
+	return out
+}
+
+// PlainText renders an event as "title\ntext", for the simplest channels.
+func PlainText(e Event) string {
+	return e.Title() + "\n" + e.Text()
+}
+
+// Color is a hex color per state, for channels that support it.
+func (e Event) Color() string {
+	switch {
+	case e.Type == EventUp:
+		return "#2eb67d"
+	case e.Severity == SeverityCritical:
+		return "#e01e5a"
+	case e.Severity == SeverityWarning:
+		return "#ecb22e"
+	}
+	return "#439fe0"
+}
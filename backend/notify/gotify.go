@@ -0,0 +1,30 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Gotify pushes a message through a Gotify application token.
+type Gotify struct {
+	ServerURL string
+	AppToken  string
+}
+
+func (g Gotify) Notify(ctx context.Context, e Event) error {
+	extras := map[string]any{
+		"client::display": map[string]string{"contentType": "text/plain"},
+	}
+	if e.DashboardURL != "" {
+		extras["client::notification"] = map[string]any{"click": map[string]string{"url": e.DashboardURL}}
+	}
+	body := map[string]any{
+		"title":    e.Title(),
+		"message":  e.Text(),
+		"priority": pushPriority(e) * 2,
+		"extras":   extras,
+	}
+	header := http.Header{"X-Gotify-Key": {g.AppToken}}
+	return postJSON(ctx, "gotify", strings.TrimRight(g.ServerURL, "/")+"/message", body, header)
+}
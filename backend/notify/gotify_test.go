@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestGotifyMessage(t *testing.T) {
+	s := newStub(t, http.StatusOK)
+	e := downEvent()
+	e.Severity = SeverityWarning
+	e.DashboardURL = "https://sw.example.com/services/3"
+	if err := (Gotify{ServerURL: s.URL + "/", AppToken: "app-1"}).Notify(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	r := s.only(t)
+	if r.Path != "/message" || r.Header.Get("X-Gotify-Key") != "app-1" {
+		t.Errorf("%s with X-Gotify-Key %q", r.Path, r.Header.Get("X-Gotify-Key"))
+	}
+	m := r.json(t)
+	if m["title"] != "[DOWN] api" || m["message"] != e.Text() || m["priority"] != float64(8) {
+		t.Errorf("payload = %v", m)
+	}
+	extras, _ := m["extras"].(map[string]any)
+	// plain text, so markdown in a failure reason isn't rendered
+	if d, _ := extras["client::display"].(map[string]any); d["contentType"] != "text/plain" {
+		t.Errorf("extras = %v", extras)
+	}
+	click, _ := extras["client::notification"].(map[string]any)["click"].(map[string]any)
+	if click["url"] != e.DashboardURL {
+		t.Errorf("click = %v", extras["client::notification"])
+	}
+}
+
+func TestGotifyError(t *testing.T) {
+	s := newStub(t, http.StatusUnauthorized)
+	err := Gotify{ServerURL: s.URL, AppToken: "bad"}.Notify(context.Background(), downEvent())
+	if err == nil || err.Error() != "gotify 401: nope" {
+		t.Errorf("err = %v", err)
+	}
+}
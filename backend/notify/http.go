@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// postJSON posts v as JSON and fails on any non-2xx answer; name prefixes
+// the error, e.g. "slack 500".
+func postJSON(ctx context.Context, name, url string, v any, header http.Header) error {
+	return sendJSON(ctx, http.MethodPost, name, url, v, header)
+}
+
+func sendJSON(ctx context.Context, method, name, url string, v any, header http.Header) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return sendBody(ctx, method, name, url, b, header)
+}
+
+// sendBody sends an already encoded JSON body, for callers that sign it.
+func sendBody(ctx context.Context, method, name, url string, b []byte, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// given headers replace the defaults rather than adding to them
+	for k, vs := range header {
+		req.Header.Del(k)
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if len(bytes.TrimSpace(msg)) > 0 {
+			return fmt.Errorf("%s %d: %s", name, resp.StatusCode, bytes.TrimSpace(msg))
+		}
+		return fmt.Errorf("%s %d", name, resp.StatusCode)
+	}
+	return nil
+}
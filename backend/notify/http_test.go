@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// request is one call captured by a stub server.
+type request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+func (r request) json(t *testing.T) map[string]any {
+	t.Helper()
+	var m map[string]any
+	if err := json.Unmarshal(r.Body, &m); err != nil {
+		t.Fatalf("body is not JSON: %v\n%s", err, r.Body)
+	}
+	return m
+}
+
+// stub records every request and answers with status.
+type stub struct {
+	*httptest.Server
+	mu     sync.Mutex
+	status int
+	reqs   []request
+}
+
+func newStub(t *testing.T, status int) *stub {
+	t.Helper()
+	s := &stub{status: status}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.reqs = append(s.reqs, request{r.Method, r.URL.Path, r.Header.Clone(), b})
+		status := s.status
+		s.mu.Unlock()
+		w.WriteHeader(status)
+		if status/100 != 2 {
+			_, _ = io.WriteString(w, "nope")
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *stub) requests() []request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]request(nil), s.reqs...)
+}
+
+// only returns the single request the stub got.
+func (s *stub) only(t *testing.T) request {
+	t.Helper()
+	reqs := s.requests()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d requests, want 1", len(reqs))
+	}
+	return reqs[0]
+}
+
+var testNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func downEvent() Event {
+	return Event{
+		Type:        EventDown,
+		Severity:    SeverityCritical,
+		ServiceID:   3,
+		ServiceName: "api",
+		ServiceURL:  "https://api.example.com/health",
+		IncidentID:  12,
+		AlertKey:    "incident-12",
+		StartedAt:   testNow,
+		OccurredAt:  testNow,
+		Reason:      "status 503 (expected 200)",
+	}
+}
+
+func upEvent() Event {
+	e := downEvent()
+	e.Type = EventUp
+	end := testNow.Add(5 * time.Minute)
+	e.EndedAt, e.OccurredAt = &end, end
+	return e
+}
+
+func TestPostJSONError(t *testing.T) {
+	s := newStub(t, http.StatusBadGateway)
+	err := postJSON(context.Background(), "slack", s.URL, map[string]string{"a": "b"}, http.Header{"X-Test": {"1"}})
+	if err == nil || err.Error() != "slack 502: nope" {
+		t.Fatalf("err = %v, want %q", err, "slack 502: nope")
+	}
+	r := s.only(t)
+	if got := r.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := r.Header.Get("X-Test"); got != "1" {
+		t.Errorf("X-Test = %q", got)
+	}
+	if !strings.Contains(string(r.Body), `"a":"b"`) {
+		t.Errorf("body = %s", r.Body)
+	}
+}
@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Matrix sends a room message as a bot user. Messages carry an HTML body
+// with the state color and a plain-text fallback.
+type Matrix struct {
+	HomeserverURL string // e.g. https://matrix.org
+	AccessToken   string
+	RoomID        string // "!abc:matrix.org"
+}
+
+func (m Matrix) Notify(ctx context.Context, e Event) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<h4><font color="%s">%s</font></h4>`, e.Color(), html.EscapeString(e.Title()))
+	if d := e.Detail(); d != "" {
+		fmt.Fprintf(&b, "<p>%s</p>", strings.ReplaceAll(html.EscapeString(d), "\n", "<br>"))
+	}
+	b.WriteString("<ul>")
+	for _, f := range e.Facts() {
+		fmt.Fprintf(&b, "<li><b>%s:</b> %s</li>", html.EscapeString(f.Name), html.EscapeString(f.Value))
+	}
+	b.WriteString("</ul>")
+	if e.DashboardURL != "" {
+		fmt.Fprintf(&b, `<p><a href="%s">Open dashboard</a></p>`, html.EscapeString(e.DashboardURL))
+	}
+	body := map[string]any{
+		"msgtype":        "m.text",
+		"body":           PlainText(e),
+		"format":         "org.matrix.custom.html",
+		"formatted_body": b.String(),
+	}
+
+	// the transaction ID makes a retried PUT idempotent
+	txn := fmt.Sprintf("sw-%s-%s-%d", e.AlertKey, e.Type, e.OccurredAt.UnixNano())
+	u := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimRight(m.HomeserverURL, "/"), url.PathEscape(m.RoomID), url.PathEscape(txn))
+	header := http.Header{"Authorization": {"Bearer " + m.AccessToken}}
+	return sendJSON(ctx, http.MethodPut, "matrix", u, body, header)
+}
@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMatrixMessage(t *testing.T) {
+	var method, rawPath, auth string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, rawPath, auth = r.Method, r.URL.EscapedPath(), r.Header.Get("Authorization")
+		body, _ = io.ReadAll(r.Body)
+		w.Write([]byte(`{"event_id":"$1"}`))
+	}))
+	defer srv.Close()
+
+	e := downEvent()
+	e.Reason = `<script>alert("x")</script>`
+	mx := Matrix{HomeserverURL: srv.URL + "/", AccessToken: "syt_abc", RoomID: "!room:example.org"}
+	if err := mx.Notify(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPut || auth != "Bearer syt_abc" {
+		t.Errorf("%s with Authorization %q", method, auth)
+	}
+	// the room ID is escaped, and the transaction ID is unique per event
+	prefix := "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/sw-incident-12-DOWN-"
+	if !strings.HasPrefix(rawPath, prefix) {
+		t.Errorf("path = %q", rawPath)
+	}
+	m := request{Body: body}.json(t)
+	if m["msgtype"] != "m.text" || m["format"] != "org.matrix.custom.html" || m["body"] != PlainText(e) {
+		t.Errorf("message = %v", m)
+	}
+	html, _ := m["formatted_body"].(string)
+	if strings.Contains(html, "<script>") || !strings.Contains(html, "&lt;script&gt;") {
+		t.Errorf("reason not escaped: %q", html)
+	}
+	if !strings.Contains(html, `<font color="#e01e5a">[DOWN] api</font>`) {
+		t.Errorf("formatted_body = %q", html)
+	}
+}
+
+func TestMatrixError(t *testing.T) {
+	s := newStub(t, http.StatusForbidden)
+	err := Matrix{HomeserverURL: s.URL, AccessToken: "t", RoomID: "!r:x"}.Notify(context.Background(), downEvent())
+	if err == nil || err.Error() != "matrix 403: nope" {
+		t.Errorf("err = %v", err)
+	}
+}
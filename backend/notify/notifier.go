@@ -1,5 +1,79 @@
 package notify
 
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
 type Notifier interface {
-	Notify(title, text string) error
+	Notify(ctx context.Context, e Event) error
+}
+
+// DefaultTimeout bounds one delivery attempt when a channel sets none.
+const DefaultTimeout = 10 * time.Second
+
+// Channel is a named, configured Notifier. The name identifies it in the
+// delivery queue, so retries reach the same channel.
+type Channel struct {
+	Name      string
+	Notifier  Notifier
+	Timeout   time.Duration
+	Templates Templates // optional message templates
+}
+
+func (c Channel) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return DefaultTimeout
+}
+
+// Send delivers one event through the channel, bounded by its timeout.
+func (c Channel) Send(ctx context.Context, e Event) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout())
+	defer cancel()
+	if len(c.Templates) > 0 {
+		e = c.Templates.apply(c.Name, e)
+	}
+	return c.Notifier.Notify(ctx, e)
+}
+
+// alertKeyed is implemented by notifiers that track each alert by its
+// AlertKey on their side: PagerDuty and Opsgenie dedupe on it, email
+// threads on it.
+type alertKeyed interface{ keyedByAlert() }
+
+func (PagerDuty) keyedByAlert() {}
+func (Opsgenie) keyedByAlert()  {}
+func (Email) keyedByAlert()     {}
+
+// KeyedByAlert reports whether every event of an alert must reach the
+// channel under the alert's own key, i.e. it can't take aggregates.
+func (c Channel) KeyedByAlert() bool {
+	_, ok := c.Notifier.(alertKeyed)
+	return ok
+}
+
+type deliveryIDKey struct{}
+
+// WithDeliveryID tags ctx with the ID of the delivery being attempted, so
+// notifiers can pass a stable ID that stays the same across retries.
+func WithDeliveryID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, deliveryIDKey{}, id)
+}
+
+// DeliveryID returns the delivery ID from ctx, or a random one.
+func DeliveryID(ctx context.Context) string {
+	if id, ok := ctx.Value(deliveryIDKey{}).(string); ok && id != "" {
+		return id
+	}
+	return randomToken()
+}
+
+func randomToken() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
 }
@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const ntfyServer = "https://ntfy.sh"
+
+// Ntfy publishes a push notification to an ntfy topic. Priority and tag
+// emojis follow the event state.
+type Ntfy struct {
+	ServerURL string // default https://ntfy.sh
+	Topic     string
+	Token     string // access token for protected topics
+}
+
+func (n Ntfy) Notify(ctx context.Context, e Event) error {
+	base := strings.TrimRight(n.ServerURL, "/")
+	if base == "" {
+		base = ntfyServer
+	}
+	body := map[string]any{
+		"topic":    n.Topic,
+		"title":    e.Title(),
+		"message":  e.Text(),
+		"priority": pushPriority(e),
+		"tags":     []string{ntfyTag(e)},
+	}
+	if e.DashboardURL != "" {
+		body["click"] = e.DashboardURL
+	}
+	var header http.Header
+	if n.Token != "" {
+		header = http.Header{"Authorization": {"Bearer " + n.Token}}
+	}
+	return postJSON(ctx, "ntfy", base, body, header)
+}
+
+// pushPriority is on ntfy's 1..5 scale; Gotify's 0..10 doubles it.
+func pushPriority(e Event) int {
+	switch {
+	case e.Resolved():
+		return 3
+	case e.Severity == SeverityCritical:
+		return 5
+	case e.Severity == SeverityWarning:
+		return 4
+	}
+	return 3
+}
+
+// ntfyTag is an emoji shortcode ntfy shows in front of the title.
+func ntfyTag(e Event) string {
+	switch {
+	case e.Resolved():
+		return "white_check_mark"
+	case e.Severity == SeverityCritical:
+		return "rotating_light"
+	case e.Severity == SeverityWarning:
+		return "warning"
+	}
+	return "information_source"
+}
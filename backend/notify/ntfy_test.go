@@ -0,0 +1,74 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestNtfyPublish(t *testing.T) {
+	s := newStub(t, http.StatusOK)
+	e := downEvent()
+	e.DashboardURL = "https://sw.example.com/services/3"
+	if err := (Ntfy{ServerURL: s.URL + "/", Topic: "alerts", Token: "tk_1"}).Notify(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	r := s.only(t)
+	if r.Path != "/" || r.Header.Get("Authorization") != "Bearer tk_1" {
+		t.Errorf("%s with Authorization %q", r.Path, r.Header.Get("Authorization"))
+	}
+	m := r.json(t)
+	if m["topic"] != "alerts" || m["title"] != "[DOWN] api" || m["message"] != e.Text() ||
+		m["priority"] != float64(5) || m["click"] != e.DashboardURL {
+		t.Errorf("payload = %v", m)
+	}
+	if tags, _ := m["tags"].([]any); len(tags) != 1 || tags[0] != "rotating_light" {
+		t.Errorf("tags = %v", m["tags"])
+	}
+}
+
+func TestNtfyWithoutToken(t *testing.T) {
+	s := newStub(t, http.StatusOK)
+	if err := (Ntfy{ServerURL: s.URL, Topic: "alerts"}).Notify(context.Background(), upEvent()); err != nil {
+		t.Fatal(err)
+	}
+	r := s.only(t)
+	if _, ok := r.Header["Authorization"]; ok {
+		t.Error("Authorization sent without a token")
+	}
+	if m := r.json(t); m["priority"] != float64(3) || m["tags"].([]any)[0] != "white_check_mark" {
+		t.Errorf("payload = %v", m)
+	}
+}
+
+func TestPushPriority(t *testing.T) {
+	warn := downEvent()
+	warn.Severity = SeverityWarning
+	info := downEvent()
+	info.Severity = SeverityInfo
+	for _, c := range []struct {
+		e    Event
+		want int
+		tag  string
+	}{
+		{downEvent(), 5, "rotating_light"},
+		{warn, 4, "warning"},
+		{info, 3, "information_source"},
+		{upEvent(), 3, "white_check_mark"},
+	} {
+		if got := pushPriority(c.e); got != c.want {
+			t.Errorf("pushPriority(%s %s) = %d, want %d", c.e.Type, c.e.Severity, got, c.want)
+		}
+		if got := ntfyTag(c.e); got != c.tag {
+			t.Errorf("ntfyTag(%s %s) = %q, want %q", c.e.Type, c.e.Severity, got, c.tag)
+		}
+	}
+}
+
+func TestNtfyError(t *testing.T) {
+	s := newStub(t, http.StatusUnauthorized)
+	err := Ntfy{ServerURL: s.URL, Topic: "alerts"}.Notify(context.Background(), downEvent())
+	if err == nil || err.Error() != "ntfy 401: nope" {
+		t.Errorf("err = %v", err)
+	}
+}
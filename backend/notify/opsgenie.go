@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const opsgenieAPI = "https://api.opsgenie.com" // EU accounts use https://api.eu.opsgenie.com
+
+// Opsgenie creates alerts through the Alert API, using the alert key as
+// alias: the resolving event closes the alert its opening event created.
+type Opsgenie struct {
+	APIKey  string
+	BaseURL string
+}
+
+func (o Opsgenie) Notify(ctx context.Context, e Event) error {
+	base := strings.TrimRight(o.BaseURL, "/")
+	if base == "" {
+		base = opsgenieAPI
+	}
+	header := http.Header{"Authorization": {"GenieKey " + o.APIKey}}
+
+	if e.Resolved() || e.Type == EventAck {
+		action := "close"
+		if e.Type == EventAck {
+			action = "acknowledge"
+		}
+		u := base + "/v2/alerts/" + url.PathEscape(e.AlertKey) + "/" + action + "?identifierType=alias"
+		body := map[string]any{"source": "serverwatcher", "note": e.Title() + "\n" + e.Text()}
+		return postJSON(ctx, "opsgenie", u, body, header)
+	}
+
+	details := map[string]string{"type": string(e.Type), "url": e.ServiceURL}
+	if e.Reason != "" {
+		details["reason"] = e.Reason
+	}
+	if e.DashboardURL != "" {
+		details["dashboard"] = e.DashboardURL
+	}
+	body := map[string]any{
+		"message":     truncate(e.Title(), 130),
+		"alias":       e.AlertKey,
+		"description": truncate(e.Text(), 15000),
+		"priority":    opsgeniePriority(e.Severity),
+		"source":      "serverwatcher",
+		"entity":      e.ServiceName,
+		"tags":        e.Tags,
+		"details":     details,
+	}
+	return postJSON(ctx, "opsgenie", base+"/v2/alerts", body, header)
+}
+
+// opsgeniePriority maps critical/warning/info onto P1/P3/P5.
+func opsgeniePriority(s Severity) string {
+	switch s {
+	case SeverityCritical:
+		return "P1"
+	case SeverityWarning:
+		return "P3"
+	}
+	return "P5"
+}
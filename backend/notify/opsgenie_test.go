@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpsgenieLifecycle(t *testing.T) {
+	s := newStub(t, http.StatusAccepted)
+	og := Opsgenie{APIKey: "k", BaseURL: s.URL}
+	down := downEvent()
+	down.Tags = []string{"team:api"}
+	ack := down
+	ack.Type = EventAck
+	for _, e := range []Event{down, ack, upEvent()} {
+		if err := og.Notify(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reqs := s.requests()
+	if len(reqs) != 3 {
+		t.Fatalf("got %d requests, want 3", len(reqs))
+	}
+	for i, r := range reqs {
+		if got := r.Header.Get("Authorization"); got != "GenieKey k" {
+			t.Errorf("request %d: Authorization = %q", i, got)
+		}
+	}
+
+	create := reqs[0].json(t)
+	if reqs[0].Path != "/v2/alerts" || create["alias"] != "incident-12" || create["priority"] != "P1" ||
+		create["message"] != "[DOWN] api" || create["entity"] != "api" {
+		t.Errorf("create: %s %v", reqs[0].Path, create)
+	}
+	if tags, _ := create["tags"].([]any); len(tags) != 1 || tags[0] != "team:api" {
+		t.Errorf("tags = %v", create["tags"])
+	}
+	for i, action := range []string{"acknowledge", "close"} {
+		r := reqs[i+1]
+		if r.Path != "/v2/alerts/incident-12/"+action {
+			t.Errorf("%s path = %q", action, r.Path)
+		}
+		if note, _ := r.json(t)["note"].(string); note == "" {
+			t.Errorf("%s without a note", action)
+		}
+	}
+}
+
+func TestOpsgenieAliasEscaped(t *testing.T) {
+	var raw, query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, query = r.URL.RawPath, r.URL.RawQuery
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	e := upEvent()
+	e.AlertKey = "a/b c"
+	if err := (Opsgenie{APIKey: "k", BaseURL: srv.URL}).Notify(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	if raw != "/v2/alerts/a%2Fb%20c/close" || query != "identifierType=alias" {
+		t.Errorf("url = %s?%s", raw, query)
+	}
+}
@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+const pagerDutyAPI = "https://events.pagerduty.com"
+
+// PagerDuty sends Events API v2 events. The alert key is the dedup key, so
+// a resolving event (e.g. UP) resolves the page opened by its DOWN.
+type PagerDuty struct {
+	RoutingKey string
+	BaseURL    string // only set to point at a stub server
+}
+
+func (p PagerDuty) Notify(ctx context.Context, e Event) error {
+	base := strings.TrimRight(p.BaseURL, "/")
+	if base == "" {
+		base = pagerDutyAPI
+	}
+	body := map[string]any{
+		"routing_key":  p.RoutingKey,
+		"event_action": "trigger",
+		"dedup_key":    e.AlertKey,
+	}
+	switch {
+	case e.Resolved():
+		body["event_action"] = "resolve"
+	case e.Type == EventAck:
+		body["event_action"] = "acknowledge"
+	default:
+		details := map[string]any{"type": e.Type, "url": e.ServiceURL}
+		if e.Reason != "" {
+			details["reason"] = e.Reason
+		}
+		if d := e.Detail(); d != "" {
+			details["message"] = d
+		}
+		if e.IncidentID > 0 {
+			details["incidentId"] = e.IncidentID
+		}
+		summary := e.Title()
+		if e.Reason != "" {
+			summary += ": " + e.Reason
+		}
+		body["payload"] = map[string]any{
+			"summary":        truncate(summary, 1024),
+			"source":         e.ServiceName,
+			"severity":       pagerDutySeverity(e.Severity),
+			"timestamp":      e.OccurredAt.Format(time.RFC3339),
+			"component":      e.ServiceURL,
+			"class":          string(e.Type),
+			"custom_details": details,
+		}
+		if e.DashboardURL != "" {
+			body["links"] = []map[string]string{{"href": e.DashboardURL, "text": "Dashboard"}}
+		}
+	}
+	return postJSON(ctx, "pagerduty", base+"/v2/enqueue", body, nil)
+}
+
+// pagerDutySeverity maps onto PD's critical/error/warning/info.
+func pagerDutySeverity(s Severity) string {
+	switch s {
+	case SeverityCritical:
+		return "critical"
+	case SeverityWarning:
+		return "warning"
+	}
+	return "info"
+}
+
+// truncate keeps at most n characters, never splitting a UTF-8 rune.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
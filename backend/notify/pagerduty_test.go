@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestPagerDutyLifecycle(t *testing.T) {
+	s := newStub(t, http.StatusAccepted)
+	pd := PagerDuty{RoutingKey: "rk", BaseURL: s.URL}
+	down := downEvent()
+	down.DashboardURL = "https://sw.example.com/services/3"
+	ack := downEvent()
+	ack.Type = EventAck
+	for _, e := range []Event{down, ack, upEvent()} {
+		if err := pd.Notify(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reqs := s.requests()
+	if len(reqs) != 3 {
+		t.Fatalf("got %d requests, want 3", len(reqs))
+	}
+	for i, want := range []string{"trigger", "acknowledge", "resolve"} {
+		r := reqs[i]
+		m := r.json(t)
+		if r.Path != "/v2/enqueue" || m["routing_key"] != "rk" || m["event_action"] != want {
+			t.Errorf("request %d: %s %v", i, r.Path, m)
+		}
+		if m["dedup_key"] != "incident-12" {
+			t.Errorf("request %d: dedup_key = %v, want incident-12", i, m["dedup_key"])
+		}
+		if _, ok := m["payload"]; ok != (want == "trigger") {
+			t.Errorf("request %d: payload present = %v", i, ok)
+		}
+	}
+
+	trigger := reqs[0].json(t)
+	p := trigger["payload"].(map[string]any)
+	if p["summary"] != "[DOWN] api: status 503 (expected 200)" || p["source"] != "api" ||
+		p["severity"] != "critical" || p["timestamp"] != "2026-03-01T12:00:00Z" {
+		t.Errorf("payload = %v", p)
+	}
+	if d := p["custom_details"].(map[string]any); d["incidentId"] != float64(12) {
+		t.Errorf("custom_details = %v", d)
+	}
+	if links, _ := trigger["links"].([]any); len(links) != 1 {
+		t.Errorf("links = %v", trigger["links"])
+	}
+}
+
+func TestPagerDutySeverity(t *testing.T) {
+	for sev, want := range map[Severity]string{
+		SeverityCritical: "critical",
+		SeverityWarning:  "warning",
+		SeverityInfo:     "info",
+		"":               "info",
+	} {
+		if got := pagerDutySeverity(sev); got != want {
+			t.Errorf("pagerDutySeverity(%q) = %q, want %q", sev, got, want)
+		}
+	}
+}
+
+func TestPagerDutyError(t *testing.T) {
+	s := newStub(t, http.StatusBadRequest)
+	err := PagerDuty{RoutingKey: "rk", BaseURL: s.URL}.Notify(context.Background(), downEvent())
+	if err == nil || !strings.HasPrefix(err.Error(), "pagerduty 400") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly", 7, "exactly"},
+		{"abcdef", 3, "abc"},
+		{"ünïcödé", 3, "ünï"},
+		{"日本語テキスト", 2, "日本"},
+	} {
+		got := truncate(c.in, c.n)
+		if got != c.want || !utf8.ValidString(got) {
+			t.Errorf("truncate(%q, %d) = %q, want %q", c.in, c.n, got, c.want)
+		}
+	}
+}
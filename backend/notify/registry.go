@@ -0,0 +1,230 @@
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Redacted replaces secret settings when channel configs are read back.
+// Sending it back unchanged on update keeps the stored secret.
+const Redacted = "********"
+
+// Spec describes one channel type: the settings it needs, which of them
+// are secrets, which pick the server secrets are sent to, and how to
+// build the Notifier from them.
+type Spec struct {
+	Required  []string
+	Secrets   []string
+	Endpoints []string
+	Build     func(settings map[string]string) (Notifier, error)
+}
+
+var specs = map[string]Spec{
+	"slack": {
+		Required: []string{"webhookUrl"},
+		Secrets:  []string{"webhookUrl"},
+		Build: func(s map[string]string) (Notifier, error) {
+			return Slack{WebhookURL: s["webhookUrl"]}, nil
+		},
+	},
+	"webhook": {
+		Required: []string{"url"},
+		// headers usually carry auth tokens, and URLs often embed them
+		Secrets:   []string{"url", "headers", "secret", "previousSecret"},
+		Endpoints: []string{"url"},
+		Build: func(s map[string]string) (Notifier, error) {
+			h, err := parseHeaders(s["headers"])
+			if err != nil {
+				return nil, err
+			}
+			return Webhook{URL: s["url"], Secret: s["secret"], PreviousSecret: s["previousSecret"], Headers: h}, nil
+		},
+	},
+	"telegram": {
+		Required:  []string{"token", "chatId"},
+		Secrets:   []string{"token"},
+		Endpoints: []string{"baseUrl"},
+		Build: func(s map[string]string) (Notifier, error) {
+			t := Telegram{Token: s["token"], ChatID: s["chatId"], ParseMode: s["parseMode"], BaseURL: s["baseUrl"]}
+			if v := s["threadId"]; v != "" {
+				id, err := strconv.Atoi(v)
+				if err != nil || id <= 0 {
+					return nil, fmt.Errorf("telegram: invalid threadId %q", v)
+				}
+				t.ThreadID = id
+			}
+			switch t.ParseMode {
+			case "", TelegramHTML, TelegramMarkdownV2:
+			default:
+				return nil, fmt.Errorf("telegram: parseMode must be %s or %s", TelegramHTML, TelegramMarkdownV2)
+			}
+			return t, nil
+		},
+	},
+	"email": {
+		Required:  []string{"host", "from", "to"},
+		Secrets:   []string{"password"},
+		Endpoints: []string{"host"},
+		Build: func(s map[string]string) (Notifier, error) {
+			m := Email{
+				Host:               s["host"],
+				Security:           s["security"],
+				Username:           s["username"],
+				Password:           s["password"],
+				From:               s["from"],
+				To:                 SplitList(s["to"]),
+				InsecureSkipVerify: s["insecureSkipVerify"] == "true",
+			}
+			if v := s["port"]; v != "" {
+				p, err := strconv.Atoi(v)
+				if err != nil || p <= 0 || p > 65535 {
+					return nil, fmt.Errorf("email: invalid port %q", v)
+				}
+				m.Port = p
+			}
+			switch m.Security {
+			case "", SMTPStartTLS, SMTPTLS, SMTPNone:
+			default:
+				return nil, fmt.Errorf("email: security must be %s, %s or %s", SMTPStartTLS, SMTPTLS, SMTPNone)
+			}
+			return m, nil
+		},
+	},
+	"pagerduty": {
+		Required:  []string{"routingKey"},
+		Secrets:   []string{"routingKey"},
+		Endpoints: []string{"baseUrl"},
+		Build: func(s map[string]string) (Notifier, error) {
+			return PagerDuty{RoutingKey: s["routingKey"], BaseURL: s["baseUrl"]}, nil
+		},
+	},
+	"opsgenie": {
+		Required:  []string{"apiKey"},
+		Secrets:   []string{"apiKey"},
+		Endpoints: []string{"baseUrl"},
+		Build: func(s map[string]string) (Notifier, error) {
+			return Opsgenie{APIKey: s["apiKey"], BaseURL: s["baseUrl"]}, nil
+		},
+	},
+	"discord": {
+		Required: []string{"webhookUrl"},
+		Secrets:  []string{"webhookUrl"},
+		Build: func(s map[string]string) (Notifier, error) {
+			return Discord{WebhookURL: s["webhookUrl"], Username: s["username"]}, nil
+		},
+	},
+	"teams": {
+		Required: []string{"webhookUrl"},
+		Secrets:  []string{"webhookUrl"},
+		Build: func(s map[string]string) (Notifier, error) {
+			return Teams{WebhookURL: s["webhookUrl"]}, nil
+		},
+	},
+	"matrix": {
+		Required:  []string{"homeserverUrl", "accessToken", "roomId"},
+		Secrets:   []string{"accessToken"},
+		Endpoints: []string{"homeserverUrl"},
+		Build: func(s map[string]string) (Notifier, error) {
+			return Matrix{HomeserverURL: s["homeserverUrl"], AccessToken: s["accessToken"], RoomID: s["roomId"]}, nil
+		},
+	},
+	"ntfy": {
+		Required:  []string{"topic"},
+		Secrets:   []string{"token"},
+		Endpoints: []string{"serverUrl"},
+		Build: func(s map[string]string) (Notifier, error) {
+			return Ntfy{ServerURL: s["serverUrl"], Topic: s["topic"], Token: s["token"]}, nil
+		},
+	},
+	"gotify": {
+		Required:  []string{"serverUrl", "appToken"},
+		Secrets:   []string{"appToken"},
+		Endpoints: []string{"serverUrl"},
+		Build: func(s map[string]string) (Notifier, error) {
+			return Gotify{ServerURL: s["serverUrl"], AppToken: s["appToken"]}, nil
+		},
+	},
+}
+
+// parseHeaders reads "Name: value" lines.
+func parseHeaders(v string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, line := range strings.Split(v, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		k, val, ok := strings.Cut(line, ":")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" || strings.ContainsAny(k, " \t") {
+			return nil, fmt.Errorf("webhook: invalid header line %q (want \"Name: value\")", line)
+		}
+		if reservedHeader(k) {
+			return nil, fmt.Errorf("webhook: header %s is set by serverwatcher", k)
+		}
+		out[k] = strings.TrimSpace(val)
+	}
+	return out, nil
+}
+
+// SplitList splits a comma separated setting, dropping blanks.
+func SplitList(v string) []string {
+	var out []string
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Types lists the supported channel types.
+func Types() []string {
+	out := make([]string, 0, len(specs))
+	for t := range specs {
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Build validates settings for the given type and builds its Notifier.
+func Build(typ string, settings map[string]string) (Notifier, error) {
+	spec, ok := specs[typ]
+	if !ok {
+		return nil, fmt.Errorf("unknown channel type %q (supported: %s)", typ, strings.Join(Types(), ", "))
+	}
+	for _, k := range spec.Required {
+		if strings.TrimSpace(settings[k]) == "" {
+			return nil, fmt.Errorf("%s: %s is required", typ, k)
+		}
+	}
+	return spec.Build(settings)
+}
+
+// SameEndpoint reports whether updated settings b of the given type still
+// send to the server of the stored settings a, so stored secrets can be
+// kept. A secret endpoint sent back as Redacted or left out is unchanged.
+func SameEndpoint(typ string, a, b map[string]string) bool {
+	for _, k := range specs[typ].Endpoints {
+		v, ok := b[k]
+		if IsSecret(typ, k) && (!ok || v == Redacted) {
+			continue
+		}
+		if strings.TrimSpace(a[k]) != strings.TrimSpace(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSecret reports whether a setting of the given type must be redacted.
+func IsSecret(typ, key string) bool {
+	for _, k := range specs[typ].Secrets {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
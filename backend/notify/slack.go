@@ -1,26 +1,45 @@
 package notify
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
+	"strings"
+	"time"
 )
 
 type Slack struct{ WebhookURL string }
 
-func (s Slack) Notify(title, text string) error {
-	body := map[string]any{
-		"text": "*" + title + "*\n" + text,
+func (s Slack) Notify(ctx context.Context, e Event) error {
+	fields := []map[string]any{
+		{"title": "Service", "value": e.ServiceURL, "short": false},
+		{"title": "Severity", "value": string(e.Severity), "short": true},
+	}
+	if e.IncidentID > 0 {
+		fields = append(fields, map[string]any{"title": "Incident", "value": fmt.Sprintf("#%d", e.IncidentID), "short": true})
+	}
+	if e.Reason != "" {
+		fields = append(fields, map[string]any{"title": "Reason", "value": e.Reason, "short": false})
+	}
+	if e.Type == EventUp {
+		fields = append(fields, map[string]any{"title": "Downtime", "value": e.Duration().Round(time.Second).String(), "short": true})
 	}
-	b, _ := json.Marshal(body)
-	resp, err := http.Post(s.WebhookURL, "application/json", bytes.NewReader(b))
-	if err != nil {
-		return err
+	if len(e.Tags) > 0 {
+		fields = append(fields, map[string]any{"title": "Tags", "value": strings.Join(e.Tags, ", "), "short": true})
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode/100 != 2 {
-		return fmt.Errorf("slack %d", resp.StatusCode)
+	att := map[string]any{
+		"color":    e.Color(),
+		"fallback": PlainText(e),
+		"title":    e.Title(),
+		"text":     e.Detail(),
+		"fields":   fields,
+		"ts":       e.OccurredAt.Unix(),
+	}
+	if e.DashboardURL != "" {
+		att["title_link"] = e.DashboardURL
+	}
+	body := map[string]any{
+		"text":        "*" + e.Title() + "*",
+		"attachments": []any{att},
 	}
-	return nil
+	return postJSON(ctx, "slack", s.WebhookURL, body, nil)
 }
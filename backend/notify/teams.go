@@ -0,0 +1,65 @@
+package notify
+
+import "context"
+
+// Teams posts an Adaptive Card to a Teams incoming webhook / workflow URL.
+type Teams struct{ WebhookURL string }
+
+func (t Teams) Notify(ctx context.Context, e Event) error {
+	var facts []map[string]string
+	for _, f := range e.Facts() {
+		facts = append(facts, map[string]string{"title": f.Name, "value": f.Value})
+	}
+	body := []any{
+		map[string]any{
+			"type":   "TextBlock",
+			"text":   e.Title(),
+			"size":   "Large",
+			"weight": "Bolder",
+			"color":  teamsColor(e),
+			"wrap":   true,
+		},
+		map[string]any{
+			"type":     "TextBlock",
+			"text":     e.OccurredAt.Format("2006-01-02 15:04:05 MST"),
+			"isSubtle": true,
+			"spacing":  "None",
+		},
+	}
+	if d := e.Detail(); d != "" {
+		body = append(body, map[string]any{"type": "TextBlock", "text": d, "wrap": true})
+	}
+	body = append(body, map[string]any{"type": "FactSet", "facts": facts})
+
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+		"msteams": map[string]any{"width": "Full"},
+	}
+	if e.DashboardURL != "" {
+		card["actions"] = []any{map[string]any{"type": "Action.OpenUrl", "title": "Open dashboard", "url": e.DashboardURL}}
+	}
+	msg := map[string]any{
+		"type": "message",
+		"attachments": []any{map[string]any{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     card,
+		}},
+	}
+	return postJSON(ctx, "teams", t.WebhookURL, msg, nil)
+}
+
+// teamsColor maps states onto Adaptive Card's named colors.
+func teamsColor(e Event) string {
+	switch {
+	case e.Resolved():
+		return "Good"
+	case e.Severity == SeverityCritical:
+		return "Attention"
+	case e.Severity == SeverityWarning:
+		return "Warning"
+	}
+	return "Accent"
+}
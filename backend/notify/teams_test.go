@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTeamsCard(t *testing.T) {
+	s := newStub(t, http.StatusAccepted)
+	e := downEvent()
+	e.DashboardURL = "https://sw.example.com/services/3"
+	if err := (Teams{WebhookURL: s.URL}).Notify(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	m := s.only(t).json(t)
+	atts, _ := m["attachments"].([]any)
+	if m["type"] != "message" || len(atts) != 1 {
+		t.Fatalf("message = %v", m)
+	}
+	att := atts[0].(map[string]any)
+	if att["contentType"] != "application/vnd.microsoft.card.adaptive" {
+		t.Errorf("contentType = %v", att["contentType"])
+	}
+	card := att["content"].(map[string]any)
+	if card["type"] != "AdaptiveCard" || card["version"] != "1.4" {
+		t.Errorf("card = %v", card)
+	}
+	body := card["body"].([]any)
+	title := body[0].(map[string]any)
+	if title["text"] != "[DOWN] api" || title["color"] != "Attention" {
+		t.Errorf("title block = %v", title)
+	}
+	facts := body[len(body)-1].(map[string]any)
+	if facts["type"] != "FactSet" || !strings.Contains(string(s.only(t).Body), `"title":"Reason","value":"status 503 (expected 200)"`) {
+		t.Errorf("facts = %v", facts)
+	}
+	actions, _ := card["actions"].([]any)
+	if len(actions) != 1 || actions[0].(map[string]any)["url"] != e.DashboardURL {
+		t.Errorf("actions = %v", card["actions"])
+	}
+}
+
+func TestTeamsColor(t *testing.T) {
+	warn := downEvent()
+	warn.Severity = SeverityWarning
+	info := downEvent()
+	info.Severity = SeverityInfo
+	for want, e := range map[string]Event{"Attention": downEvent(), "Warning": warn, "Accent": info, "Good": upEvent()} {
+		if got := teamsColor(e); got != want {
+			t.Errorf("teamsColor(%s %s) = %q, want %q", e.Type, e.Severity, got, want)
+		}
+	}
+}
+
+func TestTeamsError(t *testing.T) {
+	s := newStub(t, http.StatusBadRequest)
+	err := Teams{WebhookURL: s.URL}.Notify(context.Background(), downEvent())
+	if err == nil || err.Error() != "teams 400: nope" {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -1,21 +1,87 @@
 package notify
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"net/http"
+	"html"
+	"strings"
 )
 
-const telegramBotToken = "8396998324:AAEscq2rXYteRXxA4qmuD9RBftrRWSsn3v0"
-const telegramChatID = "277355330"
+const telegramAPI = "https://api.telegram.org"
 
-func SendTelegramNotification(message string) error {
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", telegramBotToken)
-	payload := []byte(fmt.Sprintf(`{"chat_id":"%s","text":"%s"}`, telegramChatID, message))
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(payload))
-	if err != nil {
-		return err
+// Telegram parse modes.
+const (
+	TelegramHTML       = "HTML"
+	TelegramMarkdownV2 = "MarkdownV2"
+)
+
+// Telegram sends alerts through a bot. ThreadID targets a forum topic;
+// BaseURL is only set to point at a stub server.
+type Telegram struct {
+	Token     string
+	ChatID    string
+	ThreadID  int
+	ParseMode string // TelegramHTML (default) or TelegramMarkdownV2
+	BaseURL   string
+}
+
+func (t Telegram) Notify(ctx context.Context, e Event) error {
+	base := strings.TrimRight(t.BaseURL, "/")
+	if base == "" {
+		base = telegramAPI
+	}
+	mode := t.ParseMode
+	if mode == "" {
+		mode = TelegramHTML
+	}
+	var text string
+	switch mode {
+	case TelegramHTML:
+		text = telegramHTML(e)
+	case TelegramMarkdownV2:
+		text = telegramMarkdown(e)
+	default:
+		return fmt.Errorf("telegram: unknown parse mode %q", mode)
+	}
+	body := map[string]any{
+		"chat_id":                  t.ChatID,
+		"text":                     text,
+		"parse_mode":               mode,
+		"disable_web_page_preview": true,
+	}
+	if t.ThreadID > 0 {
+		body["message_thread_id"] = t.ThreadID
+	}
+	err := postJSON(ctx, "telegram", base+"/bot"+t.Token+"/sendMessage", body, nil)
+	if err != nil && t.Token != "" {
+		// transport errors quote the URL, which holds the token
+		return errors.New(strings.ReplaceAll(err.Error(), t.Token, Redacted))
 	}
-	defer resp.Body.Close()
-	return nil
+	return err
+}
+
+func telegramHTML(e Event) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<b>%s</b>\n%s", html.EscapeString(e.Title()), html.EscapeString(e.Text()))
+	return b.String()
+}
+
+// markdownV2Special are the characters MarkdownV2 requires escaping
+// everywhere outside of entities.
+const markdownV2Special = "_*[]()~`>#+-=|{}.!\\"
+
+func escapeMarkdownV2(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(markdownV2Special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func telegramMarkdown(e Event) string {
+	return "*" + escapeMarkdownV2(e.Title()) + "*\n" + escapeMarkdownV2(e.Text())
 }
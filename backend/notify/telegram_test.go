@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTelegramHTML(t *testing.T) {
+	s := newStub(t, http.StatusOK)
+	tg := Telegram{Token: "123:abc", ChatID: "-100", ThreadID: 7, BaseURL: s.URL}
+	e := downEvent()
+	e.Reason = "body missing <ok> & more"
+	if err := tg.Notify(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	r := s.only(t)
+	if r.Path != "/bot123:abc/sendMessage" {
+		t.Errorf("path = %q", r.Path)
+	}
+	m := r.json(t)
+	if m["chat_id"] != "-100" || m["parse_mode"] != TelegramHTML || m["message_thread_id"] != float64(7) {
+		t.Errorf("payload = %v", m)
+	}
+	text, _ := m["text"].(string)
+	if !strings.HasPrefix(text, "<b>[DOWN] api</b>\n") {
+		t.Errorf("text = %q", text)
+	}
+	if !strings.Contains(text, "body missing &lt;ok&gt; &amp; more") {
+		t.Errorf("reason not escaped: %q", text)
+	}
+}
+
+func TestTelegramNoThread(t *testing.T) {
+	s := newStub(t, http.StatusOK)
+	tg := Telegram{Token: "t", ChatID: "1", BaseURL: s.URL}
+	if err := tg.Notify(context.Background(), downEvent()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.only(t).json(t)["message_thread_id"]; ok {
+		t.Error("message_thread_id sent without a thread")
+	}
+}
+
+func TestTelegramMarkdownV2(t *testing.T) {
+	s := newStub(t, http.StatusOK)
+	tg := Telegram{Token: "t", ChatID: "1", ParseMode: TelegramMarkdownV2, BaseURL: s.URL}
+	if err := tg.Notify(context.Background(), downEvent()); err != nil {
+		t.Fatal(err)
+	}
+	m := s.only(t).json(t)
+	text, _ := m["text"].(string)
+	if m["parse_mode"] != TelegramMarkdownV2 || !strings.HasPrefix(text, `*\[DOWN\] api*`) {
+		t.Errorf("payload = %v", m)
+	}
+	if !strings.Contains(text, `https://api\.example\.com/health`) {
+		t.Errorf("URL not escaped: %q", text)
+	}
+}
+
+func TestEscapeMarkdownV2(t *testing.T) {
+	in := `a_b*c[d]e(f)g~h` + "`" + `i>j#k+l-m=n|o{p}q.r!s\t`
+	want := `a\_b\*c\[d\]e\(f\)g\~h\` + "`" + `i\>j\#k\+l\-m\=n\|o\{p\}q\.r\!s\\t`
+	if got := escapeMarkdownV2(in); got != want {
+		t.Errorf("escapeMarkdownV2 = %q, want %q", got, want)
+	}
+}
+
+func TestTelegramErrorHidesToken(t *testing.T) {
+	tg := Telegram{Token: "secret-token", ChatID: "1", BaseURL: "http://127.0.0.1:1"}
+	err := tg.Notify(context.Background(), downEvent())
+	if err == nil {
+		t.Fatal("expected a transport error")
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("token leaked: %v", err)
+	}
+}
+
+func TestTelegramUnknownParseMode(t *testing.T) {
+	tg := Telegram{Token: "t", ChatID: "1", ParseMode: "Markdown", BaseURL: "http://127.0.0.1:1"}
+	if err := tg.Notify(context.Background(), downEvent()); err == nil {
+		t.Error("expected an error for an unknown parse mode")
+	}
+}
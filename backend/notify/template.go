@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultTemplate is the Templates key used for event types without a
+// template of their own.
+const DefaultTemplate = "default"
+
+// Templates are per-channel message templates keyed by event type (or
+// DefaultTemplate). They render the message text with the Event as data,
+// e.g. "{{.ServiceName}} is down: {{.Reason}} @oncall".
+type Templates map[string]*template.Template
+
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	// duration rounds to seconds: {{duration .Duration}} -> "4m12s"
+	"duration": func(d time.Duration) string { return d.Round(time.Second).String() },
+	// date formats a time: {{date .OccurredAt "15:04 MST"}}
+	"date": func(t time.Time, layout string) string { return t.Format(layout) },
+}
+
+// ParseTemplates compiles templates and renders each against a sample
+// event, so mistakes such as unknown fields are caught on save.
+func ParseTemplates(src map[string]string) (Templates, error) {
+	out := Templates{}
+	for key, text := range src {
+		if !validTemplateKey(key) {
+			return nil, fmt.Errorf("template %q: unknown event type", key)
+		}
+		t, err := template.New(key).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("template %q: %v", key, err)
+		}
+		typ := EventType(key)
+		if key == DefaultTemplate {
+			typ = EventDown
+		}
+		if err := t.Execute(&bytes.Buffer{}, SampleEvent(typ, time.Now())); err != nil {
+			return nil, fmt.Errorf("template %q: %v", key, err)
+		}
+		out[key] = t
+	}
+	return out, nil
+}
+
+func validTemplateKey(k string) bool {
+	return k == DefaultTemplate || EventType(k).Valid()
+}
+
+// Render returns the templated message for e, or "" when no template
+// applies.
+func (t Templates) Render(e Event) (string, error) {
+	tpl := t[string(e.Type)]
+	if tpl == nil {
+		tpl = t[DefaultTemplate]
+	}
+	if tpl == nil {
+		return "", nil
+	}
+	var b bytes.Buffer
+	if err := tpl.Execute(&b, e); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// apply sets e.Message from the templates. A template failing at send
+// time (it was checked on save) falls back to the built-in text rather
+// than losing the alert.
+func (t Templates) apply(channel string, e Event) Event {
+	msg, err := t.Render(e)
+	if err != nil {
+		log.Printf("notify %s: template: %v", channel, err)
+		return e
+	}
+	if msg != "" {
+		e.Message = msg
+	}
+	return e
+}
+
+// SampleEvent is a realistic event for template validation and previews.
+func SampleEvent(typ EventType, now time.Time) Event {
+	now = now.UTC().Truncate(time.Second)
+	e := Event{
+		Type:         typ,
+		Severity:     SeverityCritical,
+		ServiceID:    1,
+		ServiceName:  "checkout-api",
+		ServiceURL:   "https://checkout.example.com/health",
+		Tags:         []string{"team:payments", "env:prod"},
+		IncidentID:   42,
+		AlertKey:     "incident-42",
+		StartedAt:    now.Add(-4*time.Minute - 12*time.Second),
+		OccurredAt:   now,
+		Reason:       "status 503 (expected 200)",
+		DashboardURL: "https://status.example.com/?service=1",
+	}
+	if typ == EventUp {
+		e.Severity = SeverityInfo
+		e.EndedAt = &now
+	}
+	return e
+}
@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseTemplatesErrors(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		src  map[string]string
+		want string
+	}{
+		{"unknown event type", map[string]string{"OUTAGE": "x"}, `template "OUTAGE": unknown event type`},
+		{"syntax", map[string]string{"DOWN": "{{.ServiceName"}, `template "DOWN"`},
+		{"unknown field", map[string]string{"default": "{{.Nope}}"}, "Nope"},
+		{"unknown func", map[string]string{"UP": "{{shout .ServiceName}}"}, `function "shout" not defined`},
+		{"bad func args", map[string]string{"DOWN": `{{date .ServiceName "15:04"}}`}, `template "DOWN"`},
+	} {
+		_, err := ParseTemplates(c.src)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: err = %v, want it to mention %q", c.name, err, c.want)
+		}
+	}
+}
+
+func TestTemplatesRender(t *testing.T) {
+	tpl, err := ParseTemplates(map[string]string{
+		"default": "{{.ServiceName}} {{lower (printf \"%s\" .Type)}}",
+		"UP":      "  {{upper .ServiceName}} back after {{duration .Duration}} ({{join .Tags \", \"}})\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	up := upEvent()
+	up.Tags = []string{"team:api", "env:prod"}
+	for _, c := range []struct {
+		e    Event
+		want string
+	}{
+		{downEvent(), "api down"},
+		{up, "API back after 5m0s (team:api, env:prod)"},
+	} {
+		got, err := tpl.Render(c.e)
+		if err != nil || got != c.want {
+			t.Errorf("Render(%s) = %q, %v; want %q", c.e.Type, got, err, c.want)
+		}
+	}
+
+	none, _ := ParseTemplates(map[string]string{"UP": "up"})
+	if got, err := none.Render(downEvent()); got != "" || err != nil {
+		t.Errorf("no template for DOWN: %q, %v", got, err)
+	}
+}
+
+// recorder is a Notifier that keeps what it was sent.
+type recorder struct{ events []Event }
+
+func (r *recorder) Notify(_ context.Context, e Event) error {
+	r.events = append(r.events, e)
+	return nil
+}
+
+func TestChannelSendAppliesTemplates(t *testing.T) {
+	// passes validation against the sample event (two tags), fails at send
+	// time on an event with fewer
+	tpl, err := ParseTemplates(map[string]string{"DOWN": "{{.ServiceName}} owned by {{index .Tags 1}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &recorder{}
+	c := Channel{Name: "ops", Notifier: rec, Templates: tpl}
+
+	e := downEvent()
+	e.Tags = []string{"team:api", "owner:ana"}
+	if err := c.Send(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	e.Tags = nil
+	if err := c.Send(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.events) != 2 {
+		t.Fatalf("got %d events", len(rec.events))
+	}
+	if got := rec.events[0].Message; got != "api owned by owner:ana" {
+		t.Errorf("Message = %q", got)
+	}
+	if got := rec.events[0].Text(); got != "api owned by owner:ana" {
+		t.Errorf("Text = %q, want the templated message", got)
+	}
+	if rec.events[1].Message != "" || !strings.Contains(rec.events[1].Text(), "Reason: status 503") {
+		t.Errorf("failing template didn't fall back to the built-in text: %q", rec.events[1].Text())
+	}
+}
+
+func TestSampleEventUp(t *testing.T) {
+	e := SampleEvent(EventUp, testNow)
+	if !e.Resolved() || e.Duration() <= 0 {
+		t.Errorf("sample UP = %+v", e)
+	}
+	if SampleEvent(EventDown, testNow).Resolved() {
+		t.Error("sample DOWN is resolved")
+	}
+}
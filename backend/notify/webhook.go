@@ -1,24 +1,123 @@
 package notify
 
 import (
-	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
-type Webhook struct{ URL string }
+// WebhookVersion is the version of the webhook payload schema. It is bumped
+// on incompatible changes; new fields may be added within a version.
+const WebhookVersion = 1
 
-func (w Webhook) Notify(title, text string) error {
-	body := map[string]any{"title": title, "text": text}
-	b, _ := json.Marshal(body)
-	resp, err := http.Post(w.URL, "application/json", bytes.NewReader(b))
+// Webhook headers. The signature covers "<timestamp>.<body>".
+const (
+	HeaderDelivery  = "X-Serverwatcher-Delivery"
+	HeaderTimestamp = "X-Serverwatcher-Timestamp"
+	HeaderSignature = "X-Serverwatcher-Signature"
+)
+
+// Webhook posts a versioned JSON payload. With a Secret set the body is
+// signed with HMAC-SHA256; during rotation PreviousSecret adds a second
+// signature so receivers holding either secret accept it.
+type Webhook struct {
+	URL            string
+	Secret         string
+	PreviousSecret string
+	Headers        map[string]string
+}
+
+// WebhookPayload is the body of every webhook. title/text are kept for
+// receivers written against the old {title,text} body.
+type WebhookPayload struct {
+	Version    int       `json:"version"`
+	DeliveryID string    `json:"deliveryId"`
+	Type       EventType `json:"type"`
+	Title      string    `json:"title"`
+	Text       string    `json:"text"`
+	Event      Event     `json:"event"`
+}
+
+func (w Webhook) Notify(ctx context.Context, e Event) error {
+	id := DeliveryID(ctx)
+	b, err := json.Marshal(WebhookPayload{
+		Version:    WebhookVersion,
+		DeliveryID: id,
+		Type:       e.Type,
+		Title:      e.Title(),
+		Text:       e.Text(),
+		Event:      e,
+	})
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode/100 != 2 {
-		return fmt.Errorf("webhook %d", resp.StatusCode)
+	header := http.Header{}
+	for k, v := range w.Headers {
+		if !reservedHeader(k) {
+			header.Set(k, v)
+		}
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	header.Set(HeaderDelivery, id)
+	header.Set(HeaderTimestamp, ts)
+	var sigs []string
+	for _, secret := range []string{w.Secret, w.PreviousSecret} {
+		if secret != "" {
+			sigs = append(sigs, "v1="+signWebhook(secret, ts, b))
+		}
+	}
+	if len(sigs) > 0 {
+		header.Set(HeaderSignature, strings.Join(sigs, ","))
+	}
+	return sendBody(ctx, http.MethodPost, "webhook", w.URL, b, header)
+}
+
+// reservedHeader reports whether custom headers may not set k: the
+// signing headers, which receivers trust, and the body's Content-Type.
+func reservedHeader(k string) bool {
+	k = http.CanonicalHeaderKey(k)
+	return k == "Content-Type" || strings.HasPrefix(k, "X-Serverwatcher-")
+}
+
+func signWebhook(secret, ts string, body []byte) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(ts))
+	m.Write([]byte("."))
+	m.Write(body)
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+var (
+	ErrSignatureMismatch = errors.New("webhook signature mismatch")
+	ErrSignatureExpired  = errors.New("webhook timestamp outside tolerance")
+)
+
+// VerifyWebhook checks a received webhook: one of the signatures must
+// match secret and the timestamp must be within tolerance of now, which
+// stops old deliveries from being replayed. Receivers should also drop
+// delivery IDs they have already seen.
+func VerifyWebhook(secret string, header http.Header, body []byte, now time.Time, tolerance time.Duration) error {
+	ts := header.Get(HeaderTimestamp)
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrSignatureExpired
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > tolerance || d < -tolerance {
+		return ErrSignatureExpired
+	}
+	want := signWebhook(secret, ts, body)
+	for _, sig := range strings.Split(header.Get(HeaderSignature), ",") {
+		v, ok := strings.CutPrefix(strings.TrimSpace(sig), "v1=")
+		if ok && hmac.Equal([]byte(v), []byte(want)) {
+			return nil
+		}
 	}
-	return nil
+	return ErrSignatureMismatch
 }
@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookPayload(t *testing.T) {
+	s := newStub(t, http.StatusOK)
+	w := Webhook{URL: s.URL + "/hook", Headers: map[string]string{"Authorization": "Bearer x"}}
+	ctx := WithDeliveryID(context.Background(), "42-1700000000000")
+	if err := w.Notify(ctx, downEvent()); err != nil {
+		t.Fatal(err)
+	}
+	r := s.only(t)
+	if r.Method != http.MethodPost || r.Path != "/hook" {
+		t.Errorf("%s %s", r.Method, r.Path)
+	}
+	if r.Header.Get("Authorization") != "Bearer x" || r.Header.Get(HeaderDelivery) != "42-1700000000000" {
+		t.Errorf("headers = %v", r.Header)
+	}
+	if r.Header.Get(HeaderSignature) != "" {
+		t.Error("signed without a secret")
+	}
+	var p WebhookPayload
+	if err := json.Unmarshal(r.Body, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Version != WebhookVersion || p.DeliveryID != "42-1700000000000" || p.Type != EventDown ||
+		p.Title != "[DOWN] api" || p.Text == "" {
+		t.Errorf("payload = %+v", p)
+	}
+	if p.Event.AlertKey != "incident-12" || p.Event.IncidentID != 12 || !p.Event.StartedAt.Equal(testNow) {
+		t.Errorf("event = %+v", p.Event)
+	}
+}
+
+func TestWebhookReservedHeaders(t *testing.T) {
+	s := newStub(t, http.StatusOK)
+	w := Webhook{URL: s.URL, Headers: map[string]string{
+		"content-type":              "text/plain",
+		"X-Serverwatcher-Signature": "v1=forged",
+		"X-Team":                    "ops",
+	}}
+	if err := w.Notify(context.Background(), downEvent()); err != nil {
+		t.Fatal(err)
+	}
+	r := s.only(t)
+	if ct := r.Header.Values("Content-Type"); len(ct) != 1 || ct[0] != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if sig := r.Header.Values(HeaderSignature); len(sig) != 0 {
+		t.Errorf("signature = %q, want none without a secret", sig)
+	}
+	if r.Header.Get("X-Team") != "ops" {
+		t.Errorf("headers = %v", r.Header)
+	}
+}
+
+func TestWebhookSignatureVerifies(t *testing.T) {
+	s := newStub(t, http.StatusOK)
+	w := Webhook{URL: s.URL, Secret: "new", PreviousSecret: "old"}
+	if err := w.Notify(context.Background(), downEvent()); err != nil {
+		t.Fatal(err)
+	}
+	r := s.only(t)
+	if n := len(strings.Split(r.Header.Get(HeaderSignature), ",")); n != 2 {
+		t.Fatalf("got %d signatures, want one per secret", n)
+	}
+	now := time.Now()
+	for _, secret := range []string{"new", "old"} {
+		if err := VerifyWebhook(secret, r.Header, r.Body, now, 5*time.Minute); err != nil {
+			t.Errorf("secret %q: %v", secret, err)
+		}
+	}
+	if err := VerifyWebhook("other", r.Header, r.Body, now, 5*time.Minute); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("wrong secret: err = %v", err)
+	}
+	tampered := append([]byte(nil), r.Body...)
+	tampered[len(tampered)-2] ^= 1
+	if err := VerifyWebhook("new", r.Header, tampered, now, 5*time.Minute); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("tampered body: err = %v", err)
+	}
+	if id := r.Header.Get(HeaderDelivery); id == "" {
+		t.Error("no delivery ID without one in the context")
+	}
+}
+
+func TestVerifyWebhookTimestamp(t *testing.T) {
+	body := []byte(`{"version":1}`)
+	sent := time.Unix(1_700_000_000, 0)
+	ts := strconv.FormatInt(sent.Unix(), 10)
+	header := http.Header{}
+	header.Set(HeaderTimestamp, ts)
+	header.Set(HeaderSignature, "v1="+signWebhook("s", ts, body))
+
+	for _, c := range []struct {
+		name string
+		now  time.Time
+		want error
+	}{
+		{"fresh", sent.Add(time.Minute), nil},
+		{"at tolerance", sent.Add(5 * time.Minute), nil},
+		{"replayed", sent.Add(6 * time.Minute), ErrSignatureExpired},
+		{"from the future", sent.Add(-6 * time.Minute), ErrSignatureExpired},
+	} {
+		if err := VerifyWebhook("s", header, body, c.now, 5*time.Minute); !errors.Is(err, c.want) {
+			t.Errorf("%s: err = %v, want %v", c.name, err, c.want)
+		}
+	}
+
+	// the timestamp is signed: moving it forward breaks the signature
+	moved := header.Clone()
+	moved.Set(HeaderTimestamp, strconv.FormatInt(sent.Add(time.Hour).Unix(), 10))
+	if err := VerifyWebhook("s", moved, body, sent.Add(time.Hour), 5*time.Minute); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("moved timestamp: err = %v", err)
+	}
+
+	missing := header.Clone()
+	missing.Del(HeaderTimestamp)
+	if err := VerifyWebhook("s", missing, body, sent, 5*time.Minute); !errors.Is(err, ErrSignatureExpired) {
+		t.Errorf("no timestamp: err = %v", err)
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	h, err := parseHeaders("Authorization: Bearer abc\n\n X-Team : ops \n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != 2 || h["Authorization"] != "Bearer abc" || h["X-Team"] != "ops" {
+		t.Errorf("headers = %v", h)
+	}
+	for _, bad := range []string{"no colon", ": empty name", "Bad Name: x",
+		"content-type: text/plain", "X-Serverwatcher-Signature: v1=forged", "x-serverwatcher-delivery: 1"} {
+		if _, err := parseHeaders(bad); err == nil {
+			t.Errorf("parseHeaders(%q) accepted", bad)
+		}
+	}
+}
+
+func TestWebhookSecretsRedacted(t *testing.T) {
+	for _, k := range []string{"url", "headers", "secret", "previousSecret"} {
+		if !IsSecret("webhook", k) {
+			t.Errorf("webhook %s is not a secret", k)
+		}
+	}
+}
@@ -0,0 +1,478 @@
+package service
+
+import (
+	"bufio"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+	"time"
+)
+
+// BackupVersion is bumped whenever the archive layout changes incompatibly.
+// Version 2 added notification config: channels, routes, digest,
+// escalation policies, schedules and burn alerts.
+const BackupVersion = 2
+
+// Backup is a full snapshot of an instance. Checks/Rollups are only
+// filled when history was requested. Channels are stored with their
+// secrets, so the archive must be kept as safe as the instance itself.
+type Backup struct {
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"createdAt"`
+	Services  []*Service     `json:"services"`
+	Policy    IncidentPolicy `json:"policy"`
+	Silences  []*Silence     `json:"silences"`
+	Incidents []*Incident    `json:"incidents"`
+
+	Channels    []*NotificationChannel `json:"channels,omitempty"`
+	Routes      []RouteRule            `json:"routes,omitempty"`
+	Digest      DigestConfig           `json:"digest"`
+	Escalations []*EscalationPolicy    `json:"escalations,omitempty"`
+	Schedules   []*Schedule            `json:"schedules,omitempty"`
+	BurnAlerts  []*BurnAlert           `json:"burnAlerts,omitempty"`
+
+	Checks  []StatusResult `json:"checks,omitempty"`
+	Rollups []Rollup       `json:"rollups,omitempty"`
+}
+
+// RestoredService maps a service in the archive to its ID on this instance.
+type RestoredService struct {
+	OldID int    `json:"oldId"`
+	NewID int    `json:"newId"`
+	Name  string `json:"name"`
+	URL   string `json:"url"`
+}
+
+// RestorePlan describes what a restore does (or would do, on a dry run).
+// Services that already exist here (same name and URL) are matched, not
+// duplicated, and keep their own incidents and history. Channels,
+// escalation policies and schedules are matched by name the same way;
+// routes and the digest replace the current ones, like the policy.
+type RestorePlan struct {
+	DryRun             bool              `json:"dryRun"`
+	Version            int               `json:"version"`
+	ServicesCreated    []RestoredService `json:"servicesCreated"`
+	ServicesMatched    []RestoredService `json:"servicesMatched"`
+	Incidents          int               `json:"incidents"`
+	Silences           int               `json:"silences"`
+	Checks             int               `json:"checks"`
+	Rollups            int               `json:"rollups"`
+	PolicyChanged      bool              `json:"policyChanged"`
+	ChannelsCreated    []string          `json:"channelsCreated"`
+	EscalationsCreated []string          `json:"escalationsCreated"`
+	SchedulesCreated   []string          `json:"schedulesCreated"`
+	BurnAlerts         int               `json:"burnAlerts"`
+	RoutesChanged      bool              `json:"routesChanged"`
+	DigestChanged      bool              `json:"digestChanged"`
+}
+
+// WriteBackup encodes a backup as gzipped JSON.
+func WriteBackup(w io.Writer, b *Backup) error {
+	zw := gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(b); err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+// ReadBackup decodes a backup, gzipped or plain JSON.
+func ReadBackup(r io.Reader) (*Backup, error) {
+	br := bufio.NewReader(r)
+	var src io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		src = zr
+	}
+	var b Backup
+	if err := json.NewDecoder(src).Decode(&b); err != nil {
+		return nil, err
+	}
+	if b.Version <= 0 || b.Version > BackupVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", b.Version)
+	}
+	return &b, nil
+}
+
+// Snapshot takes a consistent copy of the store, plus SQLite history
+// (or the in-memory samples without SQLite) when withHistory is set.
+func (s *Store) Snapshot(withHistory bool) (*Backup, error) {
+	s.Lock()
+	b := &Backup{
+		Version:   BackupVersion,
+		CreatedAt: time.Now().UTC(),
+		Policy:    s.policy,
+		Routes:    slices.Clone(s.routes),
+		Digest:    s.digest,
+	}
+	for _, svc := range s.services {
+		c := *svc
+		b.Services = append(b.Services, &c)
+	}
+	for _, incs := range s.Incidents {
+		for _, inc := range incs {
+			c := *inc
+			b.Incidents = append(b.Incidents, &c)
+		}
+	}
+	for _, sl := range s.silences {
+		c := *sl
+		b.Silences = append(b.Silences, &c)
+	}
+	for _, ch := range s.channelConfigs {
+		c := *ch
+		c.Settings = maps.Clone(ch.Settings)
+		c.Templates = maps.Clone(ch.Templates)
+		b.Channels = append(b.Channels, &c)
+	}
+	for _, p := range s.escalations {
+		c := *p
+		b.Escalations = append(b.Escalations, &c)
+	}
+	for _, sc := range s.schedules {
+		c := *sc
+		b.Schedules = append(b.Schedules, &c)
+	}
+	for _, a := range s.burnAlerts {
+		c := *a
+		b.BurnAlerts = append(b.BurnAlerts, &c)
+	}
+	db := s.db
+	var hist []StatusResult
+	if withHistory && db == nil {
+		for _, h := range s.histories {
+			hist = append(hist, h...)
+		}
+	}
+	s.Unlock()
+
+	sort.Slice(b.Services, func(i, j int) bool { return b.Services[i].ID < b.Services[j].ID })
+	sort.Slice(b.Incidents, func(i, j int) bool { return b.Incidents[i].ID < b.Incidents[j].ID })
+
+	if !withHistory {
+		return b, nil
+	}
+	if db == nil {
+		b.Checks = hist
+		return b, nil
+	}
+	var err error
+	if b.Checks, err = db.exportChecks(); err != nil {
+		return nil, err
+	}
+	if b.Rollups, err = db.exportRollups(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Restore loads a backup into this instance, giving every new service,
+// incident and silence a fresh ID. With dryRun nothing is changed and the
+// returned plan shows what would happen.
+func (s *Store) Restore(b *Backup, dryRun bool) (*RestorePlan, error) {
+	s.Lock()
+	s.ensureMaps()
+	plan := &RestorePlan{
+		DryRun:             dryRun,
+		Version:            b.Version,
+		ServicesCreated:    []RestoredService{},
+		ServicesMatched:    []RestoredService{},
+		PolicyChanged:      b.Policy != s.policy,
+		ChannelsCreated:    []string{},
+		EscalationsCreated: []string{},
+		SchedulesCreated:   []string{},
+	}
+
+	existing := make(map[[2]string]int, len(s.services))
+	for _, svc := range s.services {
+		existing[[2]string{svc.Name, svc.URL}] = svc.ID
+	}
+
+	// ID remapping: old (archive) -> new (this instance)
+	idMap := make(map[int]int, len(b.Services))
+	created := make(map[int]bool, len(b.Services))
+	nextID := s.nextID
+	if nextID <= 0 {
+		nextID = 1
+	}
+	var newSvcs []*Service
+	for _, svc := range b.Services {
+		rs := RestoredService{OldID: svc.ID, Name: svc.Name, URL: svc.URL}
+		if id, ok := existing[[2]string{svc.Name, svc.URL}]; ok {
+			rs.NewID = id
+			idMap[svc.ID] = id
+			plan.ServicesMatched = append(plan.ServicesMatched, rs)
+			continue
+		}
+		rs.NewID = nextID
+		idMap[svc.ID] = nextID
+		created[svc.ID] = true
+		c := *svc
+		c.ID = nextID
+		newSvcs = append(newSvcs, &c)
+		plan.ServicesCreated = append(plan.ServicesCreated, rs)
+		nextID++
+	}
+
+	nextIncidentID := s.nextIncidentID
+	var newIncs []*Incident
+	for _, inc := range b.Incidents {
+		if !created[inc.ServiceID] && !(inc.Manual && inc.ServiceID == 0) {
+			continue // group incidents have no service to map
+		}
+		c := *inc
+		c.ID = nextIncidentID
+		c.ServiceID = idMap[inc.ServiceID]
+		nextIncidentID++
+		newIncs = append(newIncs, &c)
+	}
+	plan.Incidents = len(newIncs)
+
+	nextSilenceID := s.nextSilenceID
+	var newSils []*Silence
+	for _, sl := range b.Silences {
+		c := *sl
+		if sl.ServiceID != nil {
+			id, ok := idMap[*sl.ServiceID]
+			if !ok {
+				continue // targets a service that isn't in the archive
+			}
+			c.ServiceID = &id
+		}
+		nextSilenceID++
+		c.ID = nextSilenceID
+		newSils = append(newSils, &c)
+	}
+	plan.Silences = len(newSils)
+
+	var checks []StatusResult
+	for _, c := range b.Checks {
+		if created[c.ID] {
+			c.ID = idMap[c.ID]
+			checks = append(checks, c)
+		}
+	}
+	var rollups []Rollup
+	for _, r := range b.Rollups {
+		if created[r.ServiceID] {
+			r.ServiceID = idMap[r.ServiceID]
+			rollups = append(rollups, r)
+		}
+	}
+	plan.Checks = len(checks)
+	plan.Rollups = len(rollups)
+
+	nextBurnAlertID := s.nextBurnAlertID
+	var newBurns []*BurnAlert
+	for _, a := range b.BurnAlerts {
+		if !created[a.ServiceID] {
+			continue
+		}
+		c := *a
+		c.ID = nextBurnAlertID
+		c.ServiceID = idMap[a.ServiceID]
+		nextBurnAlertID++
+		newBurns = append(newBurns, &c)
+	}
+	plan.BurnAlerts = len(newBurns)
+
+	// channels, escalation policies and schedules already here by name win
+	nextChannelID := s.nextChannelID
+	var newChans []*NotificationChannel
+	for _, ch := range b.Channels {
+		if slices.ContainsFunc(s.channelConfigs, func(c *NotificationChannel) bool { return c.Name == ch.Name }) {
+			continue
+		}
+		c := *ch
+		nextChannelID++
+		c.ID = nextChannelID
+		newChans = append(newChans, &c)
+		plan.ChannelsCreated = append(plan.ChannelsCreated, c.Name)
+	}
+	nextEscalationID := s.nextEscalationID
+	var newEscs []*EscalationPolicy
+	for _, p := range b.Escalations {
+		if slices.ContainsFunc(s.escalations, func(e *EscalationPolicy) bool { return e.Name == p.Name }) {
+			continue
+		}
+		c := *p
+		nextEscalationID++
+		c.ID = nextEscalationID
+		c.ServiceIDs = remapIDs(p.ServiceIDs, idMap)
+		newEscs = append(newEscs, &c)
+		plan.EscalationsCreated = append(plan.EscalationsCreated, c.Name)
+	}
+	nextScheduleID := s.nextScheduleID
+	var newScheds []*Schedule
+	for _, sc := range b.Schedules {
+		if slices.ContainsFunc(s.schedules, func(x *Schedule) bool { return x.Name == sc.Name }) {
+			continue
+		}
+		c := *sc
+		nextScheduleID++
+		c.ID = nextScheduleID
+		newScheds = append(newScheds, &c)
+		plan.SchedulesCreated = append(plan.SchedulesCreated, c.Name)
+	}
+
+	// version 1 archives carry no routes or digest; keep ours then
+	var routes []RouteRule
+	if b.Version >= 2 {
+		for _, r := range b.Routes {
+			r.ServiceIDs = remapIDs(r.ServiceIDs, idMap)
+			routes = append(routes, r)
+		}
+		plan.RoutesChanged = !reflect.DeepEqual(routes, s.routes)
+		plan.DigestChanged = !reflect.DeepEqual(b.Digest, s.digest)
+	}
+
+	if dryRun {
+		s.Unlock()
+		return plan, nil
+	}
+
+	db := s.db
+	if db != nil {
+		// history first: if it fails, the store is left untouched
+		if err := db.importHistory(checks, rollups); err != nil {
+			s.Unlock()
+			return nil, err
+		}
+	}
+
+	for _, svc := range newSvcs {
+		s.services[svc.ID] = svc
+	}
+	s.nextID = nextID
+	sort.Slice(newIncs, func(i, j int) bool { return newIncs[i].StartedAt.Before(newIncs[j].StartedAt) })
+	for _, inc := range newIncs {
+		s.Incidents[inc.ServiceID] = append(s.Incidents[inc.ServiceID], inc)
+		if inc.EndedAt == nil && !inc.Manual {
+			s.openIncident[inc.ServiceID] = inc
+			s.lastStatus[inc.ServiceID] = "FAIL"
+		}
+	}
+	s.nextIncidentID = nextIncidentID
+	s.silences = append(s.silences, newSils...)
+	s.nextSilenceID = nextSilenceID
+	s.policy = b.Policy
+	if s.policy == (IncidentPolicy{}) {
+		s.policy = defaultPolicy()
+	}
+	s.burnAlerts = append(s.burnAlerts, newBurns...)
+	s.nextBurnAlertID = nextBurnAlertID
+	s.channelConfigs = append(s.channelConfigs, newChans...)
+	s.nextChannelID = nextChannelID
+	s.applyChannelsLocked()
+	s.escalations = append(s.escalations, newEscs...)
+	s.nextEscalationID = nextEscalationID
+	s.schedules = append(s.schedules, newScheds...)
+	s.nextScheduleID = nextScheduleID
+	if b.Version >= 2 {
+		s.routes = routes
+		s.digest = b.Digest
+	}
+	if db == nil {
+		for _, c := range checks {
+			s.histories[c.ID] = append(s.histories[c.ID], c)
+		}
+	}
+	err := s.saveLocked()
+	s.Unlock()
+
+	for _, svc := range newSvcs {
+		if svc.Active {
+			s.RestartChecker(svc)
+		}
+	}
+	return plan, err
+}
+
+// remapIDs maps archive service IDs to this instance's, dropping any the
+// archive didn't include.
+func remapIDs(ids []int, idMap map[int]int) []int {
+	var out []int
+	for _, id := range ids {
+		if n, ok := idMap[id]; ok {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func (s *SQLStore) exportChecks() ([]StatusResult, error) {
+	rows, err := s.DB.Query(`SELECT service_id, ts, status, latency_ms FROM checks ORDER BY service_id, ts`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []StatusResult
+	for rows.Next() {
+		var r StatusResult
+		if err := rows.Scan(&r.ID, &r.CheckedAt, &r.Status, &r.ResponseMs); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLStore) exportRollups() ([]Rollup, error) {
+	rows, err := s.DB.Query(`SELECT service_id, resolution, bucket, count, failures, min_ms, avg_ms, max_ms, p95_ms, sketch
+FROM rollups ORDER BY service_id, resolution, bucket`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Rollup
+	for rows.Next() {
+		var r Rollup
+		var res, bucket string
+		var sk sql.NullString
+		if err := rows.Scan(&r.ServiceID, &res, &bucket, &r.Count, &r.Failures,
+			&r.MinMs, &r.AvgMs, &r.MaxMs, &r.P95Ms, &sk); err != nil {
+			return nil, err
+		}
+		r.Resolution = Resolution(res)
+		r.Sketch = decodeSketch(sk.String)
+		if r.Bucket, err = time.Parse(time.RFC3339, bucket); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLStore) importHistory(checks []StatusResult, rollups []Rollup) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, c := range checks {
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO checks(service_id, ts, status, latency_ms) VALUES(?,?,?,?)`,
+			c.ID, c.CheckedAt, c.Status, c.ResponseMs); err != nil {
+			return err
+		}
+	}
+	for _, r := range rollups {
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO rollups
+(service_id, resolution, bucket, count, failures, min_ms, avg_ms, max_ms, p95_ms, sketch)
+VALUES(?,?,?,?,?,?,?,?,?,?)`,
+			r.ServiceID, string(r.Resolution), r.Bucket.UTC().Format(time.RFC3339),
+			r.Count, r.Failures, r.MinMs, r.AvgMs, r.MaxMs, r.P95Ms, encodeSketch(r.Sketch)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
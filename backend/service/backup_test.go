@@ -0,0 +1,225 @@
+package service
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sourceStore has two services, one with a closed and an open incident,
+// a silence on each service and one by tag.
+func sourceStore(t *testing.T) *Store {
+	t.Helper()
+	s := NewStore()
+	api := addTestService(s, "api", "https://api.example.com/health")
+	web := addTestService(s, "web", "https://example.com")
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(5 * time.Minute)
+	s.Incidents[api.ID] = []*Incident{
+		{ID: 1, ServiceID: api.ID, StartedAt: start, EndedAt: &end, DurationS: 300},
+		{ID: 2, ServiceID: api.ID, StartedAt: start.Add(time.Hour)},
+	}
+	s.nextIncidentID = 3
+	s.NewSilence(&api.ID, "", start.Add(24*time.Hour), "deploy")
+	s.NewSilence(&web.ID, "", start.Add(24*time.Hour), "")
+	s.NewSilence(nil, "team:web", start.Add(24*time.Hour), "")
+	s.policy = IncidentPolicy{OpenConsecutiveFails: 3, OpenSeconds: 5, CloseConsecutiveOKs: 2, AlertCooldownSec: 30}
+	return s
+}
+
+func TestRestoreRemapsIDs(t *testing.T) {
+	b, err := sourceStore(t).Snapshot(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := NewStore()
+	addTestService(dst, "local", "http://localhost")         // takes ID 1
+	web := addTestService(dst, "web", "https://example.com") // matched by name and URL
+	dst.Incidents[web.ID] = []*Incident{{ID: 1, ServiceID: web.ID}}
+	dst.nextIncidentID = 2
+
+	plan, err := dst.Restore(b, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.ServicesCreated) != 1 || len(plan.ServicesMatched) != 1 {
+		t.Fatalf("plan = %+v", plan)
+	}
+	created, matched := plan.ServicesCreated[0], plan.ServicesMatched[0]
+	if created.Name != "api" || created.OldID != 1 || created.NewID != 3 {
+		t.Errorf("created = %+v", created)
+	}
+	if matched.Name != "web" || matched.OldID != 2 || matched.NewID != web.ID {
+		t.Errorf("matched = %+v", matched)
+	}
+	if plan.Incidents != 2 || plan.Silences != 3 || !plan.PolicyChanged {
+		t.Errorf("plan = %+v", plan)
+	}
+
+	incs := dst.Incidents[3]
+	if len(incs) != 2 || incs[0].ID != 2 || incs[1].ID != 3 || incs[0].ServiceID != 3 {
+		t.Fatalf("restored incidents = %+v", incs)
+	}
+	if dst.openIncident[3] != incs[1] || dst.lastStatus[3] != "FAIL" {
+		t.Error("open incident not restored as open")
+	}
+	if len(dst.Incidents[web.ID]) != 1 {
+		t.Error("matched service got the archive's incidents")
+	}
+
+	targets := map[int]bool{}
+	for _, sl := range dst.silences {
+		if sl.ServiceID != nil {
+			targets[*sl.ServiceID] = true
+		} else if sl.Tag != "team:web" {
+			t.Errorf("tag silence = %+v", sl)
+		}
+	}
+	if !targets[3] || !targets[web.ID] || len(targets) != 2 {
+		t.Errorf("silences target %v, want services 3 and %d", targets, web.ID)
+	}
+	if dst.nextID != 4 || dst.nextIncidentID != 4 || dst.policy.OpenConsecutiveFails != 3 {
+		t.Errorf("nextID %d, nextIncidentID %d, policy %+v", dst.nextID, dst.nextIncidentID, dst.policy)
+	}
+}
+
+func TestRestoreDryRunChangesNothing(t *testing.T) {
+	b, _ := sourceStore(t).Snapshot(false)
+	dst := NewStore()
+	plan, err := dst.Restore(b, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.DryRun || len(plan.ServicesCreated) != 2 || plan.Incidents != 2 {
+		t.Errorf("plan = %+v", plan)
+	}
+	if len(dst.services) != 0 || len(dst.silences) != 0 || dst.nextID != 1 {
+		t.Error("dry run changed the store")
+	}
+}
+
+func TestRestoreHistoryFromSQLite(t *testing.T) {
+	src := sourceStore(t)
+	srcDB := openTestSQL(t)
+	src.SetSQLStore(srcDB)
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	insertCheck(t, srcDB, 1, at, "OK", 10)
+	insertCheck(t, srcDB, 1, at.Add(time.Minute), "FAIL", 0)
+	insertCheck(t, srcDB, 2, at, "OK", 20)
+	srcDB.rollup(ResMinute, at.Add(2*time.Minute))
+
+	b, err := src.Snapshot(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Checks) != 3 || len(b.Rollups) != 3 {
+		t.Fatalf("snapshot has %d checks and %d rollups", len(b.Checks), len(b.Rollups))
+	}
+
+	dst := NewStore()
+	dstDB := openTestSQL(t)
+	dst.SetSQLStore(dstDB)
+	addTestService(dst, "web", "https://example.com")
+	plan, err := dst.Restore(b, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only the created service (api, now 2) brings its history along
+	if plan.Checks != 2 || plan.Rollups != 2 {
+		t.Errorf("plan = %+v", plan)
+	}
+	var n int
+	dstDB.DB.QueryRow(`SELECT COUNT(*) FROM checks WHERE service_id = 2`).Scan(&n)
+	if n != 2 {
+		t.Errorf("restored %d checks for service 2", n)
+	}
+	dstDB.DB.QueryRow(`SELECT COUNT(*) FROM rollups WHERE service_id != 2`).Scan(&n)
+	if n != 0 {
+		t.Errorf("restored %d rollups for other services", n)
+	}
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	b, _ := sourceStore(t).Snapshot(false)
+	var buf bytes.Buffer
+	if err := WriteBackup(&buf, b); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadBackup(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Services) != 2 || len(got.Incidents) != 2 || len(got.Silences) != 3 || got.Version != BackupVersion {
+		t.Errorf("round trip = %+v", got)
+	}
+
+	// plain JSON is accepted too
+	if _, err := ReadBackup(strings.NewReader(`{"version":1,"services":[]}`)); err != nil {
+		t.Errorf("plain JSON: %v", err)
+	}
+	for _, bad := range []string{`{"version":0}`, `{"version":99}`, `not json`} {
+		if _, err := ReadBackup(strings.NewReader(bad)); err == nil {
+			t.Errorf("ReadBackup(%s) accepted", bad)
+		}
+	}
+}
+
+func TestRestoreNotificationConfig(t *testing.T) {
+	src := sourceStore(t)
+	hook := NotificationChannel{Name: "hooks", Type: "webhook", Settings: map[string]string{"url": "http://127.0.0.1:1"}}
+	mail := NotificationChannel{Name: "mail", Type: "webhook", Settings: map[string]string{"url": "http://127.0.0.1:2"}}
+	for _, c := range []NotificationChannel{hook, mail} {
+		if _, err := src.CreateChannel(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	api := 1
+	if err := src.SetRoutes([]RouteRule{{Name: "api", ServiceIDs: []int{api, 42}, Channels: []string{"hooks"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.SetDigestConfig(DigestConfig{Channel: "mail", Hour: 8}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.CreateEscalationPolicy(EscalationPolicy{Name: "api pages", ServiceIDs: []int{api},
+		Steps: []EscalationStep{{Channels: []string{"hooks"}}}}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := src.Snapshot(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Channels) != 2 || b.Channels[0].Settings["url"] != "http://127.0.0.1:1" {
+		t.Fatalf("archived channels = %+v", b.Channels)
+	}
+
+	dst := NewStore()
+	addTestService(dst, "local", "http://localhost") // api becomes 2, web 3
+	if _, err := dst.CreateChannel(NotificationChannel{Name: "mail", Type: "webhook", Settings: map[string]string{"url": "http://127.0.0.1:3"}}); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := dst.Restore(b, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.ChannelsCreated) != 1 || plan.ChannelsCreated[0] != "hooks" || len(plan.EscalationsCreated) != 1 ||
+		!plan.RoutesChanged || !plan.DigestChanged {
+		t.Errorf("plan = %+v", plan)
+	}
+	// the local "mail" channel wins over the archived one
+	for _, c := range dst.ListChannels() {
+		if c.Name == "mail" && c.ID != 1 {
+			t.Errorf("mail = %+v", c)
+		}
+	}
+	if r := dst.GetRoutes(); len(r) != 1 || len(r[0].ServiceIDs) != 1 || r[0].ServiceIDs[0] != 2 {
+		t.Errorf("routes = %+v", r)
+	}
+	if p := dst.ListEscalationPolicies(); len(p) != 1 || p[0].ServiceIDs[0] != 2 {
+		t.Errorf("escalations = %+v", p)
+	}
+	if d := dst.GetDigestConfig(); d.Channel != "mail" || d.Hour != 8 {
+		t.Errorf("digest = %+v", d)
+	}
+}
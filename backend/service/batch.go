@@ -0,0 +1,141 @@
+package service
+
+import (
+	"fmt"
+	"serverwatcher/notify"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultGroupMinEvents = 3
+
+// maxGroupLines caps how many services an aggregated alert lists.
+const maxGroupLines = 20
+
+// eventBatch collects the events of one grouping window for one channel.
+type eventBatch struct {
+	channel string
+	group   string // tag the batch is grouped by, e.g. "env:prod"
+	events  []notify.Event
+}
+
+// groupTag returns the tag an event is grouped by: its first tag with the
+// policy's key, or "" when grouping by all services.
+func groupTag(key string, tags []string) string {
+	if key == "" {
+		return ""
+	}
+	for _, t := range tags {
+		if strings.HasPrefix(t, key+":") {
+			return t
+		}
+	}
+	return ""
+}
+
+// batchLocked adds the event to the channel's open batch, starting the
+// window if needed. It reports false when the event isn't batched and
+// must be delivered right away. Channels that track alerts by key
+// (PagerDuty, Opsgenie, email) never batch: an aggregate's key would open
+// a page or thread that no per-incident UP or ACK closes. Caller must
+// hold the lock.
+func (s *Store) batchLocked(channel string, e notify.Event) bool {
+	p := s.policy
+	if p.GroupWindowSec <= 0 || e.Reminder > 0 || e.Escalation > 0 {
+		return false
+	}
+	if e.Type != notify.EventDown && e.Type != notify.EventUp {
+		return false
+	}
+	if c, ok := s.channels[channel]; !ok || c.KeyedByAlert() {
+		return false
+	}
+	group := groupTag(p.GroupByTag, e.Tags)
+	key := channel + "\x00" + group + "\x00" + string(e.Type)
+	if s.batches == nil {
+		s.batches = make(map[string]*eventBatch)
+	}
+	b := s.batches[key]
+	if b == nil {
+		b = &eventBatch{channel: channel, group: group}
+		s.batches[key] = b
+		time.AfterFunc(time.Duration(p.GroupWindowSec)*time.Second, func() { s.flushBatch(key) })
+	}
+	b.events = append(b.events, e)
+	return true
+}
+
+// flushBatch sends a closed window: one aggregated alert when it reached
+// the policy's minimum, otherwise the events one by one.
+func (s *Store) flushBatch(key string) {
+	s.Lock()
+	b := s.batches[key]
+	delete(s.batches, key)
+	min := s.policy.GroupMinEvents
+	s.Unlock()
+	if b == nil {
+		return
+	}
+	if min <= 0 {
+		min = defaultGroupMinEvents
+	}
+	if len(b.events) < min {
+		for _, e := range b.events {
+			s.deliver(b.channel, e)
+		}
+	} else {
+		s.deliver(b.channel, aggregateEvents(b.group, b.events, time.Now().UTC()))
+	}
+	s.wakeDeliveries()
+}
+
+// aggregateEvents folds a batch into one alert, e.g.
+// "[DOWN] 12 services in env:prod".
+func aggregateEvents(group string, events []notify.Event, now time.Time) notify.Event {
+	first := events[0]
+	sort.Slice(events, func(i, j int) bool { return events[i].ServiceName < events[j].ServiceName })
+
+	name := fmt.Sprintf("%d services", len(events))
+	if group != "" {
+		name += " in " + group
+	}
+	e := notify.Event{
+		Type:        first.Type,
+		Severity:    first.Severity,
+		ServiceName: name,
+		AlertKey:    fmt.Sprintf("group-%s-%s-%d", group, first.Type, first.OccurredAt.Unix()),
+		StartedAt:   first.StartedAt,
+		OccurredAt:  now,
+		Summary:     fmt.Sprintf("%d services %s", len(events), first.Type),
+	}
+	if group != "" {
+		e.Tags = []string{group}
+		e.Summary += " in " + group
+	}
+	if first.Resolved() {
+		e.EndedAt = &now
+	}
+	for _, ev := range events {
+		if ev.StartedAt.Before(e.StartedAt) {
+			e.StartedAt = ev.StartedAt
+		}
+		if ev.IncidentID > 0 {
+			e.Grouped = append(e.Grouped, ev.IncidentID)
+		}
+	}
+	var lines []string
+	for i, ev := range events {
+		if i == maxGroupLines {
+			lines = append(lines, fmt.Sprintf("... and %d more", len(events)-maxGroupLines))
+			break
+		}
+		line := ev.ServiceName
+		if ev.Reason != "" {
+			line += ": " + ev.Reason
+		}
+		lines = append(lines, line)
+	}
+	e.Reason = strings.Join(lines, "\n")
+	return e
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"fmt"
+	"serverwatcher/notify"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGroupTag(t *testing.T) {
+	tags := []string{"team:api", "env:prod", "env:eu"}
+	for key, want := range map[string]string{"": "", "env": "env:prod", "team": "team:api", "region": "", "en": ""} {
+		if got := groupTag(key, tags); got != want {
+			t.Errorf("groupTag(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestAggregateEvents(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var events []notify.Event
+	for i := 25; i > 0; i-- {
+		events = append(events, notify.Event{
+			Type: notify.EventDown, Severity: notify.SeverityCritical,
+			ServiceName: fmt.Sprintf("svc-%02d", i), Reason: "timeout",
+			StartedAt: now.Add(-time.Duration(i) * time.Second), OccurredAt: now,
+		})
+	}
+	e := aggregateEvents("env:prod", events, now)
+	if e.Title() != "[DOWN] 25 services in env:prod" || e.Summary != "25 services DOWN in env:prod" {
+		t.Errorf("title %q, summary %q", e.Title(), e.Summary)
+	}
+	if !e.StartedAt.Equal(now.Add(-25*time.Second)) || e.Resolved() || len(e.Tags) != 1 || e.Tags[0] != "env:prod" {
+		t.Errorf("event = %+v", e)
+	}
+	lines := strings.Split(e.Reason, "\n")
+	if len(lines) != maxGroupLines+1 || lines[0] != "svc-01: timeout" || lines[maxGroupLines] != "... and 5 more" {
+		t.Errorf("reason lines = %q", lines)
+	}
+
+	ups := []notify.Event{{Type: notify.EventUp, ServiceName: "a", EndedAt: &now}, {Type: notify.EventUp, ServiceName: "b", EndedAt: &now}}
+	if e := aggregateEvents("", ups, now); !e.Resolved() || e.Title() != "[UP] 2 services" || len(e.Tags) != 0 {
+		t.Errorf("recovery = %+v", e)
+	}
+}
+
+func TestBatchFlush(t *testing.T) {
+	s := NewStore()
+	s.SetSQLStore(openTestSQL(t))
+	s.SetNotifiers([]notify.Channel{{Name: "ops", Notifier: notify.Webhook{URL: "http://127.0.0.1:1"}}})
+	s.policy.GroupWindowSec = 3600 // flushed by hand below
+	s.policy.GroupByTag = "env"
+	s.policy.GroupMinEvents = 3
+
+	ev := func(name, env string) notify.Event {
+		return notify.Event{Type: notify.EventDown, ServiceName: name, Tags: []string{"env:" + env}}
+	}
+	s.Lock()
+	for _, e := range []notify.Event{ev("a", "prod"), ev("b", "prod"), ev("c", "prod"), ev("d", "dev")} {
+		if !s.batchLocked("ops", e) {
+			t.Errorf("%s not batched", e.ServiceName)
+		}
+	}
+	if s.batchLocked("ops", notify.Event{Type: notify.EventSLOBurn}) || s.batchLocked("ops", notify.Event{Type: notify.EventDown, Reminder: 1}) {
+		t.Error("burn alerts and reminders are batched")
+	}
+	var keys []string
+	for k := range s.batches {
+		keys = append(keys, k)
+	}
+	s.Unlock()
+	if len(keys) != 2 {
+		t.Fatalf("%d batches, want one per env", len(keys))
+	}
+	for _, k := range keys {
+		s.flushBatch(k)
+	}
+
+	titles := map[string]bool{}
+	for _, e := range queuedEvents(t, s) {
+		titles[e.Title()] = true
+	}
+	// prod reached the minimum and is folded, dev goes out on its own
+	if len(titles) != 2 || !titles["[DOWN] 3 services in env:prod"] || !titles["[DOWN] d"] {
+		t.Errorf("sent %v", titles)
+	}
+}
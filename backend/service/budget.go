@@ -0,0 +1,139 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// Calendar periods for error budget reports.
+const (
+	PeriodMonth   = "month"
+	PeriodQuarter = "quarter"
+)
+
+// PeriodBounds returns the calendar month/quarter containing t, in t's
+// location.
+func PeriodBounds(period string, t time.Time) (time.Time, time.Time, error) {
+	y, m, _ := t.Date()
+	switch period {
+	case PeriodMonth:
+		start := time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+		return start, start.AddDate(0, 1, 0), nil
+	case PeriodQuarter:
+		qm := time.Month((int(m)-1)/3*3 + 1)
+		start := time.Date(y, qm, 1, 0, 0, 0, 0, t.Location())
+		return start, start.AddDate(0, 3, 0), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("unknown period %q (month or quarter)", period)
+}
+
+// BudgetPoint is the error budget left at the end of one day.
+type BudgetPoint struct {
+	At              time.Time `json:"at"`
+	BadUnits        float64   `json:"badUnits"` // cumulative downtime seconds (time SLI) or failed checks (events SLI)
+	BudgetRemaining float64   `json:"budgetRemainingPercent"`
+}
+
+// PeriodDelta compares a metric between the current and previous period.
+type PeriodDelta struct {
+	Current  float64 `json:"current"`
+	Previous float64 `json:"previous"`
+	Change   float64 `json:"change"` // current - previous
+}
+
+func delta(cur, prev float64) PeriodDelta {
+	return PeriodDelta{Current: cur, Previous: prev, Change: cur - prev}
+}
+
+// BudgetReport is the error budget of one calendar period, day by day,
+// plus how the period compares to the one before it. Points stop at the
+// current time while the period is in progress; the budget is sized for
+// the whole period (time SLI) or for the checks seen so far (events SLI),
+// and Previous only covers as much of the previous period as has elapsed
+// of this one, up to PreviousEnd.
+type BudgetReport struct {
+	ServiceID int       `json:"serviceId"`
+	Period    string    `json:"period"`
+	Timezone  string    `json:"timezone"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Complete  bool      `json:"complete"`
+	SLI       SLIKind   `json:"sli"`
+	Target    float64   `json:"target"`
+
+	AllowedBadUnits float64       `json:"allowedBadUnits"`
+	Points          []BudgetPoint `json:"points"`
+
+	Current     Analytics `json:"current"`
+	Previous    Analytics `json:"previous"`
+	PreviousEnd time.Time `json:"previousEnd"`
+
+	Uptime    PeriodDelta `json:"uptimePercent"`
+	MTTR      PeriodDelta `json:"mttrSeconds"`
+	Incidents PeriodDelta `json:"incidentCount"`
+	P95       PeriodDelta `json:"p95Ms"`
+}
+
+// ErrorBudgetReport builds the report for the period containing `at`,
+// with day boundaries in at's location.
+func (s *Store) ErrorBudgetReport(id int, period string, at time.Time) (*BudgetReport, error) {
+	start, end, err := PeriodBounds(period, at)
+	if err != nil {
+		return nil, err
+	}
+	prevStart, _, _ := PeriodBounds(period, start.Add(-time.Nanosecond))
+
+	now := time.Now().In(at.Location())
+	until := minTime(end, now)
+	r := &BudgetReport{
+		ServiceID: id,
+		Period:    period,
+		Timezone:  at.Location().String(),
+		Start:     start,
+		End:       end,
+		Complete:  !now.Before(end),
+		Points:    []BudgetPoint{},
+	}
+
+	// the time budget is fixed by the length of the period (minus maintenance)
+	full := s.sloOver(id, span{start, end}, "")
+	r.SLI = full.SLI
+	r.Target = full.Target
+	budgetFrac := 1 - r.Target/100
+
+	var bad, total float64
+	if r.SLI == SLITime {
+		r.AllowedBadUnits = float64(full.EligibleSec) * budgetFrac
+	}
+	for day := start; day.Before(until); day = day.AddDate(0, 0, 1) {
+		dayEnd := minTime(day.AddDate(0, 0, 1), until)
+		d := s.sloOver(id, span{day, dayEnd}, r.SLI)
+		if r.SLI == SLIEvents {
+			bad += float64(d.TotalEvents - d.GoodEvents)
+			total += float64(d.TotalEvents)
+			r.AllowedBadUnits = total * budgetFrac
+		} else {
+			bad += float64(d.ObservedDowntimeSec)
+		}
+		p := BudgetPoint{At: dayEnd, BadUnits: bad, BudgetRemaining: 100}
+		if r.AllowedBadUnits > 0 {
+			p.BudgetRemaining = 100 * (1 - bad/r.AllowedBadUnits)
+		} else if bad > 0 {
+			p.BudgetRemaining = 0
+		}
+		r.Points = append(r.Points, p)
+	}
+
+	r.Current = s.AnalyticsBetween(id, start, until)
+	// a week into the month compares with the first week of the last one
+	r.PreviousEnd = start
+	if !r.Complete {
+		r.PreviousEnd = minTime(prevStart.Add(until.Sub(start)), start)
+	}
+	r.Previous = s.AnalyticsBetween(id, prevStart, r.PreviousEnd)
+	r.Uptime = delta(r.Current.UptimePercent, r.Previous.UptimePercent)
+	r.MTTR = delta(float64(r.Current.MTTRSeconds), float64(r.Previous.MTTRSeconds))
+	r.Incidents = delta(float64(r.Current.IncidentCount), float64(r.Previous.IncidentCount))
+	r.P95 = delta(float64(r.Current.P95Ms), float64(r.Previous.P95Ms))
+	return r, nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPeriodBounds(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	for _, c := range []struct {
+		period     string
+		at         time.Time
+		start, end string
+	}{
+		{PeriodMonth, time.Date(2026, 2, 14, 9, 0, 0, 0, time.UTC), "2026-02-01T00:00:00Z", "2026-03-01T00:00:00Z"},
+		{PeriodMonth, time.Date(2026, 12, 31, 23, 59, 0, 0, time.UTC), "2026-12-01T00:00:00Z", "2027-01-01T00:00:00Z"},
+		{PeriodQuarter, time.Date(2026, 5, 20, 0, 0, 0, 0, time.UTC), "2026-04-01T00:00:00Z", "2026-07-01T00:00:00Z"},
+		{PeriodQuarter, time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC), "2026-10-01T00:00:00Z", "2027-01-01T00:00:00Z"},
+		// local midnight, across the switch to summer time
+		{PeriodMonth, time.Date(2026, 3, 10, 0, 0, 0, 0, berlin), "2026-03-01T00:00:00+01:00", "2026-04-01T00:00:00+02:00"},
+	} {
+		start, end, err := PeriodBounds(c.period, c.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if start.Format(time.RFC3339) != c.start || end.Format(time.RFC3339) != c.end {
+			t.Errorf("%s of %v = [%v, %v), want [%s, %s)", c.period, c.at, start, end, c.start, c.end)
+		}
+	}
+	if _, _, err := PeriodBounds("week", time.Now()); err == nil {
+		t.Error("unknown period accepted")
+	}
+}
+
+func TestErrorBudgetReport(t *testing.T) {
+	s := NewStore()
+	svc := addTestService(s, "api", "https://api.example.com")
+	svc.SLOTargetPercent = 99.9
+	day := func(d, h int) time.Time { return time.Date(2025, 2, d, h, 0, 0, 0, time.UTC) }
+	janEnd, febEnd := time.Date(2025, 1, 20, 2, 0, 0, 0, time.UTC), day(10, 11)
+	s.Incidents[svc.ID] = []*Incident{
+		{ID: 1, ServiceID: svc.ID, StartedAt: janEnd.Add(-2 * time.Hour), EndedAt: &janEnd, DurationS: 7200},
+		{ID: 2, ServiceID: svc.ID, StartedAt: day(10, 10), EndedAt: &febEnd, DurationS: 3600},
+	}
+
+	r, err := s.ErrorBudgetReport(svc.ID, PeriodMonth, day(14, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Complete || r.SLI != SLITime || len(r.Points) != 28 {
+		t.Fatalf("complete %v, sli %s, %d points", r.Complete, r.SLI, len(r.Points))
+	}
+	if allowed := 28 * 86400 * 0.001; math.Abs(r.AllowedBadUnits-allowed) > 1e-6 {
+		t.Errorf("allowed = %v, want %v", r.AllowedBadUnits, allowed)
+	}
+	if p := r.Points[8]; p.BadUnits != 0 || p.BudgetRemaining != 100 || !p.At.Equal(day(10, 0)) {
+		t.Errorf("Feb 9 = %+v", p)
+	}
+	if p := r.Points[9]; p.BadUnits != 3600 || p.BudgetRemaining >= 0 {
+		t.Errorf("Feb 10 = %+v", p)
+	}
+	if last := r.Points[27]; last.BadUnits != 3600 || !last.At.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("last point = %+v", last)
+	}
+
+	if r.Incidents.Current != 1 || r.Incidents.Previous != 1 || r.Incidents.Change != 0 {
+		t.Errorf("incidents = %+v", r.Incidents)
+	}
+	if r.MTTR.Current != 3600 || r.MTTR.Previous != 7200 || r.MTTR.Change != -3600 {
+		t.Errorf("MTTR = %+v", r.MTTR)
+	}
+	if r.Uptime.Change <= 0 {
+		t.Errorf("uptime = %+v, want February better than January", r.Uptime)
+	}
+	if !r.PreviousEnd.Equal(r.Start) {
+		t.Errorf("a complete period compares with all of the last one, up to %v", r.PreviousEnd)
+	}
+}
+
+func TestErrorBudgetReportInProgress(t *testing.T) {
+	now := time.Now().UTC()
+	start, _, _ := PeriodBounds(PeriodMonth, now)
+	prevStart, _, _ := PeriodBounds(PeriodMonth, start.Add(-time.Nanosecond))
+	if now.Sub(start) < 2*time.Hour || prevStart.Add(now.Sub(start)).After(start.Add(-2*time.Hour)) {
+		t.Skip("too close to a month boundary")
+	}
+	s := NewStore()
+	svc := addTestService(s, "api", "https://api.example.com")
+	svc.SLOTargetPercent = 99.9
+	outage := func(id int, at time.Time) *Incident {
+		end := at.Add(time.Hour)
+		return &Incident{ID: id, ServiceID: svc.ID, StartedAt: at, EndedAt: &end, DurationS: 3600}
+	}
+	// the first hour of last month is within the elapsed part, its last
+	// hours aren't
+	s.Incidents[svc.ID] = []*Incident{outage(1, prevStart), outage(2, start.Add(-2*time.Hour))}
+
+	r, err := s.ErrorBudgetReport(svc.ID, PeriodMonth, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Complete || r.PreviousEnd.After(prevStart.Add(now.Sub(start)+time.Minute)) {
+		t.Errorf("complete %v, previous end %v", r.Complete, r.PreviousEnd)
+	}
+	if r.Incidents.Previous != 1 {
+		t.Errorf("previous incidents = %v, want only the one in the elapsed part", r.Incidents.Previous)
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"serverwatcher/notify"
+	"time"
+)
+
+// Burn alert severities.
+const (
+	SeverityPage   = "page"
+	SeverityTicket = "ticket"
+)
+
+// BurnWindow is one multi-window burn rate rule: it trips when both the
+// long and the short window burn the error budget at least Factor times
+// faster than sustainable. The short window makes it reset quickly once
+// the burn stops.
+type BurnWindow struct {
+	Long     time.Duration
+	Short    time.Duration
+	Factor   float64
+	Severity string
+}
+
+// Burn windows for a 30-day budget, as recommended in the SRE workbook.
+var defaultBurnWindows = []BurnWindow{
+	{Long: time.Hour, Short: 5 * time.Minute, Factor: 14.4, Severity: SeverityPage},
+	{Long: 6 * time.Hour, Short: 30 * time.Minute, Factor: 6, Severity: SeverityPage},
+	{Long: 24 * time.Hour, Short: 2 * time.Hour, Factor: 3, Severity: SeverityTicket},
+	{Long: 72 * time.Hour, Short: 6 * time.Hour, Factor: 1, Severity: SeverityTicket},
+}
+
+// BurnAlert is an open (or resolved) SLO burn alert for one severity.
+type BurnAlert struct {
+	ID        int        `json:"id"`
+	ServiceID int        `json:"serviceId"`
+	Severity  string     `json:"severity"` // "page" or "ticket"
+	Window    string     `json:"window"`   // rule that tripped, e.g. "1h/5m@14.4x"
+	LongBurn  float64    `json:"longBurn"`
+	ShortBurn float64    `json:"shortBurn"`
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+	Alerted   bool       `json:"alerted,omitempty"` // the alert went out, so the resolution must follow
+}
+
+func (b BurnWindow) String() string {
+	return fmt.Sprintf("%s/%s@%gx", shortDur(b.Long), shortDur(b.Short), b.Factor)
+}
+
+func shortDur(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
+// StartBurnAlerts evaluates burn rates for every service with an SLO
+// target once a minute.
+func (s *Store) StartBurnAlerts() {
+	go func() {
+		t := time.NewTicker(time.Minute)
+		defer t.Stop()
+		for now := range t.C {
+			s.evaluateBurnAlerts(now.UTC())
+		}
+	}()
+}
+
+func (s *Store) evaluateBurnAlerts(now time.Time) {
+	s.Lock()
+	var svcs []*Service
+	for _, svc := range s.services {
+		if svc.SLOTargetPercent > 0 {
+			c := *svc
+			svcs = append(svcs, &c)
+		}
+	}
+	s.Unlock()
+
+	changed := false
+	for _, svc := range svcs {
+		for _, sev := range []string{SeverityPage, SeverityTicket} {
+			rule, long, short, firing := s.burnFiring(svc.ID, sev, now)
+			if s.transitionBurnAlert(svc, sev, rule, long, short, firing, now) {
+				changed = true
+			}
+		}
+	}
+	if changed {
+		if err := s.SaveToFile(); err != nil {
+			log.Println("burn alerts: save:", err)
+		}
+	}
+}
+
+// burnFiring checks the rules of one severity, returning the first that
+// trips (or, if none does, the last evaluated) with its burn rates.
+func (s *Store) burnFiring(id int, severity string, now time.Time) (BurnWindow, float64, float64, bool) {
+	var last BurnWindow
+	var lb, sb float64
+	for _, bw := range defaultBurnWindows {
+		if bw.Severity != severity {
+			continue
+		}
+		last = bw
+		lb = s.sloOver(id, span{now.Add(-bw.Long), now}, "").BurnRate
+		sb = s.sloOver(id, span{now.Add(-bw.Short), now}, "").BurnRate
+		if lb >= bw.Factor && sb >= bw.Factor {
+			return bw, lb, sb, true
+		}
+	}
+	return last, lb, sb, false
+}
+
+// transitionBurnAlert opens or resolves the alert for (service, severity)
+// and notifies, respecting silences but always resolving what was
+// alerted; it reports whether anything changed.
+func (s *Store) transitionBurnAlert(svc *Service, severity string, rule BurnWindow, long, short float64, firing bool, now time.Time) bool {
+	s.Lock()
+	defer s.Unlock()
+	open := s.openBurnAlertLocked(svc.ID, severity)
+	switch {
+	case firing && open == nil:
+		a := &BurnAlert{
+			ID:        s.nextBurnAlertID,
+			ServiceID: svc.ID,
+			Severity:  severity,
+			Window:    rule.String(),
+			LongBurn:  long,
+			ShortBurn: short,
+			StartedAt: now,
+		}
+		s.nextBurnAlertID++
+		s.burnAlerts = append(s.burnAlerts, a)
+		e := s.newEventLocked(notify.EventSLOBurn, burnSeverity(severity), svc, now)
+		e.AlertKey = burnKey(a.ID)
+		e.Summary = fmt.Sprintf("Error budget burning %.1fx over %s (%.1fx over %s), threshold %gx, target %g%%",
+			long, shortDur(rule.Long), short, shortDur(rule.Short), rule.Factor, svc.SLOTargetPercent)
+		if !s.isSilencedLocked(svc) {
+			a.Alerted = true
+			go s.broadcast(e)
+		}
+		return true
+
+	case !firing && open != nil:
+		open.EndedAt = &now
+		e := s.newEventLocked(notify.EventSLOBurn, notify.SeverityInfo, svc, now)
+		e.AlertKey = burnKey(open.ID)
+		e.StartedAt = open.StartedAt
+		e.EndedAt = open.EndedAt
+		e.Summary = fmt.Sprintf("Burn rate back under threshold (%s alert)", severity)
+		if open.Alerted {
+			go s.broadcast(e)
+		}
+		return true
+	}
+	if open != nil {
+		// keep the latest rates visible while firing
+		open.LongBurn, open.ShortBurn = long, short
+	}
+	return false
+}
+
+func burnKey(id int) string { return fmt.Sprintf("slo-burn-%d", id) }
+
+// burnSeverity maps page/ticket onto notifier severities.
+func burnSeverity(severity string) notify.Severity {
+	if severity == SeverityPage {
+		return notify.SeverityCritical
+	}
+	return notify.SeverityWarning
+}
+
+func (s *Store) openBurnAlertLocked(id int, severity string) *BurnAlert {
+	for _, a := range s.burnAlerts {
+		if a.ServiceID == id && a.Severity == severity && a.EndedAt == nil {
+			return a
+		}
+	}
+	return nil
+}
+
+// ListBurnAlerts returns burn alerts, optionally for one service (id > 0)
+// and/or only open ones, newest first.
+func (s *Store) ListBurnAlerts(id int, openOnly bool) []BurnAlert {
+	s.Lock()
+	defer s.Unlock()
+	out := []BurnAlert{}
+	for i := len(s.burnAlerts) - 1; i >= 0; i-- {
+		a := s.burnAlerts[i]
+		if id > 0 && a.ServiceID != id {
+			continue
+		}
+		if openOnly && a.EndedAt != nil {
+			continue
+		}
+		out = append(out, *a)
+	}
+	return out
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"reflect"
+	"serverwatcher/notify"
+	"testing"
+	"time"
+)
+
+func TestBurnWindowString(t *testing.T) {
+	want := []string{"1h/5m@14.4x", "6h/30m@6x", "1d/2h@3x", "3d/6h@1x"}
+	for i, bw := range defaultBurnWindows {
+		if got := bw.String(); got != want[i] {
+			t.Errorf("window %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestBurnFiring(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+	for _, c := range []struct {
+		name       string
+		started    time.Duration
+		ended      *time.Time
+		page, tick string // rule that fires, "" for none
+	}{
+		{"no incident", 0, nil, "", ""},
+		{"down for the last 10m", -10 * time.Minute, nil, "1h/5m@14.4x", "1d/2h@3x"},
+		// the 5m window has recovered, the 30m one hasn't
+		{"recovered 10m ago", -20 * time.Minute, at(-10 * time.Minute), "6h/30m@6x", "1d/2h@3x"},
+		// 3 minutes is 2.1x over a day, under every rule
+		{"3m blip 1h ago", -63 * time.Minute, at(-time.Hour), "", ""},
+		{"2h outage 3h ago", -5 * time.Hour, at(-3 * time.Hour), "", "3d/6h@1x"},
+	} {
+		s := NewStore()
+		svc := addTestService(s, "api", "https://api.example.com")
+		svc.SLOTargetPercent = 99.9
+		if c.started != 0 {
+			s.Incidents[svc.ID] = []*Incident{{ID: 1, ServiceID: svc.ID, StartedAt: now.Add(c.started), EndedAt: c.ended}}
+		}
+		for sev, want := range map[string]string{SeverityPage: c.page, SeverityTicket: c.tick} {
+			rule, _, _, firing := s.burnFiring(svc.ID, sev, now)
+			got := ""
+			if firing {
+				got = rule.String()
+			}
+			if got != want {
+				t.Errorf("%s: %s fires %q, want %q", c.name, sev, got, want)
+			}
+		}
+	}
+}
+
+func TestBurnAlertLifecycle(t *testing.T) {
+	s := NewStore()
+	svc := addTestService(s, "api", "https://api.example.com")
+	svc.SLOTargetPercent = 99.9
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	inc := &Incident{ID: 1, ServiceID: svc.ID, StartedAt: now.Add(-10 * time.Minute)}
+	s.Incidents[svc.ID] = []*Incident{inc}
+
+	s.evaluateBurnAlerts(now)
+	open := s.ListBurnAlerts(svc.ID, true)
+	if len(open) != 2 {
+		t.Fatalf("got %d open alerts, want page and ticket", len(open))
+	}
+	page := open[1] // newest first; page is evaluated first
+	if page.Severity != SeverityPage || page.Window != "1h/5m@14.4x" || !page.StartedAt.Equal(now) || page.LongBurn < 14.4 {
+		t.Errorf("page alert = %+v", page)
+	}
+
+	// still firing: no new alert, rates follow
+	s.evaluateBurnAlerts(now.Add(time.Minute))
+	if all := s.ListBurnAlerts(0, false); len(all) != 2 || all[1].LongBurn <= page.LongBurn {
+		t.Errorf("alerts after a second evaluation = %+v", all)
+	}
+
+	ended := now.Add(time.Minute)
+	inc.EndedAt = &ended
+	later := now.Add(4 * 24 * time.Hour)
+	s.evaluateBurnAlerts(later)
+	if n := len(s.ListBurnAlerts(svc.ID, true)); n != 0 {
+		t.Errorf("%d alerts still open after the burn stopped", n)
+	}
+	for _, a := range s.ListBurnAlerts(svc.ID, false) {
+		if a.EndedAt == nil || !a.EndedAt.Equal(later) {
+			t.Errorf("alert %d ended at %v", a.ID, a.EndedAt)
+		}
+	}
+
+	// services without a target aren't evaluated
+	other := addTestService(s, "web", "https://example.com")
+	s.Incidents[other.ID] = []*Incident{{ID: 2, ServiceID: other.ID, StartedAt: later.Add(-time.Hour)}}
+	s.evaluateBurnAlerts(later)
+	if n := len(s.ListBurnAlerts(other.ID, false)); n != 0 {
+		t.Errorf("got %d alerts for a service without an SLO target", n)
+	}
+}
+
+// waitQueued polls for n queued events, as burn alerts are broadcast
+// from a goroutine.
+func waitQueued(t *testing.T, s *Store, n int) []notify.Event {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		evs := queuedEvents(t, s)
+		if len(evs) >= n || time.Now().After(deadline) {
+			return evs
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBurnAlertSilenced(t *testing.T) {
+	s := NewStore()
+	s.SetSQLStore(openTestSQL(t))
+	s.SetNotifiers([]notify.Channel{{Name: "ops", Notifier: notify.Webhook{URL: "http://127.0.0.1:1"}}})
+	svc := addTestService(s, "api", "https://api.example.com")
+	svc.SLOTargetPercent = 99.9
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	outage := func(start time.Time) *Incident {
+		inc := &Incident{ID: len(s.Incidents[svc.ID]) + 1, ServiceID: svc.ID, StartedAt: start}
+		s.Incidents[svc.ID] = append(s.Incidents[svc.ID], inc)
+		return inc
+	}
+	end := func(inc *Incident, at time.Time) { inc.EndedAt = &at }
+
+	// opened under a silence: nothing goes out, not even the resolution
+	// (silences use the wall clock)
+	sil := s.NewSilence(&svc.ID, "", time.Now().Add(time.Hour), "maintenance")
+	first := outage(now.Add(-10 * time.Minute))
+	s.evaluateBurnAlerts(now)
+	for _, a := range s.ListBurnAlerts(svc.ID, true) {
+		if a.Alerted {
+			t.Errorf("%s alert opened under a silence is alerted", a.Severity)
+		}
+	}
+	s.DeleteSilence(sil.ID)
+	end(first, now)
+	s.evaluateBurnAlerts(now.Add(4 * day))
+
+	// opened outside one and resolved inside one: both go out
+	later := now.Add(10 * day)
+	second := outage(later.Add(-10 * time.Minute))
+	s.evaluateBurnAlerts(later)
+	open := s.ListBurnAlerts(svc.ID, true)
+	if len(open) != 2 || !open[0].Alerted || !open[1].Alerted {
+		t.Fatalf("open alerts = %+v", open)
+	}
+	s.NewSilence(&svc.ID, "", time.Now().Add(time.Hour), "maintenance")
+	end(second, later)
+	s.evaluateBurnAlerts(later.Add(4 * day))
+
+	evs := waitQueued(t, s, 4)
+	keys := map[string]int{}
+	for _, e := range evs {
+		keys[e.AlertKey]++
+	}
+	want := map[string]int{burnKey(open[0].ID): 2, burnKey(open[1].ID): 2}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("queued alert keys = %v, want %v", keys, want)
+	}
+}
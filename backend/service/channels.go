@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"serverwatcher/notify"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrChannelNotFound = errors.New("channel not found")
+	ErrChannelInUse    = errors.New("channel is in use")
+)
+
+// NotificationChannel is a channel configured through the API. Settings
+// are type specific (see notify.Types); secrets in them are redacted on
+// read.
+type NotificationChannel struct {
+	ID        int               `json:"id"`
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	Settings  map[string]string `json:"settings"`
+	Templates map[string]string `json:"templates,omitempty"` // event type or "default" -> text/template
+	TimeoutMs int               `json:"timeoutMs,omitempty"`
+	Disabled  bool              `json:"disabled,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+// redacted returns a copy safe to hand out.
+func (c *NotificationChannel) redacted() NotificationChannel {
+	out := *c
+	out.Settings = make(map[string]string, len(c.Settings))
+	for k, v := range c.Settings {
+		if v != "" && notify.IsSecret(c.Type, k) {
+			v = notify.Redacted
+		}
+		out.Settings[k] = v
+	}
+	return out
+}
+
+func (c *NotificationChannel) build() (notify.Channel, error) {
+	n, err := notify.Build(c.Type, c.Settings)
+	if err != nil {
+		return notify.Channel{}, err
+	}
+	tpl, err := notify.ParseTemplates(c.Templates)
+	if err != nil {
+		return notify.Channel{}, err
+	}
+	return notify.Channel{
+		Name:      c.Name,
+		Notifier:  n,
+		Timeout:   time.Duration(c.TimeoutMs) * time.Millisecond,
+		Templates: tpl,
+	}, nil
+}
+
+// SetNotifiers sets the channels configured outside the API (env vars).
+// Channels managed through the API are added on top and re-applied
+// whenever they change. Queued deliveries are matched to channels by name
+// on their next attempt.
+func (s *Store) SetNotifiers(chs []notify.Channel) {
+	s.Lock()
+	defer s.Unlock()
+	s.envChannels = chs
+	s.applyChannelsLocked()
+}
+
+func (s *Store) applyChannelsLocked() {
+	s.channels = make(map[string]notify.Channel)
+	s.channelOrder = s.channelOrder[:0]
+	add := func(c notify.Channel) {
+		if _, dup := s.channels[c.Name]; dup {
+			log.Printf("notify: duplicate channel name %q ignored", c.Name)
+			return
+		}
+		s.channels[c.Name] = c
+		s.channelOrder = append(s.channelOrder, c.Name)
+	}
+	for _, c := range s.envChannels {
+		add(c)
+	}
+	for _, cfg := range s.channelConfigs {
+		if cfg.Disabled {
+			continue
+		}
+		c, err := cfg.build()
+		if err != nil {
+			log.Printf("notify: channel %q: %v", cfg.Name, err)
+			continue
+		}
+		add(c)
+	}
+}
+
+func (s *Store) channel(name string) (notify.Channel, bool) {
+	s.Lock()
+	defer s.Unlock()
+	c, ok := s.channels[name]
+	return c, ok
+}
+
+// ListChannels returns the API-managed channels with secrets redacted.
+func (s *Store) ListChannels() []NotificationChannel {
+	s.Lock()
+	defer s.Unlock()
+	out := make([]NotificationChannel, 0, len(s.channelConfigs))
+	for _, c := range s.channelConfigs {
+		out = append(out, c.redacted())
+	}
+	return out
+}
+
+func (s *Store) GetChannel(id int) (NotificationChannel, error) {
+	s.Lock()
+	defer s.Unlock()
+	c := s.channelConfigLocked(id)
+	if c == nil {
+		return NotificationChannel{}, ErrChannelNotFound
+	}
+	return c.redacted(), nil
+}
+
+// CreateChannel validates and stores a new channel and applies it.
+func (s *Store) CreateChannel(c NotificationChannel) (NotificationChannel, error) {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.validateChannelLocked(&c, 0); err != nil {
+		return NotificationChannel{}, err
+	}
+	now := time.Now().UTC()
+	s.nextChannelID++
+	c.ID = s.nextChannelID
+	c.CreatedAt, c.UpdatedAt = now, now
+	s.channelConfigs = append(s.channelConfigs, &c)
+	s.applyChannelsLocked()
+	return c.redacted(), s.saveLocked()
+}
+
+// UpdateChannel replaces a channel's config. Secret settings sent back as
+// notify.Redacted (or left out) keep their stored value, unless the type
+// or the server they would be sent to changes; then they must be given
+// again.
+func (s *Store) UpdateChannel(id int, in NotificationChannel) (NotificationChannel, error) {
+	s.Lock()
+	defer s.Unlock()
+	cur := s.channelConfigLocked(id)
+	if cur == nil {
+		return NotificationChannel{}, ErrChannelNotFound
+	}
+	if in.Settings == nil {
+		in.Settings = map[string]string{}
+	}
+	if in.Type == "" {
+		in.Type = cur.Type
+	}
+	if in.Type == cur.Type && notify.SameEndpoint(cur.Type, cur.Settings, in.Settings) {
+		for k, v := range cur.Settings {
+			if !notify.IsSecret(cur.Type, k) {
+				continue
+			}
+			if nv, ok := in.Settings[k]; !ok || nv == notify.Redacted {
+				in.Settings[k] = v
+			}
+		}
+	} else {
+		// the stored secrets don't carry over, so the placeholder must not
+		// be taken for one
+		for k, v := range in.Settings {
+			if v == notify.Redacted && notify.IsSecret(in.Type, k) {
+				delete(in.Settings, k)
+			}
+		}
+	}
+	if err := s.validateChannelLocked(&in, id); err != nil {
+		return NotificationChannel{}, err
+	}
+	if in.Name != cur.Name {
+		if err := s.checkChannelRefsLocked(cur.Name); err != nil {
+			return NotificationChannel{}, err
+		}
+	}
+	in.ID = id
+	in.CreatedAt = cur.CreatedAt
+	in.UpdatedAt = time.Now().UTC()
+	*cur = in
+	s.applyChannelsLocked()
+	return cur.redacted(), s.saveLocked()
+}
+
+func (s *Store) DeleteChannel(id int) error {
+	s.Lock()
+	defer s.Unlock()
+	for i, c := range s.channelConfigs {
+		if c.ID == id {
+			if err := s.checkChannelRefsLocked(c.Name); err != nil {
+				return err
+			}
+			s.channelConfigs = append(s.channelConfigs[:i], s.channelConfigs[i+1:]...)
+			s.applyChannelsLocked()
+			return s.saveLocked()
+		}
+	}
+	return ErrChannelNotFound
+}
+
+// checkChannelRefsLocked refuses to let an API channel go away (deleted or
+// renamed) while routes, escalation steps, schedule contacts or the digest
+// still send to it by name. An environment channel of the same name keeps
+// them working.
+func (s *Store) checkChannelRefsLocked(name string) error {
+	if slices.ContainsFunc(s.envChannels, func(c notify.Channel) bool { return c.Name == name }) {
+		return nil
+	}
+	var refs []string
+	for _, r := range s.routes {
+		if slices.Contains(r.Channels, name) {
+			refs = append(refs, fmt.Sprintf("routing rule %q", r.Name))
+		}
+	}
+	for _, p := range s.escalations {
+		for _, st := range p.Steps {
+			if slices.Contains(st.Channels, name) {
+				refs = append(refs, fmt.Sprintf("escalation policy %q", p.Name))
+				break
+			}
+		}
+	}
+	for _, sc := range s.schedules {
+		for _, chans := range sc.Contacts {
+			if slices.Contains(chans, name) {
+				refs = append(refs, fmt.Sprintf("schedule %q", sc.Name))
+				break
+			}
+		}
+	}
+	if s.digest.Channel == name {
+		refs = append(refs, "the digest")
+	}
+	if len(refs) > 0 {
+		return fmt.Errorf("%w: used by %s", ErrChannelInUse, strings.Join(refs, ", "))
+	}
+	return nil
+}
+
+// TestChannel sends a sample event straight through the channel, bypassing
+// the queue, so the caller sees the error right away. Disabled channels
+// can be tested too.
+func (s *Store) TestChannel(ctx context.Context, id int) error {
+	s.Lock()
+	cfg := s.channelConfigLocked(id)
+	if cfg == nil {
+		s.Unlock()
+		return ErrChannelNotFound
+	}
+	c, err := cfg.build()
+	s.Unlock()
+	if err != nil {
+		return err
+	}
+	return c.Send(ctx, notify.TestEvent(c.Name, time.Now().UTC()))
+}
+
+// PreviewTemplate renders a message against a sample event of the given
+// type. src is a template to try out; when empty, the templates saved on
+// channel id are used.
+func (s *Store) PreviewTemplate(id int, typ notify.EventType, src string) (notify.Event, error) {
+	tpls := map[string]string{string(typ): src}
+	if src == "" {
+		s.Lock()
+		cfg := s.channelConfigLocked(id)
+		if cfg == nil {
+			s.Unlock()
+			return notify.Event{}, ErrChannelNotFound
+		}
+		tpls = cfg.Templates
+		s.Unlock()
+	}
+	t, err := notify.ParseTemplates(tpls)
+	if err != nil {
+		return notify.Event{}, err
+	}
+	e := notify.SampleEvent(typ, time.Now())
+	if e.Message, err = t.Render(e); err != nil {
+		return notify.Event{}, err
+	}
+	return e, nil
+}
+
+func (s *Store) channelConfigLocked(id int) *NotificationChannel {
+	for _, c := range s.channelConfigs {
+		if c.ID == id {
+			return c
+		}
+	}
+	return nil
+}
+
+// validateChannelLocked checks the name is unique (ignoring channel self)
+// and that the settings build a notifier.
+func (s *Store) validateChannelLocked(c *NotificationChannel, self int) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	if c.TimeoutMs < 0 {
+		return errors.New("timeoutMs must be >= 0")
+	}
+	for _, e := range s.envChannels {
+		if e.Name == c.Name {
+			return fmt.Errorf("name %q is used by a channel configured from the environment", c.Name)
+		}
+	}
+	for _, o := range s.channelConfigs {
+		if o.ID != self && o.Name == c.Name {
+			return fmt.Errorf("name %q already exists", c.Name)
+		}
+	}
+	if c.Settings == nil {
+		c.Settings = map[string]string{}
+	}
+	_, err := c.build()
+	return err
+}
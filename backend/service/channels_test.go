@@ -0,0 +1,98 @@
+package service
+
+import (
+	"serverwatcher/notify"
+	"strings"
+	"testing"
+)
+
+func TestUpdateChannelKeepsSecrets(t *testing.T) {
+	r := notify.Redacted
+	for _, c := range []struct {
+		name   string
+		cur    NotificationChannel
+		update NotificationChannel
+		secret string // setting to check
+		want   string // its stored value, or "" when the update must fail
+	}{
+		{"redacted token kept",
+			NotificationChannel{Type: "telegram", Settings: map[string]string{"token": "t0k", "chatId": "1"}},
+			NotificationChannel{Settings: map[string]string{"token": r, "chatId": "2"}},
+			"token", "t0k"},
+		{"left out token kept",
+			NotificationChannel{Type: "telegram", Settings: map[string]string{"token": "t0k", "chatId": "1", "baseUrl": "https://tg.example.com"}},
+			NotificationChannel{Settings: map[string]string{"chatId": "1", "baseUrl": " https://tg.example.com "}},
+			"token", "t0k"},
+		{"new token replaces",
+			NotificationChannel{Type: "telegram", Settings: map[string]string{"token": "t0k", "chatId": "1"}},
+			NotificationChannel{Settings: map[string]string{"token": "n3w", "chatId": "1"}},
+			"token", "n3w"},
+		{"telegram baseUrl changed",
+			NotificationChannel{Type: "telegram", Settings: map[string]string{"token": "t0k", "chatId": "1"}},
+			NotificationChannel{Settings: map[string]string{"token": r, "chatId": "1", "baseUrl": "https://evil.example.com"}},
+			"token", ""},
+		{"email host changed",
+			NotificationChannel{Type: "email", Settings: map[string]string{"host": "smtp.example.com", "from": "a@example.com", "to": "b@example.com", "password": "pw"}},
+			NotificationChannel{Settings: map[string]string{"host": "smtp.evil.com", "from": "a@example.com", "to": "b@example.com", "password": r}},
+			"password", "-"},
+		{"pagerduty baseUrl changed",
+			NotificationChannel{Type: "pagerduty", Settings: map[string]string{"routingKey": "rk"}},
+			NotificationChannel{Settings: map[string]string{"routingKey": r, "baseUrl": "https://evil.example.com"}},
+			"routingKey", ""},
+		{"opsgenie baseUrl cleared",
+			NotificationChannel{Type: "opsgenie", Settings: map[string]string{"apiKey": "k", "baseUrl": "https://api.eu.opsgenie.com"}},
+			NotificationChannel{Settings: map[string]string{"apiKey": r}},
+			"apiKey", ""},
+		{"matrix homeserver changed",
+			NotificationChannel{Type: "matrix", Settings: map[string]string{"homeserverUrl": "https://matrix.org", "accessToken": "at", "roomId": "!r"}},
+			NotificationChannel{Settings: map[string]string{"homeserverUrl": "https://evil.example.com", "accessToken": r, "roomId": "!r"}},
+			"accessToken", ""},
+		{"ntfy server changed",
+			NotificationChannel{Type: "ntfy", Settings: map[string]string{"topic": "ops", "token": "tk"}},
+			NotificationChannel{Settings: map[string]string{"topic": "ops", "token": r, "serverUrl": "https://evil.example.com"}},
+			"token", "-"},
+		{"gotify server changed",
+			NotificationChannel{Type: "gotify", Settings: map[string]string{"serverUrl": "https://push.example.com", "appToken": "at"}},
+			NotificationChannel{Settings: map[string]string{"serverUrl": "https://evil.example.com", "appToken": r}},
+			"appToken", ""},
+		{"webhook headers kept with the url redacted",
+			NotificationChannel{Type: "webhook", Settings: map[string]string{"url": "https://hooks.example.com/x", "headers": "Authorization: Bearer s"}},
+			NotificationChannel{Settings: map[string]string{"url": r, "headers": r}},
+			"headers", "Authorization: Bearer s"},
+		{"webhook url changed",
+			NotificationChannel{Type: "webhook", Settings: map[string]string{"url": "https://hooks.example.com/x", "headers": "Authorization: Bearer s"}},
+			NotificationChannel{Settings: map[string]string{"url": "https://evil.example.com", "headers": r}},
+			"headers", "-"},
+		{"type changed",
+			NotificationChannel{Type: "slack", Settings: map[string]string{"webhookUrl": "https://hooks.slack.com/x"}},
+			NotificationChannel{Type: "discord", Settings: map[string]string{"webhookUrl": r}},
+			"webhookUrl", ""},
+	} {
+		s := NewStore()
+		c.cur.Name = "ops"
+		created, err := s.CreateChannel(c.cur)
+		if err != nil {
+			t.Fatalf("%s: create: %v", c.name, err)
+		}
+		c.update.Name = "ops"
+		_, err = s.UpdateChannel(created.ID, c.update)
+		if c.want == "" {
+			if err == nil || !strings.Contains(err.Error(), "is required") {
+				t.Errorf("%s: err = %v, want the secret to be required again", c.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		// "-": optional secrets are dropped, not stored as the placeholder
+		want := c.want
+		if want == "-" {
+			want = ""
+		}
+		if got := s.channelConfigLocked(created.ID).Settings[c.secret]; got != want {
+			t.Errorf("%s: %s = %q, want %q", c.name, c.secret, got, want)
+		}
+	}
+}
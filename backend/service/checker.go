@@ -3,9 +3,12 @@ package service
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"serverwatcher/notify"
 	"time"
 )
 
@@ -23,12 +26,14 @@ func (s *Store) startChecker(svc *Service, stopChan chan struct{}) {
 	runCheck := func() {
 		status := checkService(svc)
 		now := time.Now().UTC()
+		s.recordCheck(status)
 
 		s.Lock()
 		defer s.Unlock()
 		s.ensureMaps()
 
-		// Update latest + history (bounded)
+		// Update latest + recent in-memory history (bounded); the full
+		// history lives in SQLite (see recordCheck)
 		s.statuses[svc.ID] = status
 		const maxHistory = 1000
 		h := s.histories[svc.ID]
@@ -68,6 +73,8 @@ func (s *Store) startChecker(svc *Service, stopChan chan struct{}) {
 					ID:        s.nextIncidentID,
 					ServiceID: svc.ID,
 					StartedAt: now,
+					Reason:    status.Reason,
+					Severity:  svc.defaultSeverity(),
 				}
 				s.nextIncidentID++
 				s.openIncident[svc.ID] = inc
@@ -75,11 +82,14 @@ func (s *Store) startChecker(svc *Service, stopChan chan struct{}) {
 				s.lastStatus[svc.ID] = "FAIL"
 
 				// Notify (respect cooldown + silences)
-				if s.canNotify(svc.ID, now) && !s.IsSilenced(svc) {
+				if s.canNotify(svc.ID, now) && !s.isSilencedLocked(svc) {
 					s.lastAlertAt[svc.ID] = now
-					title := fmt.Sprintf("[DOWN] %s", svc.Name)
-					text := fmt.Sprintf("URL: %s\nTime: %s", svc.URL, now.Format(time.RFC3339))
-					go s.broadcast(title, text)
+					inc.Alerted = true
+					e := s.newEventLocked(notify.EventDown, notifySeverity(inc.Severity), svc, now)
+					e.IncidentID = inc.ID
+					e.AlertKey = incidentKey(inc.ID)
+					e.Reason = inc.Reason
+					go s.broadcast(e)
 				}
 			}
 		}
@@ -93,13 +103,17 @@ func (s *Store) startChecker(svc *Service, stopChan chan struct{}) {
 					s.openIncident[svc.ID] = nil
 					s.lastStatus[svc.ID] = "OK"
 
-					// Notify (respect cooldown + silences)
-					if s.canNotify(svc.ID, now) && !s.IsSilenced(svc) {
+					// Notify (respect cooldown + silences, but always resolve
+					// what was alerted so keyed pages get closed)
+					if open.Alerted || (s.canNotify(svc.ID, now) && !s.isSilencedLocked(svc)) {
 						s.lastAlertAt[svc.ID] = now
-						title := fmt.Sprintf("[UP] %s", svc.Name)
-						text := fmt.Sprintf("URL: %s\nTime: %s\nDowntime: %ds",
-							svc.URL, now.Format(time.RFC3339), open.DurationS)
-						go s.broadcast(title, text)
+						e := s.newEventLocked(notify.EventUp, notify.SeverityInfo, svc, now)
+						e.IncidentID = open.ID
+						e.AlertKey = incidentKey(open.ID)
+						e.StartedAt = open.StartedAt
+						e.EndedAt = open.EndedAt
+						e.Reason = open.Reason
+						go s.broadcast(e)
 					}
 				} else {
 					// No open incident tracked; just set status
@@ -107,8 +121,6 @@ func (s *Store) startChecker(svc *Service, stopChan chan struct{}) {
 				}
 			}
 		}
-
-		// NOTE: SQLite write removed. We'll add it back once SqlStore is integrated.
 	}
 
 	// run once immediately
@@ -147,6 +159,7 @@ func checkService(svc *Service) StatusResult {
 
 	totalStart := time.Now()
 	statusStr := "FAIL"
+	reason := ""
 
 	tryCount := retries + 1
 	for i := 0; i < tryCount; i++ {
@@ -170,14 +183,22 @@ func checkService(svc *Service) StatusResult {
 				resp.Body.Close()
 			}
 			ok = (resp.StatusCode == expected) && bodyOK
+			switch {
+			case resp.StatusCode != expected:
+				reason = fmt.Sprintf("status %d (expected %d)", resp.StatusCode, expected)
+			case !bodyOK:
+				reason = fmt.Sprintf("body does not contain %q", needle)
+			}
 		} else {
 			if resp != nil && resp.Body != nil {
 				resp.Body.Close()
 			}
+			reason = failureReason(err, timeoutMs)
 		}
 
 		if ok {
 			statusStr = "OK"
+			reason = ""
 			break
 		}
 		// backoff if more attempts remain
@@ -193,9 +214,27 @@ func checkService(svc *Service) StatusResult {
 		Status:     statusStr,                                  // "OK"/"FAIL"
 		ResponseMs: int(time.Since(totalStart).Milliseconds()), // total wall time incl. retries
 		CheckedAt:  time.Now().UTC().Format(time.RFC3339),
+		Reason:     reason,
 	}
 }
 
+func incidentKey(id int) string { return fmt.Sprintf("incident-%d", id) }
+
+// failureReason turns a transport error into a short, readable reason.
+func failureReason(err error, timeoutMs int) string {
+	if err == nil {
+		return "no response"
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Sprintf("timeout after %dms", timeoutMs)
+	}
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		return uerr.Err.Error()
+	}
+	return err.Error()
+}
+
 // AddService creates a service with reliability settings and starts its checker.
 func (s *Store) AddService(name, url string, interval, timeoutMs, retries, backoffMs int) int {
 	s.Lock()
@@ -258,6 +297,49 @@ func (s *Store) RemoveService(id int) {
 	s.Unlock()
 }
 
+// PauseService stops checking a service until ResumeService.
+func (s *Store) PauseService(id int) error {
+	s.Lock()
+	defer s.Unlock()
+	svc, ok := s.services[id]
+	if !ok {
+		return fmt.Errorf("service not found")
+	}
+	if !svc.Active {
+		return nil
+	}
+	if stopChan, ok := s.stopChans[id]; ok {
+		close(stopChan)
+		delete(s.stopChans, id)
+	}
+	svc.Active = false
+	svc.Pauses = append(svc.Pauses, Pause{Start: time.Now().UTC()})
+	return s.saveLocked()
+}
+
+// ResumeService restarts checks of a paused service.
+func (s *Store) ResumeService(id int) error {
+	s.Lock()
+	svc, ok := s.services[id]
+	if !ok {
+		s.Unlock()
+		return fmt.Errorf("service not found")
+	}
+	if svc.Active {
+		s.Unlock()
+		return nil
+	}
+	svc.Active = true
+	if n := len(svc.Pauses); n > 0 && svc.Pauses[n-1].End == nil {
+		now := time.Now().UTC()
+		svc.Pauses[n-1].End = &now
+	}
+	err := s.saveLocked()
+	s.Unlock()
+	s.RestartChecker(svc)
+	return err
+}
+
 func (s *Store) canNotify(svcID int, now time.Time) bool {
 	cd := time.Duration(s.policy.AlertCooldownSec) * time.Second
 	if cd <= 0 {
@@ -0,0 +1,488 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"serverwatcher/notify"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// maxDeliveryAttempts is how often a delivery is tried before it is
+	// moved to the dead-letter table.
+	maxDeliveryAttempts = 8
+	deliveryBaseBackoff = 5 * time.Second
+	deliveryMaxBackoff  = 10 * time.Minute
+	deliveryBatch       = 50
+)
+
+// queueTimeLayout is fixed-width so timestamps compare correctly as text.
+const queueTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+var (
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	ErrNoDeliveryQueue  = errors.New("delivery queue needs sqlite")
+)
+
+// Delivery is one event queued for (or given up on) one channel.
+type Delivery struct {
+	ID            int64        `json:"id"`
+	Channel       string       `json:"channel"`
+	Event         notify.Event `json:"event"`
+	Attempts      int          `json:"attempts"`
+	LastError     string       `json:"lastError,omitempty"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	NextAttemptAt *time.Time   `json:"nextAttemptAt,omitempty"` // pending only
+	DeadAt        *time.Time   `json:"deadAt,omitempty"`        // dead letters only
+}
+
+// broadcast sends the event to every channel its route selects, through
+// the grouping window when one is configured. An incident's UP and ACK
+// also go to every channel that alerted on it.
+func (s *Store) broadcast(e notify.Event) {
+	s.Lock()
+	names := s.incidentChannelsLocked(e, s.routeLocked(e).Channels)
+	var direct []string
+	for _, name := range names {
+		if !s.batchLocked(name, e) {
+			direct = append(direct, name)
+		}
+	}
+	s.Unlock()
+
+	for _, name := range direct {
+		s.deliver(name, e)
+	}
+	s.wakeDeliveries()
+}
+
+// incidentChannelsLocked records where a DOWN went on the incidents it
+// covers, and adds those channels to the incidents' UP and ACK events.
+// Caller must hold the lock.
+func (s *Store) incidentChannelsLocked(e notify.Event, names []string) []string {
+	ids := e.Grouped
+	if e.IncidentID > 0 {
+		ids = []int{e.IncidentID}
+	}
+	changed := false
+	for _, id := range ids {
+		inc := s.incidentLocked(id)
+		if inc == nil {
+			continue
+		}
+		switch e.Type {
+		case notify.EventDown:
+			changed = inc.addAlerted(names) || changed
+		case notify.EventUp, notify.EventAck:
+			for _, name := range inc.AlertedChannels {
+				if _, ok := s.channels[name]; ok && !slices.Contains(names, name) {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	if changed {
+		if err := s.saveLocked(); err != nil {
+			log.Println("notify: save:", err)
+		}
+	}
+	return names
+}
+
+// addAlerted adds channels to AlertedChannels, reporting whether any
+// was new.
+func (inc *Incident) addAlerted(names []string) bool {
+	changed := false
+	for _, name := range names {
+		if !slices.Contains(inc.AlertedChannels, name) {
+			inc.AlertedChannels = append(inc.AlertedChannels, name)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// deliver queues the event for one channel. Without SQLite it falls back
+// to a single best-effort attempt.
+func (s *Store) deliver(name string, e notify.Event) {
+	s.Lock()
+	db := s.db
+	s.Unlock()
+	if db == nil {
+		c, ok := s.channel(name)
+		if !ok {
+			return
+		}
+		start := time.Now()
+		err := c.Send(context.Background(), e)
+		entry := newDeliveryLogEntry(name, 1, e, start)
+		if err != nil {
+			log.Printf("notify %s: %v", name, err)
+			entry.Status, entry.Error = DeliveryDead, err.Error()
+		}
+		s.logDelivery(entry, e.Grouped)
+		return
+	}
+	if err := db.enqueueDelivery(name, e, time.Now().UTC()); err != nil {
+		log.Printf("notify %s: enqueue: %v", name, err)
+	}
+}
+
+func (s *Store) wakeDeliveries() {
+	s.Lock()
+	ch := s.deliveryWake
+	s.Unlock()
+	if ch == nil {
+		return
+	}
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// StartDeliveries runs the worker that drains the outbox, retrying failed
+// deliveries with exponential backoff. Requires SetSQLStore.
+func (s *Store) StartDeliveries() {
+	wake := make(chan struct{}, 1)
+	s.Lock()
+	s.deliveryWake = wake
+	s.Unlock()
+	go func() {
+		t := time.NewTicker(time.Second)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+			case <-wake:
+			}
+			s.deliverDue(time.Now().UTC())
+		}
+	}()
+}
+
+// deliverDue hands due deliveries to one worker per channel, which
+// attempts them in order. Channels whose worker is still busy are left
+// for a later run, so a slow or timing-out endpoint only holds up its
+// own deliveries.
+func (s *Store) deliverDue(now time.Time) {
+	s.Lock()
+	db := s.db
+	var busy []string
+	for name := range s.deliveryBusy {
+		busy = append(busy, name)
+	}
+	s.Unlock()
+	if db == nil {
+		return
+	}
+	due, err := db.dueDeliveries(now, deliveryBatch, busy)
+	if err != nil {
+		log.Println("deliveries: load:", err)
+		return
+	}
+	byChannel := make(map[string][]Delivery)
+	var order []string
+	for _, d := range due {
+		if _, ok := byChannel[d.Channel]; !ok {
+			order = append(order, d.Channel)
+		}
+		byChannel[d.Channel] = append(byChannel[d.Channel], d)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	for _, name := range order {
+		if s.deliveryBusy[name] {
+			continue
+		}
+		s.deliveryBusy[name] = true
+		go s.deliveryWorker(db, name, byChannel[name])
+	}
+}
+
+// deliveryWorker attempts one channel's deliveries, then frees the
+// channel and asks for another run in case more became due meanwhile.
+func (s *Store) deliveryWorker(db *SQLStore, name string, ds []Delivery) {
+	for _, d := range ds {
+		s.attemptDelivery(db, d)
+	}
+	s.Lock()
+	delete(s.deliveryBusy, name)
+	s.Unlock()
+	s.wakeDeliveries()
+}
+
+func (s *Store) attemptDelivery(db *SQLStore, d Delivery) {
+	c, ok := s.channel(d.Channel)
+	var err error
+	start := time.Now()
+	if !ok {
+		err = errors.New("channel not configured")
+	} else {
+		ctx := notify.WithDeliveryID(context.Background(), fmt.Sprintf("%d-%d", d.ID, d.CreatedAt.UnixMilli()))
+		err = c.Send(ctx, d.Event)
+	}
+	now := time.Now().UTC()
+	entry := newDeliveryLogEntry(d.Channel, d.Attempts+1, d.Event, start)
+	entry.DeliveryID = d.ID
+	if err == nil {
+		s.logDelivery(entry, d.Event.Grouped)
+		if err := db.deleteDelivery(d.ID); err != nil {
+			log.Println("deliveries: delete:", err)
+		}
+		return
+	}
+
+	d.Attempts++
+	d.LastError = err.Error()
+	entry.Error = d.LastError
+	if !ok || d.Attempts >= maxDeliveryAttempts {
+		log.Printf("notify %s: giving up after %d attempts: %v", d.Channel, d.Attempts, err)
+		entry.Status = DeliveryDead
+		s.logDelivery(entry, d.Event.Grouped)
+		if err := db.killDelivery(d, now); err != nil {
+			log.Println("deliveries: dead-letter:", err)
+		}
+		return
+	}
+	entry.Status = DeliveryRetrying
+	s.logDelivery(entry, d.Event.Grouped)
+	if err := db.retryDelivery(d, now.Add(deliveryBackoff(d.Attempts))); err != nil {
+		log.Println("deliveries: reschedule:", err)
+	}
+}
+
+// newDeliveryLogEntry describes an attempt begun at start, as sent.
+func newDeliveryLogEntry(channel string, attempt int, e notify.Event, start time.Time) DeliveryLogEntry {
+	now := time.Now().UTC()
+	return DeliveryLogEntry{
+		Channel:    channel,
+		Attempt:    attempt,
+		EventType:  e.Type,
+		AlertKey:   e.AlertKey,
+		Title:      e.Title(),
+		ServiceID:  e.ServiceID,
+		IncidentID: e.IncidentID,
+		Status:     DeliverySent,
+		LatencyMs:  int(now.Sub(start).Milliseconds()),
+		At:         now,
+	}
+}
+
+// deliveryBackoff doubles per attempt (5s, 10s, 20s, ...) up to 10m,
+// with ±20% jitter so a recovering endpoint isn't hit all at once.
+func deliveryBackoff(attempts int) time.Duration {
+	d := deliveryBaseBackoff << (attempts - 1)
+	if d <= 0 || d > deliveryMaxBackoff {
+		d = deliveryMaxBackoff
+	}
+	jitter := time.Duration(rand.Int63n(int64(d)/5*2+1)) - d/5
+	return d + jitter
+}
+
+func (s *Store) sqlStore() (*SQLStore, error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.db == nil {
+		return nil, ErrNoDeliveryQueue
+	}
+	return s.db, nil
+}
+
+// PendingDeliveries returns queued deliveries, soonest first.
+func (s *Store) PendingDeliveries(limit int) ([]Delivery, error) {
+	db, err := s.sqlStore()
+	if err != nil {
+		return nil, err
+	}
+	return db.listPendingDeliveries(limit)
+}
+
+// DeadLetters returns deliveries that were given up on, newest first.
+func (s *Store) DeadLetters(limit int) ([]Delivery, error) {
+	db, err := s.sqlStore()
+	if err != nil {
+		return nil, err
+	}
+	return db.listDeadLetters(limit)
+}
+
+// ReplayDeadLetter re-queues a dead letter with a fresh attempt budget.
+func (s *Store) ReplayDeadLetter(id int64) error {
+	db, err := s.sqlStore()
+	if err != nil {
+		return err
+	}
+	if err := db.replayDeadLetter(id, time.Now().UTC()); err != nil {
+		return err
+	}
+	s.wakeDeliveries()
+	return nil
+}
+
+// DeleteDeadLetter drops a dead letter for good.
+func (s *Store) DeleteDeadLetter(id int64) error {
+	db, err := s.sqlStore()
+	if err != nil {
+		return err
+	}
+	return db.deleteDeadLetter(id)
+}
+
+// / --- SQL --- /
+
+func (s *SQLStore) enqueueDelivery(channel string, e notify.Event, now time.Time) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	ts := now.UTC().Format(queueTimeLayout)
+	_, err = s.DB.Exec(`INSERT INTO outbox(channel, event, attempts, next_attempt_at, last_error, created_at)
+VALUES(?,?,0,?,'',?)`, channel, string(b), ts, ts)
+	return err
+}
+
+// dueDeliveries returns up to limit due deliveries, oldest due first,
+// leaving out the skipped channels.
+func (s *SQLStore) dueDeliveries(now time.Time, limit int, skip []string) ([]Delivery, error) {
+	args := []any{now.UTC().Format(queueTimeLayout)}
+	stmt := `SELECT id, channel, event, attempts, last_error, created_at, next_attempt_at
+FROM outbox WHERE next_attempt_at <= ?`
+	if len(skip) > 0 {
+		stmt += ` AND channel NOT IN (?` + strings.Repeat(`,?`, len(skip)-1) + `)`
+		for _, name := range skip {
+			args = append(args, name)
+		}
+	}
+	stmt += ` ORDER BY next_attempt_at, id LIMIT ?`
+	args = append(args, limit)
+	rows, err := s.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanDeliveries(rows, false)
+}
+
+func (s *SQLStore) deleteDelivery(id int64) error {
+	_, err := s.DB.Exec(`DELETE FROM outbox WHERE id = ?`, id)
+	return err
+}
+
+func (s *SQLStore) retryDelivery(d Delivery, next time.Time) error {
+	_, err := s.DB.Exec(`UPDATE outbox SET attempts = ?, last_error = ?, next_attempt_at = ? WHERE id = ?`,
+		d.Attempts, d.LastError, next.UTC().Format(queueTimeLayout), d.ID)
+	return err
+}
+
+func (s *SQLStore) killDelivery(d Delivery, now time.Time) error {
+	b, err := json.Marshal(d.Event)
+	if err != nil {
+		return err
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`INSERT INTO dead_letters(channel, event, attempts, last_error, created_at, dead_at)
+VALUES(?,?,?,?,?,?)`, d.Channel, string(b), d.Attempts, d.LastError,
+		d.CreatedAt.UTC().Format(queueTimeLayout), now.UTC().Format(queueTimeLayout)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM outbox WHERE id = ?`, d.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// listPendingDeliveries returns queued deliveries, soonest first.
+func (s *SQLStore) listPendingDeliveries(limit int) ([]Delivery, error) {
+	rows, err := s.DB.Query(`SELECT id, channel, event, attempts, last_error, created_at, next_attempt_at
+FROM outbox ORDER BY next_attempt_at LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanDeliveries(rows, false)
+}
+
+// listDeadLetters returns deliveries that were given up on, newest first.
+func (s *SQLStore) listDeadLetters(limit int) ([]Delivery, error) {
+	rows, err := s.DB.Query(`SELECT id, channel, event, attempts, last_error, created_at, dead_at
+FROM dead_letters ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanDeliveries(rows, true)
+}
+
+// replayDeadLetter moves a dead letter back into the outbox with a fresh
+// attempt budget.
+func (s *SQLStore) replayDeadLetter(id int64, now time.Time) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var channel, event, created string
+	err = tx.QueryRow(`SELECT channel, event, created_at FROM dead_letters WHERE id = ?`, id).Scan(&channel, &event, &created)
+	if err == sql.ErrNoRows {
+		return ErrDeliveryNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO outbox(channel, event, attempts, next_attempt_at, last_error, created_at)
+VALUES(?,?,0,?,'',?)`, channel, event, now.UTC().Format(queueTimeLayout), created); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM dead_letters WHERE id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleteDeadLetter drops a dead letter for good.
+func (s *SQLStore) deleteDeadLetter(id int64) error {
+	res, err := s.DB.Exec(`DELETE FROM dead_letters WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrDeliveryNotFound
+	}
+	return nil
+}
+
+func scanDeliveries(rows *sql.Rows, dead bool) ([]Delivery, error) {
+	out := []Delivery{}
+	for rows.Next() {
+		var d Delivery
+		var event, created, at string
+		if err := rows.Scan(&d.ID, &d.Channel, &event, &d.Attempts, &d.LastError, &created, &at); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(event), &d.Event); err != nil {
+			return nil, err
+		}
+		d.CreatedAt, _ = time.Parse(time.RFC3339Nano, created)
+		t, _ := time.Parse(time.RFC3339Nano, at)
+		if dead {
+			d.DeadAt = &t
+		} else {
+			d.NextAttemptAt = &t
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
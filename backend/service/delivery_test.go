@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"serverwatcher/notify"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubNotifier counts calls and, with a gate, blocks each one until the
+// gate is closed.
+type stubNotifier struct {
+	gate  chan struct{}
+	calls *atomic.Int32
+}
+
+func (n stubNotifier) Notify(ctx context.Context, e notify.Event) error {
+	n.calls.Add(1)
+	if n.gate != nil {
+		select {
+		case <-n.gate:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func TestDeliverDuePerChannelWorkers(t *testing.T) {
+	s := NewStore()
+	s.SetSQLStore(openTestSQL(t))
+	gate := make(chan struct{})
+	defer close(gate)
+	var slowCalls, fastCalls atomic.Int32
+	s.SetNotifiers([]notify.Channel{
+		{Name: "slow", Notifier: stubNotifier{gate: gate, calls: &slowCalls}},
+		{Name: "fast", Notifier: stubNotifier{calls: &fastCalls}},
+	})
+	e := notify.Event{Type: notify.EventDown, ServiceID: 1}
+	for i := 0; i < 2; i++ {
+		s.deliver("slow", e)
+		s.deliver("fast", e)
+	}
+
+	s.deliverDue(time.Now().UTC())
+	deadline := time.Now().Add(2 * time.Second)
+	for len(queuedEvents(t, s)) > 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := fastCalls.Load(); n != 2 {
+		t.Errorf("fast channel got %d of its deliveries while slow was stuck", n)
+	}
+
+	// the stuck channel's queue is not handed out a second time
+	s.deliverDue(time.Now().UTC())
+	time.Sleep(50 * time.Millisecond)
+	if n := slowCalls.Load(); n != 1 {
+		t.Errorf("slow channel was called %d times, want 1 in flight", n)
+	}
+	if ds, _ := s.PendingDeliveries(10); len(ds) != 2 || ds[0].Channel != "slow" || ds[1].Channel != "slow" {
+		t.Errorf("pending = %+v", ds)
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"log"
+	"serverwatcher/notify"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Delivery attempt outcomes.
+const (
+	DeliverySent     = "sent"
+	DeliveryRetrying = "retrying" // failed, will be retried
+	DeliveryDead     = "dead"     // failed for good, moved to dead letters
+)
+
+// DeliveryLogEntry records one attempt to send one event to one channel.
+type DeliveryLogEntry struct {
+	ID         int64            `json:"id"`
+	DeliveryID int64            `json:"deliveryId"`
+	Channel    string           `json:"channel"`
+	Attempt    int              `json:"attempt"`
+	EventType  notify.EventType `json:"eventType"`
+	AlertKey   string           `json:"alertKey"`
+	Title      string           `json:"title"`
+	ServiceID  int              `json:"serviceId,omitempty"`
+	IncidentID int              `json:"incidentId,omitempty"`
+	Status     string           `json:"status"`
+	Error      string           `json:"error,omitempty"`
+	LatencyMs  int              `json:"latencyMs"`
+	At         time.Time        `json:"at"`
+}
+
+// DeliveryLogQuery filters the delivery log; zero values match anything.
+type DeliveryLogQuery struct {
+	IncidentID int
+	ServiceID  int
+	Channel    string
+	Limit      int
+}
+
+// maxMemDeliveryLog caps the delivery log kept without SQLite.
+const maxMemDeliveryLog = 1000
+
+// DeliveryLog returns matching attempts, newest first.
+func (s *Store) DeliveryLog(q DeliveryLogQuery) ([]DeliveryLogEntry, error) {
+	s.Lock()
+	db := s.db
+	if db == nil {
+		defer s.Unlock()
+		return s.memDeliveryLogLocked(q), nil
+	}
+	s.Unlock()
+	return db.queryDeliveryLog(q)
+}
+
+// logDelivery is best effort: a failing log must not fail the delivery.
+// An aggregated alert is logged once per incident it covers, under that
+// incident's service, so each incident's timeline shows it.
+func (s *Store) logDelivery(e DeliveryLogEntry, grouped []int) {
+	entries := []DeliveryLogEntry{e}
+	s.Lock()
+	if e.IncidentID == 0 && len(grouped) > 0 {
+		entries = entries[:0]
+		for _, id := range grouped {
+			e.IncidentID = id
+			if inc := s.incidentLocked(id); inc != nil {
+				e.ServiceID = inc.ServiceID
+			}
+			entries = append(entries, e)
+		}
+	}
+	db := s.db
+	if db == nil {
+		for _, e := range entries {
+			s.nextDeliveryLogID++
+			e.ID = s.nextDeliveryLogID
+			s.deliveryLog = append(s.deliveryLog, e)
+		}
+		if n := len(s.deliveryLog) - maxMemDeliveryLog; n > 0 {
+			s.deliveryLog = slices.Delete(s.deliveryLog, 0, n)
+		}
+	}
+	s.Unlock()
+	if db != nil {
+		for _, e := range entries {
+			db.insertDeliveryLog(e)
+		}
+	}
+}
+
+func (s *Store) memDeliveryLogLocked(q DeliveryLogQuery) []DeliveryLogEntry {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	out := []DeliveryLogEntry{}
+	for i := len(s.deliveryLog) - 1; i >= 0 && len(out) < limit; i-- {
+		e := s.deliveryLog[i]
+		if (q.IncidentID > 0 && e.IncidentID != q.IncidentID) ||
+			(q.ServiceID > 0 && e.ServiceID != q.ServiceID) ||
+			(q.Channel != "" && e.Channel != q.Channel) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func (s *SQLStore) insertDeliveryLog(e DeliveryLogEntry) {
+	_, err := s.DB.Exec(`INSERT INTO delivery_log
+(delivery_id, channel, attempt, event_type, alert_key, title, service_id, incident_id, status, error, latency_ms, ts)
+VALUES(?,?,?,?,?,?,?,?,?,?,?,?)`,
+		e.DeliveryID, e.Channel, e.Attempt, string(e.EventType), e.AlertKey, e.Title,
+		e.ServiceID, e.IncidentID, e.Status, e.Error, e.LatencyMs, e.At.UTC().Format(queueTimeLayout))
+	if err != nil {
+		log.Println("deliveries: log:", err)
+	}
+}
+
+func (s *SQLStore) queryDeliveryLog(q DeliveryLogQuery) ([]DeliveryLogEntry, error) {
+	var where []string
+	var args []any
+	if q.IncidentID > 0 {
+		where = append(where, "incident_id = ?")
+		args = append(args, q.IncidentID)
+	}
+	if q.ServiceID > 0 {
+		where = append(where, "service_id = ?")
+		args = append(args, q.ServiceID)
+	}
+	if q.Channel != "" {
+		where = append(where, "channel = ?")
+		args = append(args, q.Channel)
+	}
+	query := `SELECT id, delivery_id, channel, attempt, event_type, alert_key, title,
+service_id, incident_id, status, error, latency_ms, ts FROM delivery_log`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []DeliveryLogEntry{}
+	for rows.Next() {
+		var e DeliveryLogEntry
+		var typ, ts string
+		if err := rows.Scan(&e.ID, &e.DeliveryID, &e.Channel, &e.Attempt, &typ, &e.AlertKey, &e.Title,
+			&e.ServiceID, &e.IncidentID, &e.Status, &e.Error, &e.LatencyMs, &ts); err != nil {
+			return nil, err
+		}
+		e.EventType = notify.EventType(typ)
+		e.At, _ = time.Parse(time.RFC3339Nano, ts)
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"serverwatcher/notify"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Digest periods.
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// DigestConfig sends a periodic summary of incidents and uptime to one
+// channel, outside of routing.
+type DigestConfig struct {
+	Channel  string `json:"channel"`           // "" disables the digest
+	Period   string `json:"period"`            // "daily" or "weekly"
+	Hour     int    `json:"hour"`              // local hour it is sent at, 0-23
+	Weekday  string `json:"weekday,omitempty"` // weekly: "mon".."sun", default "mon"
+	Timezone string `json:"timezone,omitempty"`
+
+	LastSentAt *time.Time `json:"lastSentAt,omitempty"`
+}
+
+// DigestService is one service's line in a digest.
+type DigestService struct {
+	ServiceID     int     `json:"serviceId"`
+	Name          string  `json:"name"`
+	UptimePercent float64 `json:"uptimePercent"`
+	Incidents     int     `json:"incidents"` // started in the period
+	DowntimeSec   int     `json:"downtimeSec"`
+}
+
+// Digest summarizes one period.
+type Digest struct {
+	Period        string          `json:"period"`
+	Start         time.Time       `json:"start"`
+	End           time.Time       `json:"end"`
+	UptimePercent float64         `json:"uptimePercent"` // mean over services
+	Incidents     int             `json:"incidents"`
+	Services      []DigestService `json:"services"`
+}
+
+func (c DigestConfig) location() *time.Location {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// periodStart returns when the period ending at due began. Days are
+// counted on the local calendar, so a period spanning a DST change is
+// 23 or 25 hours (or a week off by one hour) rather than drifting.
+func (c DigestConfig) periodStart(due time.Time) time.Time {
+	if c.Period == DigestWeekly {
+		return due.AddDate(0, 0, -7)
+	}
+	return due.AddDate(0, 0, -1)
+}
+
+// lastDue returns the most recent scheduled send time at or before now.
+func (c DigestConfig) lastDue(now time.Time) time.Time {
+	now = now.In(c.location())
+	y, m, d := now.Date()
+	t := time.Date(y, m, d, c.Hour, 0, 0, 0, now.Location())
+	if t.After(now) {
+		t = t.AddDate(0, 0, -1)
+	}
+	if c.Period == DigestWeekly {
+		want, ok := weekdays[strings.ToLower(c.Weekday)]
+		if !ok {
+			want = time.Monday
+		}
+		for t.Weekday() != want {
+			t = t.AddDate(0, 0, -1)
+		}
+	}
+	return t
+}
+
+func (c *DigestConfig) validate() error {
+	if c.Period == "" {
+		c.Period = DigestDaily
+	}
+	if c.Period != DigestDaily && c.Period != DigestWeekly {
+		return fmt.Errorf("period must be %s or %s", DigestDaily, DigestWeekly)
+	}
+	if c.Hour < 0 || c.Hour > 23 {
+		return fmt.Errorf("hour must be 0-23")
+	}
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q", c.Timezone)
+	}
+	if c.Weekday != "" {
+		if _, ok := weekdays[strings.ToLower(c.Weekday)]; !ok {
+			return fmt.Errorf("invalid weekday %q (mon..sun)", c.Weekday)
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetDigestConfig() DigestConfig {
+	s.Lock()
+	defer s.Unlock()
+	return s.digest
+}
+
+// SetDigestConfig validates and stores the digest config. The first
+// digest goes out at the next scheduled time, not right away.
+func (s *Store) SetDigestConfig(c DigestConfig) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	if c.Channel != "" {
+		if _, ok := s.channels[c.Channel]; !ok {
+			return fmt.Errorf("unknown channel %q", c.Channel)
+		}
+	}
+	now := time.Now().UTC()
+	c.LastSentAt = &now
+	s.digest = c
+	return s.saveLocked()
+}
+
+// StartDigests checks once a minute whether a digest is due.
+func (s *Store) StartDigests() {
+	go func() {
+		t := time.NewTicker(time.Minute)
+		defer t.Stop()
+		for now := range t.C {
+			s.sendDigestIfDue(now.UTC())
+		}
+	}()
+}
+
+func (s *Store) sendDigestIfDue(now time.Time) {
+	s.Lock()
+	c := s.digest
+	if c.Channel == "" {
+		s.Unlock()
+		return
+	}
+	due := c.lastDue(now)
+	if c.LastSentAt != nil && !c.LastSentAt.Before(due) {
+		s.Unlock()
+		return
+	}
+	s.digest.LastSentAt = &now
+	err := s.saveLocked()
+	s.Unlock()
+	if err != nil {
+		log.Println("digest: save:", err)
+	}
+
+	d := s.BuildDigest(c.Period, c.periodStart(due), due)
+	s.deliver(c.Channel, digestEvent(d, now))
+	s.wakeDeliveries()
+}
+
+// PreviewDigest builds the digest for the period ending at the last
+// scheduled time.
+func (s *Store) PreviewDigest(now time.Time) Digest {
+	c := s.GetDigestConfig()
+	if c.Period == "" {
+		c.Period = DigestDaily
+	}
+	due := c.lastDue(now)
+	return s.BuildDigest(c.Period, c.periodStart(due), due)
+}
+
+// BuildDigest summarizes uptime and incidents of every service in
+// [start, end); services with incidents come first.
+func (s *Store) BuildDigest(period string, start, end time.Time) Digest {
+	d := Digest{Period: period, Start: start, End: end, Services: []DigestService{}}
+	w := span{start, end}
+
+	s.Lock()
+	for _, svc := range s.services {
+		incs := s.Incidents[svc.ID]
+		down := totalSpans(incidentSpans(incs, w))
+		ds := DigestService{
+			ServiceID:     svc.ID,
+			Name:          svc.Name,
+			UptimePercent: 100 * (1 - down.Seconds()/w.dur().Seconds()),
+			DowntimeSec:   int(down.Seconds()),
+		}
+		for _, inc := range incs {
+			if !inc.StartedAt.Before(start) && inc.StartedAt.Before(end) {
+				ds.Incidents++
+			}
+		}
+		d.Incidents += ds.Incidents
+		d.UptimePercent += ds.UptimePercent
+		d.Services = append(d.Services, ds)
+	}
+	s.Unlock()
+
+	if n := len(d.Services); n > 0 {
+		d.UptimePercent /= float64(n)
+	} else {
+		d.UptimePercent = 100
+	}
+	sort.Slice(d.Services, func(i, j int) bool {
+		a, b := d.Services[i], d.Services[j]
+		if a.DowntimeSec != b.DowntimeSec {
+			return a.DowntimeSec > b.DowntimeSec
+		}
+		return a.Name < b.Name
+	})
+	return d
+}
+
+// Text renders the digest as plain text.
+func (d Digest) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s to %s\n", d.Start.Format("2006-01-02 15:04"), d.End.Format("2006-01-02 15:04 MST"))
+	fmt.Fprintf(&b, "%d services, %d incidents, mean uptime %.3f%%\n", len(d.Services), d.Incidents, d.UptimePercent)
+	clean := 0
+	for _, ds := range d.Services {
+		if ds.DowntimeSec == 0 && ds.Incidents == 0 {
+			clean++
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s: %.3f%% up, %d incidents, %s down", ds.Name, ds.UptimePercent, ds.Incidents,
+			notify.HumanDuration(time.Duration(ds.DowntimeSec)*time.Second))
+	}
+	if clean > 0 {
+		fmt.Fprintf(&b, "\n%d services had no downtime", clean)
+	}
+	return b.String()
+}
+
+func digestEvent(d Digest, now time.Time) notify.Event {
+	return notify.Event{
+		Type:        notify.EventDigest,
+		Severity:    notify.SeverityInfo,
+		ServiceName: d.Period + " digest",
+		AlertKey:    fmt.Sprintf("digest-%s-%d", d.Period, d.End.Unix()),
+		StartedAt:   d.Start,
+		OccurredAt:  now,
+		Message:     d.Text(),
+	}
+}
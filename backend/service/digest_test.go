@@ -0,0 +1,182 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"serverwatcher/notify"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDigestLastDue(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	daily := DigestConfig{Period: DigestDaily, Hour: 9, Timezone: "Europe/Berlin"}
+	weekly := DigestConfig{Period: DigestWeekly, Hour: 9, Weekday: "mon", Timezone: "Europe/Berlin"}
+	at := func(mon, day, h, m int) time.Time { return time.Date(2026, time.Month(mon), day, h, m, 0, 0, berlin) }
+	for _, c := range []struct {
+		name string
+		c    DigestConfig
+		now  time.Time
+		want time.Time
+	}{
+		{"daily: right at the hour", daily, at(3, 10, 9, 0), at(3, 10, 9, 0)},
+		{"daily: before the hour is yesterday's", daily, at(3, 10, 8, 59), at(3, 9, 9, 0)},
+		// 2026-03-29 is the switch to summer time: 09:00 local is 07:00 UTC
+		{"daily: the day summer time starts", daily, at(3, 29, 12, 0), at(3, 29, 9, 0)},
+		{"daily: the day after", daily, at(3, 30, 8, 0), at(3, 29, 9, 0)},
+		// and 2026-10-25 back to winter time
+		{"daily: the day winter time starts", daily, at(10, 25, 10, 0), at(10, 25, 9, 0)},
+		// 2026-03-11 is a Wednesday
+		{"weekly: mid-week", weekly, at(3, 11, 12, 0), at(3, 9, 9, 0)},
+		{"weekly: Monday before the hour", weekly, at(3, 9, 8, 0), at(3, 2, 9, 0)},
+		{"weekly: across summer time", weekly, at(3, 31, 12, 0), at(3, 30, 9, 0)},
+		{"weekly: weekday defaults to Monday", DigestConfig{Period: DigestWeekly, Hour: 9, Timezone: "Europe/Berlin"},
+			at(3, 11, 12, 0), at(3, 9, 9, 0)},
+	} {
+		got := c.c.lastDue(c.now.UTC())
+		if !got.Equal(c.want) || got.In(berlin).Hour() != 9 {
+			t.Errorf("%s: lastDue(%v) = %v, want %v", c.name, c.now, got, c.want)
+		}
+	}
+}
+
+func TestDigestPeriodStart(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	daily := DigestConfig{Period: DigestDaily, Hour: 9, Timezone: "Europe/Berlin"}
+	weekly := DigestConfig{Period: DigestWeekly, Hour: 9, Timezone: "Europe/Berlin"}
+	at := func(mon, day int) time.Time { return time.Date(2026, time.Month(mon), day, 9, 0, 0, 0, berlin) }
+	for _, c := range []struct {
+		name string
+		c    DigestConfig
+		due  time.Time
+		want time.Duration
+	}{
+		{"daily", daily, at(3, 10), 24 * time.Hour},
+		{"daily over summer time", daily, at(3, 29), 23 * time.Hour},
+		{"daily over winter time", daily, at(10, 25), 25 * time.Hour},
+		{"weekly over summer time", weekly, at(3, 30), 7*24*time.Hour - time.Hour},
+	} {
+		due := c.c.lastDue(c.due.UTC())
+		start := c.c.periodStart(due)
+		if got := due.Sub(start); got != c.want || start.In(berlin).Hour() != 9 {
+			t.Errorf("%s: period %v to %v is %v, want %v", c.name, start, due, got, c.want)
+		}
+	}
+}
+
+func TestDigestConfigValidate(t *testing.T) {
+	for _, c := range []DigestConfig{
+		{Period: "monthly"},
+		{Hour: 24},
+		{Timezone: "Nowhere/Town"},
+		{Period: DigestWeekly, Weekday: "monday"},
+	} {
+		if err := c.validate(); err == nil {
+			t.Errorf("%+v accepted", c)
+		}
+	}
+	c := DigestConfig{Hour: 7}
+	if err := c.validate(); err != nil || c.Period != DigestDaily {
+		t.Errorf("defaults: %+v, %v", c, err)
+	}
+}
+
+func TestSendDigestIfDue(t *testing.T) {
+	s := NewStore()
+	s.SetSQLStore(openTestSQL(t))
+	s.SetNotifiers([]notify.Channel{{Name: "ops", Notifier: notify.Webhook{URL: "http://127.0.0.1:1"}}})
+	svc := addTestService(s, "api", "https://api.example.com")
+	addTestService(s, "web", "https://example.com")
+	day := func(d, h int) time.Time { return time.Date(2026, 3, d, h, 0, 0, 0, time.UTC) }
+	end := day(2, 3)
+	s.Incidents[svc.ID] = []*Incident{{ID: 1, ServiceID: svc.ID, StartedAt: day(2, 2), EndedAt: &end}}
+
+	if err := s.SetDigestConfig(DigestConfig{Channel: "sms"}); err == nil {
+		t.Error("unknown channel accepted")
+	}
+	if err := s.SetDigestConfig(DigestConfig{Channel: "ops", Period: DigestDaily, Hour: 8}); err != nil {
+		t.Fatal(err)
+	}
+	sent := day(1, 9) // as if the last digest went out yesterday
+	s.digest.LastSentAt = &sent
+
+	s.sendDigestIfDue(day(2, 7))
+	if n := len(queuedEvents(t, s)); n != 0 {
+		t.Fatalf("sent %d digests before the hour", n)
+	}
+	s.sendDigestIfDue(day(2, 8))
+	s.sendDigestIfDue(day(2, 9)) // already sent today
+	events := queuedEvents(t, s)
+	if len(events) != 1 {
+		t.Fatalf("sent %d digests, want 1", len(events))
+	}
+	e := events[0]
+	if e.Type != notify.EventDigest || e.ServiceName != "daily digest" || !e.StartedAt.Equal(day(1, 8)) {
+		t.Errorf("digest event = %+v", e)
+	}
+	if !strings.Contains(e.Message, "2 services, 1 incidents") || !strings.Contains(e.Message, "api: 95.833% up") ||
+		!strings.Contains(e.Message, "1 services had no downtime") {
+		t.Errorf("message = %q", e.Message)
+	}
+	if got := s.GetDigestConfig().LastSentAt; got == nil || !got.Equal(day(2, 8)) {
+		t.Errorf("last sent at %v", got)
+	}
+}
+
+func TestBuildDigest(t *testing.T) {
+	s := NewStore()
+	a := addTestService(s, "b-api", "https://api.example.com")
+	b := addTestService(s, "a-web", "https://example.com")
+	c := addTestService(s, "c-db", "tcp://db:5432")
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	h := func(n int) *time.Time { t := start.Add(time.Duration(n) * time.Hour); return &t }
+	s.Incidents[a.ID] = []*Incident{{ID: 1, ServiceID: a.ID, StartedAt: *h(-2), EndedAt: h(1)}} // started before
+	s.Incidents[c.ID] = []*Incident{{ID: 2, ServiceID: c.ID, StartedAt: *h(20), EndedAt: h(22)}}
+
+	d := s.BuildDigest(DigestDaily, start, start.Add(24*time.Hour))
+	if d.Incidents != 1 || len(d.Services) != 3 {
+		t.Fatalf("digest = %+v", d)
+	}
+	// most downtime first, then by name
+	var names []string
+	for _, ds := range d.Services {
+		names = append(names, ds.Name)
+	}
+	if got := strings.Join(names, ","); got != "c-db,b-api,a-web" {
+		t.Errorf("order = %s", got)
+	}
+	if ds := d.Services[1]; ds.ServiceID != a.ID || ds.DowntimeSec != 3600 || ds.Incidents != 0 {
+		t.Errorf("b-api = %+v", ds)
+	}
+	if ds := d.Services[2]; ds.ServiceID != b.ID || ds.UptimePercent != 100 {
+		t.Errorf("a-web = %+v", ds)
+	}
+	if want := 100 - 100*3.0/(3*24); math.Abs(d.UptimePercent-want) > 1e-9 {
+		t.Errorf("mean uptime = %v, want %v", d.UptimePercent, want)
+	}
+
+	if d := NewStore().BuildDigest(DigestDaily, start, start.Add(24*time.Hour)); d.UptimePercent != 100 {
+		t.Errorf("empty digest uptime = %v", d.UptimePercent)
+	}
+}
+
+func TestChannelInUseByDigest(t *testing.T) {
+	s := NewStore()
+	c, err := s.CreateChannel(NotificationChannel{Name: "mail", Type: "webhook", Settings: map[string]string{"url": "http://127.0.0.1:1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetDigestConfig(DigestConfig{Channel: "mail"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteChannel(c.ID); !errors.Is(err, ErrChannelInUse) || !strings.Contains(err.Error(), "the digest") {
+		t.Errorf("err = %v", err)
+	}
+}
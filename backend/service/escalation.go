@@ -0,0 +1,219 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"serverwatcher/notify"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrEscalationNotFound = errors.New("escalation policy not found")
+
+// EscalationPolicy pages further channels while a DOWN incident stays
+// unacknowledged. Steps fire in order, each AfterMin minutes after the
+// previous one (the first after the incident opened); the whole sequence
+// runs Repeat more times after the last step. It stops on ack or resolve.
+type EscalationPolicy struct {
+	ID         int              `json:"id"`
+	Name       string           `json:"name"`
+	ServiceIDs []int            `json:"serviceIds,omitempty"` // attached services
+	Tags       []string         `json:"tags,omitempty"`       // or services with any of these tags
+	Steps      []EscalationStep `json:"steps"`
+	Repeat     int              `json:"repeat,omitempty"`
+}
+
+type EscalationStep struct {
+	AfterMin int      `json:"afterMin"`
+	Channels []string `json:"channels"` // channel names or "oncall:<team>"
+}
+
+// escalationForLocked picks the policy for a service: one attached by service
+// ID wins over one attached by tag; ties go to the lowest ID.
+func (s *Store) escalationForLocked(svc *Service) *EscalationPolicy {
+	var byTag *EscalationPolicy
+	for _, p := range s.escalations {
+		if slices.Contains(p.ServiceIDs, svc.ID) {
+			return p
+		}
+		if byTag == nil && anyTag(p.Tags, svc.Tags) {
+			byTag = p
+		}
+	}
+	return byTag
+}
+
+// StartEscalations runs the escalation timer.
+func (s *Store) StartEscalations() {
+	go func() {
+		t := time.NewTicker(15 * time.Second)
+		defer t.Stop()
+		for now := range t.C {
+			s.escalate(now.UTC())
+		}
+	}()
+}
+
+type escalationSend struct {
+	channels []string
+	event    notify.Event
+}
+
+func (s *Store) escalate(now time.Time) {
+	s.Lock()
+	var sends []escalationSend
+	for _, inc := range s.openIncidentsLocked() {
+		svc := s.subjectLocked(inc)
+		if svc == nil {
+			continue
+		}
+		p := s.escalationForLocked(svc)
+		if p == nil || len(p.Steps) == 0 {
+			continue
+		}
+		if inc.Escalations >= len(p.Steps)*(p.Repeat+1) {
+			continue
+		}
+		step := p.Steps[inc.Escalations%len(p.Steps)]
+		last := inc.StartedAt
+		if inc.LastEscalatedAt != nil {
+			last = *inc.LastEscalatedAt
+		}
+		if now.Sub(last) < time.Duration(step.AfterMin)*time.Minute {
+			continue
+		}
+		// a silenced service doesn't escalate; the step fires once the
+		// silence ends, if still unacknowledged
+		if s.isSilencedLocked(svc) {
+			continue
+		}
+		inc.Escalations++
+		inc.LastEscalatedAt = &now
+		inc.Alerted = true
+
+		e := s.newEventLocked(notify.EventDown, notifySeverity(inc.Severity), svc, now)
+		e.IncidentID = inc.ID
+		e.AlertKey = incidentKey(inc.ID)
+		e.StartedAt = inc.StartedAt
+		e.Reason = inc.Reason
+		e.Escalation = inc.Escalations
+		e.Summary = fmt.Sprintf("Escalated by %q: not acknowledged for %s", p.Name, notify.HumanDuration(now.Sub(inc.StartedAt)))
+		channels := s.resolveTargetsLocked(step.Channels, now)
+		inc.addAlerted(channels)
+		sends = append(sends, escalationSend{channels: channels, event: e})
+	}
+	var err error
+	if len(sends) > 0 {
+		err = s.saveLocked()
+	}
+	s.Unlock()
+
+	if err != nil {
+		log.Println("escalations: save:", err)
+	}
+	for _, x := range sends {
+		for _, name := range x.channels {
+			s.deliver(name, x.event)
+		}
+	}
+	if len(sends) > 0 {
+		s.wakeDeliveries()
+	}
+}
+
+// resolveTargetsLocked turns step targets into channel names, paging
+// whoever is on call at now for "oncall:" targets and dropping unknown
+// channels.
+func (s *Store) resolveTargetsLocked(targets []string, now time.Time) []string {
+	var out []string
+	for _, t := range targets {
+		for _, name := range s.targetChannelsLocked(t, now) {
+			if _, ok := s.channels[name]; ok && !slices.Contains(out, name) {
+				out = append(out, name)
+			}
+		}
+	}
+	return out
+}
+
+// / --- CRUD --- /
+
+func (s *Store) ListEscalationPolicies() []EscalationPolicy {
+	s.Lock()
+	defer s.Unlock()
+	out := make([]EscalationPolicy, 0, len(s.escalations))
+	for _, p := range s.escalations {
+		out = append(out, *p)
+	}
+	return out
+}
+
+func (s *Store) CreateEscalationPolicy(p EscalationPolicy) (EscalationPolicy, error) {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.validateEscalationLocked(&p); err != nil {
+		return EscalationPolicy{}, err
+	}
+	s.nextEscalationID++
+	p.ID = s.nextEscalationID
+	s.escalations = append(s.escalations, &p)
+	return p, s.saveLocked()
+}
+
+func (s *Store) UpdateEscalationPolicy(id int, p EscalationPolicy) (EscalationPolicy, error) {
+	s.Lock()
+	defer s.Unlock()
+	for _, cur := range s.escalations {
+		if cur.ID != id {
+			continue
+		}
+		if err := s.validateEscalationLocked(&p); err != nil {
+			return EscalationPolicy{}, err
+		}
+		p.ID = id
+		*cur = p
+		return p, s.saveLocked()
+	}
+	return EscalationPolicy{}, ErrEscalationNotFound
+}
+
+func (s *Store) DeleteEscalationPolicy(id int) error {
+	s.Lock()
+	defer s.Unlock()
+	for i, p := range s.escalations {
+		if p.ID == id {
+			s.escalations = append(s.escalations[:i], s.escalations[i+1:]...)
+			return s.saveLocked()
+		}
+	}
+	return ErrEscalationNotFound
+}
+
+func (s *Store) validateEscalationLocked(p *EscalationPolicy) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(p.Steps) == 0 {
+		return errors.New("at least one step is required")
+	}
+	if p.Repeat < 0 {
+		return errors.New("repeat must be >= 0")
+	}
+	for i, st := range p.Steps {
+		if st.AfterMin < 0 {
+			return fmt.Errorf("step %d: afterMin must be >= 0", i+1)
+		}
+		if len(st.Channels) == 0 {
+			return fmt.Errorf("step %d: no channels", i+1)
+		}
+		for _, c := range st.Channels {
+			if err := s.validateTargetLocked(c); err != nil {
+				return fmt.Errorf("step %d: %v", i+1, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"errors"
+	"serverwatcher/notify"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// escalationStore has channels "chat", "pager" and "phone" and one open
+// incident of service "api", started at start.
+func escalationStore(t *testing.T, start time.Time) (*Store, *Incident) {
+	t.Helper()
+	s := NewStore()
+	s.SetSQLStore(openTestSQL(t))
+	var chs []notify.Channel
+	for _, name := range []string{"chat", "pager", "phone"} {
+		chs = append(chs, notify.Channel{Name: name, Notifier: notify.Webhook{URL: "http://127.0.0.1:1"}})
+	}
+	s.SetNotifiers(chs)
+	svc := addTestService(s, "api", "https://api.example.com")
+	inc := &Incident{ID: 9, ServiceID: svc.ID, StartedAt: start, Reason: "timeout"}
+	s.Incidents[svc.ID] = []*Incident{inc}
+	s.openIncident[svc.ID] = inc
+	return s, inc
+}
+
+// queuedChannels lists the channels of queued deliveries, sorted.
+func queuedChannels(t *testing.T, s *Store) []string {
+	t.Helper()
+	ds, err := s.PendingDeliveries(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, d := range ds {
+		out = append(out, d.Channel)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestEscalationStepTiming(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s, inc := escalationStore(t, start)
+	_, err := s.CreateEscalationPolicy(EscalationPolicy{
+		Name:       "api on-call",
+		ServiceIDs: []int{inc.ServiceID},
+		Steps: []EscalationStep{
+			{AfterMin: 5, Channels: []string{"chat"}},
+			{AfterMin: 10, Channels: []string{"pager", "phone"}},
+		},
+		Repeat: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		at    time.Duration // since the incident started
+		steps int           // steps fired so far
+	}{
+		{4 * time.Minute, 0},
+		{5 * time.Minute, 1},
+		// the second step counts from the first, not from the start
+		{10 * time.Minute, 1},
+		{15 * time.Minute, 2},
+		// repeat: the first step again, 5 minutes after the last one
+		{20 * time.Minute, 3},
+		{30 * time.Minute, 4},
+		{time.Hour, 4}, // 2 steps x (1 + 1 repeat)
+	} {
+		s.escalate(start.Add(c.at))
+		if inc.Escalations != c.steps {
+			t.Errorf("after %v: %d steps fired, want %d", c.at, inc.Escalations, c.steps)
+		}
+	}
+	if got := strings.Join(queuedChannels(t, s), ","); got != "chat,chat,pager,pager,phone,phone" {
+		t.Errorf("sent to %s", got)
+	}
+	events := queuedEvents(t, s)
+	if e := events[len(events)-1]; e.Escalation != 4 || e.AlertKey != "incident-9" ||
+		!strings.Contains(e.Summary, `Escalated by "api on-call"`) {
+		t.Errorf("last escalation = %+v", e)
+	}
+}
+
+func TestEscalationStops(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	steps := []EscalationStep{{AfterMin: 0, Channels: []string{"pager"}}}
+	for _, c := range []struct {
+		name  string
+		setup func(s *Store, inc *Incident)
+	}{
+		{"acknowledged", func(s *Store, inc *Incident) {
+			if _, err := s.AcknowledgeIncident(inc.ID, "alice"); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"resolved", func(s *Store, inc *Incident) { end := start; inc.EndedAt = &end }},
+		{"silenced", func(s *Store, inc *Incident) {
+			s.silences = []*Silence{{ID: 1, ServiceID: &inc.ServiceID, CreatedAt: start, Until: time.Now().Add(time.Hour)}}
+		}},
+		{"no policy", func(s *Store, _ *Incident) { s.escalations = nil }},
+	} {
+		s, inc := escalationStore(t, start)
+		if _, err := s.CreateEscalationPolicy(EscalationPolicy{Name: "p", ServiceIDs: []int{inc.ServiceID}, Steps: steps}); err != nil {
+			t.Fatal(err)
+		}
+		c.setup(s, inc)
+		s.escalate(start.Add(time.Hour))
+		if inc.Escalations != 0 || inc.LastEscalatedAt != nil {
+			t.Errorf("%s: escalated", c.name)
+		}
+	}
+}
+
+func TestEscalationPolicySelection(t *testing.T) {
+	s := NewStore()
+	s.SetNotifiers([]notify.Channel{{Name: "pager", Notifier: notify.Webhook{URL: "http://127.0.0.1:1"}}})
+	steps := []EscalationStep{{Channels: []string{"pager"}}}
+	svc := addTestService(s, "api", "https://api.example.com")
+	svc.Tags = []string{"team:api"}
+	other := addTestService(s, "web", "https://example.com")
+	for _, p := range []EscalationPolicy{
+		{Name: "by tag", Tags: []string{"team:api"}, Steps: steps},
+		{Name: "by tag too", Tags: []string{"team:api"}, Steps: steps},
+		{Name: "by id", ServiceIDs: []int{svc.ID}, Steps: steps},
+	} {
+		if _, err := s.CreateEscalationPolicy(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Lock()
+	defer s.Unlock()
+	if p := s.escalationForLocked(svc); p == nil || p.Name != "by id" {
+		t.Errorf("api gets %+v, want the policy attached by ID", p)
+	}
+	s.escalations = s.escalations[:2]
+	if p := s.escalationForLocked(svc); p == nil || p.Name != "by tag" {
+		t.Errorf("api gets %+v, want the first tag match", p)
+	}
+	if p := s.escalationForLocked(other); p != nil {
+		t.Errorf("web gets %+v", p)
+	}
+}
+
+func TestEscalationPolicyValidates(t *testing.T) {
+	s := NewStore()
+	s.SetNotifiers([]notify.Channel{{Name: "pager", Notifier: notify.Webhook{URL: "http://127.0.0.1:1"}}})
+	for _, c := range []struct {
+		p    EscalationPolicy
+		want string
+	}{
+		{EscalationPolicy{Steps: []EscalationStep{{Channels: []string{"pager"}}}}, "name is required"},
+		{EscalationPolicy{Name: "p"}, "at least one step"},
+		{EscalationPolicy{Name: "p", Repeat: -1, Steps: []EscalationStep{{Channels: []string{"pager"}}}}, "repeat"},
+		{EscalationPolicy{Name: "p", Steps: []EscalationStep{{AfterMin: -5, Channels: []string{"pager"}}}}, "step 1: afterMin"},
+		{EscalationPolicy{Name: "p", Steps: []EscalationStep{{Channels: []string{"pager"}}, {}}}, "step 2: no channels"},
+		{EscalationPolicy{Name: "p", Steps: []EscalationStep{{Channels: []string{"sms"}}}}, `step 1: unknown channel "sms"`},
+	} {
+		if _, err := s.CreateEscalationPolicy(c.p); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("err = %v, want %q", err, c.want)
+		}
+	}
+	if _, err := s.UpdateEscalationPolicy(42, EscalationPolicy{}); !errors.Is(err, ErrEscalationNotFound) {
+		t.Errorf("update unknown: err = %v", err)
+	}
+}
+
+func TestChannelInUseByEscalation(t *testing.T) {
+	s := NewStore()
+	c, err := s.CreateChannel(NotificationChannel{Name: "pager", Type: "webhook", Settings: map[string]string{"url": "http://127.0.0.1:1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateEscalationPolicy(EscalationPolicy{Name: "night", Steps: []EscalationStep{{Channels: []string{"pager"}}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteChannel(c.ID); !errors.Is(err, ErrChannelInUse) || !strings.Contains(err.Error(), `escalation policy "night"`) {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"time"
+)
+
+// HistoryQuery selects checks of one service in [From, To).
+// With Step > 0 the checks are aggregated into fixed buckets instead.
+type HistoryQuery struct {
+	ServiceID int
+	From      time.Time
+	To        time.Time
+	Status    string // "", "OK" or "FAIL"
+	Limit     int
+	Cursor    string // opaque; returned as NextCursor by the previous page
+	Step      time.Duration
+}
+
+// HistoryPoint is one aggregated bucket of checks.
+// Latency stats only cover OK checks.
+type HistoryPoint struct {
+	Bucket        time.Time `json:"bucket"`
+	Count         int       `json:"count"`
+	Failures      int       `json:"failures"`
+	UptimePercent float64   `json:"uptimePercent"`
+	AvgMs         int       `json:"avgMs"`
+	MinMs         int       `json:"minMs"`
+	MaxMs         int       `json:"maxMs"`
+
+	sumOK float64
+	okCnt int
+}
+
+const (
+	DefaultHistoryLimit = 1000
+	MaxHistoryLimit     = 10000
+	maxHistoryPoints    = 5000
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrTooManyPoints = errors.New("step too small for range")
+	ErrNotRetained   = errors.New("range is older than the checks kept at this step")
+)
+
+func encodeCursor(ts string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(ts))
+}
+
+func decodeCursor(c string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(c)
+	if err != nil {
+		return "", ErrInvalidCursor
+	}
+	if _, err := time.Parse(time.RFC3339, string(b)); err != nil {
+		return "", ErrInvalidCursor
+	}
+	return string(b), nil
+}
+
+func (q *HistoryQuery) normalize() {
+	if q.To.IsZero() {
+		q.To = time.Now().UTC()
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultHistoryLimit
+	}
+	if q.Limit > MaxHistoryLimit {
+		q.Limit = MaxHistoryLimit
+	}
+}
+
+// QueryHistory returns one page of raw checks, oldest first, plus the
+// cursor for the next page ("" when there is none).
+func (s *Store) QueryHistory(q HistoryQuery) ([]StatusResult, string, error) {
+	q.normalize()
+	after := ""
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		after = c
+	}
+
+	s.Lock()
+	db := s.db
+	s.Unlock()
+	if db != nil {
+		return db.queryChecks(q, after)
+	}
+
+	h, _ := s.GetHistory(q.ServiceID)
+	out := make([]StatusResult, 0, len(h))
+	for _, v := range h {
+		t, err := time.Parse(time.RFC3339, v.CheckedAt)
+		if err != nil || t.Before(q.From) || !t.Before(q.To) {
+			continue
+		}
+		if after != "" && v.CheckedAt <= after {
+			continue
+		}
+		if q.Status != "" && v.Status != q.Status {
+			continue
+		}
+		out = append(out, v)
+	}
+	return page(out, q.Limit)
+}
+
+func page(out []StatusResult, limit int) ([]StatusResult, string, error) {
+	if len(out) <= limit {
+		return out, "", nil
+	}
+	out = out[:limit]
+	return out, encodeCursor(out[len(out)-1].CheckedAt), nil
+}
+
+func (s *SQLStore) queryChecks(q HistoryQuery, after string) ([]StatusResult, string, error) {
+	args := []any{q.ServiceID, q.From.UTC().Format(time.RFC3339), q.To.UTC().Format(time.RFC3339)}
+	stmt := `SELECT service_id, ts, status, latency_ms FROM checks
+WHERE service_id = ? AND ts >= ? AND ts < ?`
+	if after != "" {
+		// ts is unique per service, so "ts > cursor" is a stable keyset
+		stmt += ` AND ts > ?`
+		args = append(args, after)
+	}
+	if q.Status != "" {
+		stmt += ` AND status = ?`
+		args = append(args, q.Status)
+	}
+	stmt += ` ORDER BY ts LIMIT ?`
+	args = append(args, q.Limit+1) // one extra row tells us there's a next page
+
+	rows, err := s.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	out := make([]StatusResult, 0, q.Limit+1)
+	for rows.Next() {
+		var r StatusResult
+		if err := rows.Scan(&r.ID, &r.CheckedAt, &r.Status, &r.ResponseMs); err != nil {
+			return nil, "", err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	return page(out, q.Limit)
+}
+
+// QueryHistoryPoints aggregates checks in [From, To) into Step-wide buckets.
+// Only buckets that contain checks are returned.
+func (s *Store) QueryHistoryPoints(q HistoryQuery) ([]HistoryPoint, error) {
+	q.normalize()
+	if q.Step < time.Second || q.To.Sub(q.From)/q.Step > maxHistoryPoints {
+		return nil, ErrTooManyPoints
+	}
+
+	s.Lock()
+	db := s.db
+	s.Unlock()
+
+	acc := make(map[int64]*HistoryPoint)
+	if db != nil {
+		if err := db.bucketChecks(q, acc); err != nil {
+			return nil, err
+		}
+	} else {
+		h, _ := s.GetHistory(q.ServiceID)
+		for _, v := range h {
+			t, err := time.Parse(time.RFC3339, v.CheckedAt)
+			if err != nil || t.Before(q.From) || !t.Before(q.To) {
+				continue
+			}
+			p := bucketFor(acc, t, q.Step)
+			p.Count++
+			if v.Status == "OK" {
+				p.addOK(float64(v.ResponseMs), 1, v.ResponseMs, v.ResponseMs)
+			} else {
+				p.Failures++
+			}
+		}
+	}
+
+	out := make([]HistoryPoint, 0, len(acc))
+	for _, p := range acc {
+		if p.Count > 0 {
+			p.UptimePercent = 100 * float64(p.Count-p.Failures) / float64(p.Count)
+		}
+		if p.okCnt > 0 {
+			p.AvgMs = int(p.sumOK / float64(p.okCnt))
+		}
+		out = append(out, *p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Bucket.Before(out[j].Bucket) })
+	return out, nil
+}
+
+// bucketFor aligns buckets to the Unix epoch, same as the SQL grouping.
+func bucketFor(acc map[int64]*HistoryPoint, t time.Time, step time.Duration) *HistoryPoint {
+	sec := int64(step / time.Second)
+	k := t.Unix() / sec * sec
+	p, ok := acc[k]
+	if !ok {
+		p = &HistoryPoint{Bucket: time.Unix(k, 0).UTC()}
+		acc[k] = p
+	}
+	return p
+}
+
+func (p *HistoryPoint) addOK(sum float64, n, minMs, maxMs int) {
+	if n <= 0 {
+		return
+	}
+	if p.okCnt == 0 || minMs < p.MinMs {
+		p.MinMs = minMs
+	}
+	if maxMs > p.MaxMs {
+		p.MaxMs = maxMs
+	}
+	p.sumOK += sum
+	p.okCnt += n
+}
+
+// historySource picks the tier to bucket from: raw checks while they
+// still cover the range, else the coarsest rollup tier whose buckets nest
+// inside Step, which is kept longest. A range that tier no longer covers
+// fails with ErrNotRetained rather than returning a partial answer.
+func (s *SQLStore) historySource(q HistoryQuery) (Resolution, error) {
+	s.mu.Lock()
+	p := s.retention
+	s.mu.Unlock()
+	now := time.Now().UTC()
+	if !q.From.Before(now.Add(-p.Keep(ResRaw))) {
+		return ResRaw, nil
+	}
+	for i := len(rollupTiers) - 1; i >= 0; i-- {
+		res := rollupTiers[i]
+		if q.Step%res.Step() != 0 {
+			continue
+		}
+		if q.From.Before(now.Add(-p.Keep(res))) {
+			return "", ErrNotRetained
+		}
+		return res, nil
+	}
+	return "", ErrNotRetained
+}
+
+func (s *SQLStore) bucketChecks(q HistoryQuery, acc map[int64]*HistoryPoint) error {
+	res, err := s.historySource(q)
+	if err != nil {
+		return err
+	}
+	rawFrom := q.From
+	if res != ResRaw {
+		s.mu.Lock()
+		until := s.rolledUntil[res]
+		s.mu.Unlock()
+		if until.After(q.From) {
+			if err := s.bucketRollups(q, res, minTime(until, q.To), acc); err != nil {
+				return err
+			}
+			rawFrom = until
+		}
+	}
+	if !rawFrom.Before(q.To) {
+		return nil
+	}
+
+	stepSec := int64(q.Step / time.Second)
+	rows, err := s.DB.Query(`SELECT (CAST(strftime('%s', ts) AS INTEGER) / ?) * ? AS b,
+ COUNT(*),
+ SUM(CASE WHEN status = 'OK' THEN 0 ELSE 1 END),
+ SUM(CASE WHEN status = 'OK' THEN latency_ms ELSE 0 END),
+ MIN(CASE WHEN status = 'OK' THEN latency_ms END),
+ MAX(CASE WHEN status = 'OK' THEN latency_ms END)
+FROM checks WHERE service_id = ? AND ts >= ? AND ts < ?
+GROUP BY b`,
+		stepSec, stepSec, q.ServiceID,
+		rawFrom.UTC().Format(time.RFC3339), q.To.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var b int64
+		var cnt, fails int
+		var sumOK sql.NullFloat64
+		var minMs, maxMs sql.NullInt64
+		if err := rows.Scan(&b, &cnt, &fails, &sumOK, &minMs, &maxMs); err != nil {
+			return err
+		}
+		p := bucketFor(acc, time.Unix(b, 0), q.Step)
+		p.Count += cnt
+		p.Failures += fails
+		p.addOK(sumOK.Float64, cnt-fails, int(minMs.Int64), int(maxMs.Int64))
+	}
+	return rows.Err()
+}
+
+func (s *SQLStore) bucketRollups(q HistoryQuery, res Resolution, until time.Time, acc map[int64]*HistoryPoint) error {
+	rows, err := s.DB.Query(`SELECT bucket, count, failures, min_ms, avg_ms, max_ms FROM rollups
+WHERE service_id = ? AND resolution = ? AND bucket >= ? AND bucket < ?`,
+		q.ServiceID, string(res),
+		q.From.UTC().Truncate(res.Step()).Format(time.RFC3339), until.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ts string
+		var cnt, fails, minMs, maxMs int
+		var avg float64
+		if err := rows.Scan(&ts, &cnt, &fails, &minMs, &avg, &maxMs); err != nil {
+			return err
+		}
+		t, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			continue
+		}
+		p := bucketFor(acc, t, q.Step)
+		p.Count += cnt
+		p.Failures += fails
+		p.addOK(avg*float64(cnt-fails), cnt-fails, minMs, maxMs)
+	}
+	return rows.Err()
+}
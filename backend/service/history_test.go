@@ -0,0 +1,180 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueryHistoryPagesWithCursor(t *testing.T) {
+	db := openTestSQL(t)
+	s := NewStore()
+	s.SetSQLStore(db)
+	from := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 7; i++ {
+		status := "OK"
+		if i%3 == 0 {
+			status = "FAIL"
+		}
+		insertCheck(t, db, 1, from.Add(time.Duration(i)*time.Minute), status, 10*i)
+	}
+	insertCheck(t, db, 2, from, "OK", 1)
+
+	var got []StatusResult
+	cursor := ""
+	pages := 0
+	for {
+		page, next, err := s.QueryHistory(HistoryQuery{ServiceID: 1, From: from, To: from.Add(time.Hour), Limit: 3, Cursor: cursor})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		got = append(got, page...)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if pages != 3 || len(got) != 7 {
+		t.Fatalf("got %d checks in %d pages, want 7 in 3", len(got), pages)
+	}
+	for i, r := range got {
+		if want := from.Add(time.Duration(i) * time.Minute).Format(time.RFC3339); r.CheckedAt != want || r.ID != 1 {
+			t.Errorf("check %d = %+v, want %s", i, r, want)
+		}
+	}
+
+	fails, next, _ := s.QueryHistory(HistoryQuery{ServiceID: 1, From: from, To: from.Add(time.Hour), Status: "FAIL"})
+	if len(fails) != 3 || next != "" {
+		t.Errorf("FAIL filter: %d checks, next %q", len(fails), next)
+	}
+	// To is exclusive
+	if page, _, _ := s.QueryHistory(HistoryQuery{ServiceID: 1, From: from, To: from.Add(2 * time.Minute)}); len(page) != 2 {
+		t.Errorf("[from, from+2m) = %d checks, want 2", len(page))
+	}
+}
+
+func TestQueryHistoryFromMemory(t *testing.T) {
+	s := NewStore()
+	from := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.services[1] = &Service{ID: 1}
+	for i := 0; i < 5; i++ {
+		s.histories[1] = append(s.histories[1], StatusResult{ID: 1, Status: "OK",
+			CheckedAt: from.Add(time.Duration(i) * time.Minute).Format(time.RFC3339)})
+	}
+	page, next, err := s.QueryHistory(HistoryQuery{ServiceID: 1, From: from.Add(time.Minute), To: from.Add(time.Hour), Limit: 2})
+	if err != nil || len(page) != 2 || next == "" {
+		t.Fatalf("first page = %d checks, next %q, %v", len(page), next, err)
+	}
+	page, next, _ = s.QueryHistory(HistoryQuery{ServiceID: 1, From: from.Add(time.Minute), To: from.Add(time.Hour), Limit: 2, Cursor: next})
+	if len(page) != 2 || page[0].CheckedAt != from.Add(3*time.Minute).Format(time.RFC3339) || next != "" {
+		t.Errorf("second page = %+v, next %q", page, next)
+	}
+}
+
+func TestQueryHistoryInvalidCursor(t *testing.T) {
+	s := NewStore()
+	for _, c := range []string{"!!", encodeCursor("yesterday")} {
+		if _, _, err := s.QueryHistory(HistoryQuery{ServiceID: 1, Cursor: c}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor %q: err = %v", c, err)
+		}
+	}
+}
+
+func TestQueryHistoryPoints(t *testing.T) {
+	db := openTestSQL(t)
+	s := NewStore()
+	s.SetSQLStore(db)
+	from := time.Now().UTC().Truncate(time.Hour).Add(-3 * time.Hour)
+	insertCheck(t, db, 1, from.Add(time.Minute), "OK", 100)
+	insertCheck(t, db, 1, from.Add(2*time.Minute), "OK", 300)
+	insertCheck(t, db, 1, from.Add(3*time.Minute), "FAIL", 0)
+	insertCheck(t, db, 1, from.Add(2*time.Hour), "FAIL", 0)
+
+	pts, err := s.QueryHistoryPoints(HistoryQuery{ServiceID: 1, From: from, To: from.Add(3 * time.Hour), Step: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != 2 {
+		t.Fatalf("got %d points, want 2 (empty buckets are left out)", len(pts))
+	}
+	p := pts[0]
+	if !p.Bucket.Equal(from) || p.Count != 3 || p.Failures != 1 || p.AvgMs != 200 || p.MinMs != 100 || p.MaxMs != 300 {
+		t.Errorf("first point = %+v", p)
+	}
+	if int(p.UptimePercent) != 66 {
+		t.Errorf("uptime = %v", p.UptimePercent)
+	}
+	if p := pts[1]; !p.Bucket.Equal(from.Add(2*time.Hour)) || p.UptimePercent != 0 || p.MinMs != 0 {
+		t.Errorf("second point = %+v", p)
+	}
+
+	if _, err := s.QueryHistoryPoints(HistoryQuery{ServiceID: 1, From: from, To: from.Add(3 * time.Hour), Step: time.Second}); !errors.Is(err, ErrTooManyPoints) {
+		t.Errorf("1s step over 3h: err = %v", err)
+	}
+}
+
+func TestQueryHistoryPointsFromRollups(t *testing.T) {
+	db := openTestSQL(t)
+	s := NewStore()
+	s.SetSQLStore(db)
+	now := time.Now().UTC().Truncate(2 * time.Hour) // so the checks share a 2h bucket
+	from := now.Add(-10 * 24 * time.Hour)
+	insertCheck(t, db, 1, from.Add(time.Minute), "OK", 100)
+	insertCheck(t, db, 1, from.Add(30*time.Minute), "FAIL", 0)
+	insertCheck(t, db, 1, from.Add(90*time.Minute), "OK", 50)
+	db.rollup(ResHour, now)
+	// gone from raw and the minute tier, only the hour tier still has them
+	db.DB.Exec(`DELETE FROM checks`)
+	db.retention = RetentionPolicy{RawDays: 2, MinuteDays: 2, HourDays: 365, DayDays: 1000}
+
+	q := HistoryQuery{ServiceID: 1, From: from, To: from.Add(24 * time.Hour), Step: 2 * time.Hour}
+	pts, err := s.QueryHistoryPoints(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != 1 || pts[0].Count != 3 || pts[0].Failures != 1 || pts[0].AvgMs != 75 {
+		t.Errorf("points = %+v", pts)
+	}
+}
+
+func TestQueryHistoryPointsNotRetained(t *testing.T) {
+	db := openTestSQL(t)
+	s := NewStore()
+	s.SetSQLStore(db)
+	from := time.Now().UTC().Add(-60 * 24 * time.Hour)
+	q := HistoryQuery{ServiceID: 1, From: from, To: from.Add(time.Hour), Step: 5 * time.Minute}
+	if _, err := s.QueryHistoryPoints(q); !errors.Is(err, ErrNotRetained) {
+		t.Errorf("5m step 60 days back: err = %v", err)
+	}
+}
+
+func TestHistorySource(t *testing.T) {
+	db := openTestSQL(t) // raw 7d, 1m 30d, 1h 365d
+	now := time.Now().UTC()
+	day := 24 * time.Hour
+	for _, c := range []struct {
+		ago  time.Duration
+		step time.Duration
+		want Resolution
+		err  error
+	}{
+		{day, time.Minute, ResRaw, nil},
+		{day, 90 * time.Second, ResRaw, nil},
+		{10 * day, time.Hour, ResHour, nil},
+		{10 * day, 5 * time.Minute, ResMinute, nil},
+		{60 * day, time.Hour, ResHour, nil},
+		{60 * day, day, ResDay, nil},
+		{400 * day, day, ResDay, nil},
+		// minute buckets are gone and hours don't nest in 5m
+		{60 * day, 5 * time.Minute, "", ErrNotRetained},
+		// raw checks are gone and no tier nests in 90s
+		{10 * day, 90 * time.Second, "", ErrNotRetained},
+		{400 * day, time.Hour, "", ErrNotRetained},
+	} {
+		q := HistoryQuery{From: now.Add(-c.ago), To: now, Step: c.step}
+		if got, err := db.historySource(q); got != c.want || err != c.err {
+			t.Errorf("%v ago, step %v: %q, %v, want %q, %v", c.ago, c.step, got, err, c.want, c.err)
+		}
+	}
+}
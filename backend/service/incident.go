@@ -0,0 +1,367 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"serverwatcher/notify"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrIncidentNotFound  = errors.New("incident not found")
+	ErrIncidentResolved  = errors.New("incident already resolved")
+	ErrIncidentNotManual = errors.New("incident was opened by checks and resolves when they recover")
+)
+
+// Incident severities, SEV1 being the worst.
+const (
+	SEV1 = "SEV1"
+	SEV2 = "SEV2"
+	SEV3 = "SEV3"
+	SEV4 = "SEV4"
+)
+
+var severities = []string{SEV1, SEV2, SEV3, SEV4}
+
+// ParseSeverity accepts "SEV1".."SEV4", case-insensitively.
+func ParseSeverity(v string) (string, bool) {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	return v, slices.Contains(severities, v)
+}
+
+// defaultSeverity is the severity of incidents opened by checks: the
+// service's own setting, else SEV1 for services with a 99.9%+ SLO and
+// SEV2 for the rest.
+func (svc *Service) defaultSeverity() string {
+	if svc.Severity != "" {
+		return svc.Severity
+	}
+	if svc.SLOTargetPercent >= 99.9 {
+		return SEV1
+	}
+	return SEV2
+}
+
+// SetServiceSeverity sets the default severity of a service's automatic
+// incidents; "" goes back to the derived default.
+func (s *Store) SetServiceSeverity(id int, sev string) error {
+	if sev != "" {
+		v, ok := ParseSeverity(sev)
+		if !ok {
+			return fmt.Errorf("invalid severity %q (SEV1..SEV4)", sev)
+		}
+		sev = v
+	}
+	s.Lock()
+	defer s.Unlock()
+	svc, ok := s.services[id]
+	if !ok {
+		return fmt.Errorf("service not found")
+	}
+	svc.Severity = sev
+	return s.saveLocked()
+}
+
+// notifySeverity maps an incident severity onto alert severity. Incidents
+// from before severities existed page as critical, as they used to.
+func notifySeverity(sev string) notify.Severity {
+	switch sev {
+	case SEV1, SEV2, "":
+		return notify.SeverityCritical
+	case SEV3:
+		return notify.SeverityWarning
+	}
+	return notify.SeverityInfo
+}
+
+// openIncidentsLocked returns every open, unacknowledged incident that
+// reminders and escalation act on: those opened by checks and declared
+// ones, oldest first. Caller must hold the lock.
+func (s *Store) openIncidentsLocked() []*Incident {
+	var out []*Incident
+	for _, inc := range s.openIncident {
+		if inc != nil {
+			out = append(out, inc)
+		}
+	}
+	for _, incs := range s.Incidents {
+		for _, inc := range incs {
+			if inc.Manual {
+				out = append(out, inc)
+			}
+		}
+	}
+	out = slices.DeleteFunc(out, func(inc *Incident) bool {
+		return inc.EndedAt != nil || inc.AcknowledgedAt != nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// subjectLocked is what an incident's alerts are about: its service, or
+// for a group incident a stand-in carrying the group tag.
+func (s *Store) subjectLocked(inc *Incident) *Service {
+	if inc.ServiceID == 0 && inc.Group != "" {
+		return &Service{Name: inc.Group, Tags: []string{inc.Group}}
+	}
+	return s.services[inc.ServiceID]
+}
+
+func (s *Store) incidentLocked(id int) *Incident {
+	for _, incs := range s.Incidents {
+		for _, inc := range incs {
+			if inc.ID == id {
+				return inc
+			}
+		}
+	}
+	return nil
+}
+
+// GetIncident returns a copy of the incident.
+func (s *Store) GetIncident(id int) (Incident, error) {
+	s.Lock()
+	defer s.Unlock()
+	inc := s.incidentLocked(id)
+	if inc == nil {
+		return Incident{}, ErrIncidentNotFound
+	}
+	return *inc, nil
+}
+
+// AcknowledgeIncident marks an open incident as being worked on: reminders
+// and escalation stop, and an ACK event goes out through the notifiers.
+// Acknowledging twice keeps the first acknowledgement.
+func (s *Store) AcknowledgeIncident(id int, by string) (Incident, error) {
+	s.Lock()
+	inc := s.incidentLocked(id)
+	if inc == nil {
+		s.Unlock()
+		return Incident{}, ErrIncidentNotFound
+	}
+	if inc.EndedAt != nil {
+		s.Unlock()
+		return Incident{}, ErrIncidentResolved
+	}
+	if inc.AcknowledgedAt != nil {
+		out := *inc
+		s.Unlock()
+		return out, nil
+	}
+	now := time.Now().UTC()
+	inc.AcknowledgedAt = &now
+	inc.AcknowledgedBy = strings.TrimSpace(by)
+
+	var e *notify.Event
+	if svc := s.subjectLocked(inc); svc != nil && (inc.Alerted || !s.isSilencedLocked(svc)) {
+		ev := s.newEventLocked(notify.EventAck, notify.SeverityInfo, svc, now)
+		ev.IncidentID = inc.ID
+		ev.AlertKey = incidentKey(inc.ID)
+		ev.StartedAt = inc.StartedAt
+		ev.Reason = inc.Reason
+		ev.Summary = "Acknowledged"
+		if inc.AcknowledgedBy != "" {
+			ev.Summary += " by " + inc.AcknowledgedBy
+		}
+		e = &ev
+	}
+	out := *inc
+	err := s.saveLocked()
+	s.Unlock()
+
+	if e != nil {
+		go s.broadcast(*e)
+	}
+	return out, err
+}
+
+// AssignIncident sets (or with "" clears) the incident owner.
+func (s *Store) AssignIncident(id int, assignee string) (Incident, error) {
+	s.Lock()
+	defer s.Unlock()
+	inc := s.incidentLocked(id)
+	if inc == nil {
+		return Incident{}, ErrIncidentNotFound
+	}
+	inc.Assignee = strings.TrimSpace(assignee)
+	return *inc, s.saveLocked()
+}
+
+// AddIncidentNote appends a timestamped note.
+func (s *Store) AddIncidentNote(id int, author, text string) (Incident, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Incident{}, fmt.Errorf("note text is required")
+	}
+	s.Lock()
+	defer s.Unlock()
+	inc := s.incidentLocked(id)
+	if inc == nil {
+		return Incident{}, ErrIncidentNotFound
+	}
+	inc.Notes = append(inc.Notes, IncidentNote{At: time.Now().UTC(), Author: strings.TrimSpace(author), Text: text})
+	return *inc, s.saveLocked()
+}
+
+// IncidentDeclaration opens an incident by hand, for one service or for
+// every service carrying the Group tag.
+type IncidentDeclaration struct {
+	ServiceID int    `json:"serviceId,omitempty"`
+	Group     string `json:"group,omitempty"`
+	Severity  string `json:"severity"`
+	Title     string `json:"title"`
+	Impact    string `json:"impact,omitempty"`
+	By        string `json:"by,omitempty"`
+}
+
+// DeclareIncident opens a manual incident and alerts like a DOWN would,
+// at the alert severity matching the incident severity.
+func (s *Store) DeclareIncident(d IncidentDeclaration) (Incident, error) {
+	d.Title = strings.TrimSpace(d.Title)
+	d.Group = strings.TrimSpace(d.Group)
+	if d.Title == "" {
+		return Incident{}, errors.New("title is required")
+	}
+	sev, ok := ParseSeverity(d.Severity)
+	if !ok {
+		return Incident{}, fmt.Errorf("invalid severity %q (SEV1..SEV4)", d.Severity)
+	}
+	if (d.ServiceID == 0) == (d.Group == "") {
+		return Incident{}, errors.New("set exactly one of serviceId and group")
+	}
+
+	s.Lock()
+	if d.ServiceID != 0 {
+		if _, ok := s.services[d.ServiceID]; !ok {
+			s.Unlock()
+			return Incident{}, fmt.Errorf("service %d not found", d.ServiceID)
+		}
+	} else {
+		found := false
+		for _, svc := range s.services {
+			if slices.Contains(svc.Tags, d.Group) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			s.Unlock()
+			return Incident{}, fmt.Errorf("no service is tagged %q", d.Group)
+		}
+	}
+	now := time.Now().UTC()
+	inc := &Incident{
+		ID:         s.nextIncidentID,
+		ServiceID:  d.ServiceID,
+		Group:      d.Group,
+		StartedAt:  now,
+		Reason:     d.Title,
+		Severity:   sev,
+		Manual:     true,
+		Title:      d.Title,
+		Impact:     strings.TrimSpace(d.Impact),
+		DeclaredBy: strings.TrimSpace(d.By),
+	}
+	s.nextIncidentID++
+	s.Incidents[inc.ServiceID] = append(s.Incidents[inc.ServiceID], inc)
+
+	var e *notify.Event
+	if svc := s.subjectLocked(inc); !s.isSilencedLocked(svc) {
+		inc.Alerted = true
+		ev := s.newEventLocked(notify.EventDown, notifySeverity(sev), svc, now)
+		ev.IncidentID = inc.ID
+		ev.AlertKey = incidentKey(inc.ID)
+		ev.Reason = inc.Reason
+		ev.Summary = sev + " declared"
+		if inc.DeclaredBy != "" {
+			ev.Summary += " by " + inc.DeclaredBy
+		}
+		if inc.Impact != "" {
+			ev.Summary += ". Impact: " + inc.Impact
+		}
+		e = &ev
+	}
+	out := *inc
+	err := s.saveLocked()
+	s.Unlock()
+
+	if e != nil {
+		go s.broadcast(*e)
+	}
+	return out, err
+}
+
+// ResolveIncident closes a manually declared incident; incidents opened by
+// checks close themselves on recovery.
+func (s *Store) ResolveIncident(id int, by string) (Incident, error) {
+	s.Lock()
+	inc := s.incidentLocked(id)
+	if inc == nil {
+		s.Unlock()
+		return Incident{}, ErrIncidentNotFound
+	}
+	if !inc.Manual {
+		s.Unlock()
+		return Incident{}, ErrIncidentNotManual
+	}
+	if inc.EndedAt != nil {
+		s.Unlock()
+		return Incident{}, ErrIncidentResolved
+	}
+	now := time.Now().UTC()
+	inc.EndedAt = &now
+	inc.DurationS = int(now.Sub(inc.StartedAt).Seconds())
+
+	var e *notify.Event
+	if svc := s.subjectLocked(inc); svc != nil && (inc.Alerted || !s.isSilencedLocked(svc)) {
+		ev := s.newEventLocked(notify.EventUp, notify.SeverityInfo, svc, now)
+		ev.IncidentID = inc.ID
+		ev.AlertKey = incidentKey(inc.ID)
+		ev.StartedAt = inc.StartedAt
+		ev.EndedAt = inc.EndedAt
+		ev.Reason = inc.Reason
+		if by = strings.TrimSpace(by); by != "" {
+			ev.Summary = "Resolved by " + by
+		}
+		e = &ev
+	}
+	out := *inc
+	err := s.saveLocked()
+	s.Unlock()
+
+	if e != nil {
+		go s.broadcast(*e)
+	}
+	return out, err
+}
+
+// OpenIncidents lists every open incident, automatic and declared, oldest
+// first; with severities set only those levels are kept.
+func (s *Store) OpenIncidents(severities []string) []Incident {
+	s.Lock()
+	defer s.Unlock()
+	out := []Incident{}
+	for _, incs := range s.Incidents {
+		for _, inc := range incs {
+			if inc.EndedAt != nil {
+				continue
+			}
+			c := *inc
+			if c.Severity == "" {
+				// opened before severities existed
+				if svc := s.services[c.ServiceID]; svc != nil {
+					c.Severity = svc.defaultSeverity()
+				}
+			}
+			if len(severities) > 0 && !slices.Contains(severities, c.Severity) {
+				continue
+			}
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.Before(out[j].StartedAt) })
+	return out
+}
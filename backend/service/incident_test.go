@@ -0,0 +1,168 @@
+package service
+
+import (
+	"errors"
+	"serverwatcher/notify"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSeverity(t *testing.T) {
+	for in, want := range map[string]string{"SEV1": SEV1, " sev3 ": SEV3, "Sev4": SEV4} {
+		if got, ok := ParseSeverity(in); !ok || got != want {
+			t.Errorf("ParseSeverity(%q) = %q, %v", in, got, ok)
+		}
+	}
+	for _, in := range []string{"", "SEV0", "SEV5", "critical"} {
+		if _, ok := ParseSeverity(in); ok {
+			t.Errorf("ParseSeverity(%q) accepted", in)
+		}
+	}
+}
+
+func TestIncidentSeverities(t *testing.T) {
+	for _, c := range []struct {
+		svc  Service
+		want string
+	}{
+		{Service{}, SEV2},
+		{Service{SLOTargetPercent: 99.9}, SEV1},
+		{Service{SLOTargetPercent: 99.5}, SEV2},
+		{Service{SLOTargetPercent: 99.99, Severity: SEV3}, SEV3},
+	} {
+		if got := c.svc.defaultSeverity(); got != c.want {
+			t.Errorf("%+v: default severity %s, want %s", c.svc, got, c.want)
+		}
+	}
+	for sev, want := range map[string]notify.Severity{
+		SEV1: notify.SeverityCritical, SEV2: notify.SeverityCritical, SEV3: notify.SeverityWarning, SEV4: notify.SeverityInfo,
+		"": notify.SeverityCritical, // incidents from before severities
+	} {
+		if got := notifySeverity(sev); got != want {
+			t.Errorf("%s pages as %s, want %s", sev, got, want)
+		}
+	}
+}
+
+func TestSetServiceSeverity(t *testing.T) {
+	s := NewStore()
+	svc := addTestService(s, "api", "https://api.example.com")
+	if err := s.SetServiceSeverity(svc.ID, "sev4"); err != nil || svc.Severity != SEV4 {
+		t.Errorf("severity %q, %v", svc.Severity, err)
+	}
+	if err := s.SetServiceSeverity(svc.ID, "SEV9"); err == nil || svc.Severity != SEV4 {
+		t.Errorf("invalid severity: %q, %v", svc.Severity, err)
+	}
+	if err := s.SetServiceSeverity(svc.ID, ""); err != nil || svc.Severity != "" {
+		t.Errorf("clearing: %q, %v", svc.Severity, err)
+	}
+	if err := s.SetServiceSeverity(99, SEV1); err == nil {
+		t.Error("unknown service accepted")
+	}
+}
+
+func TestDeclareIncident(t *testing.T) {
+	s := NewStore()
+	api := addTestService(s, "api", "https://api.example.com")
+	api.Tags = []string{"team:payments"}
+	for _, c := range []struct {
+		d    IncidentDeclaration
+		want string
+	}{
+		{IncidentDeclaration{ServiceID: api.ID, Severity: SEV2}, "title is required"},
+		{IncidentDeclaration{ServiceID: api.ID, Severity: "P1", Title: "x"}, `invalid severity "P1"`},
+		{IncidentDeclaration{Severity: SEV2, Title: "x"}, "exactly one"},
+		{IncidentDeclaration{ServiceID: api.ID, Group: "team:payments", Severity: SEV2, Title: "x"}, "exactly one"},
+		{IncidentDeclaration{ServiceID: 42, Severity: SEV2, Title: "x"}, "service 42 not found"},
+		{IncidentDeclaration{Group: "team:search", Severity: SEV2, Title: "x"}, `no service is tagged "team:search"`},
+	} {
+		if _, err := s.DeclareIncident(c.d); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("err = %v, want %q", err, c.want)
+		}
+	}
+
+	inc, err := s.DeclareIncident(IncidentDeclaration{Group: " team:payments ", Severity: "sev1", Title: " Card payments failing ",
+		Impact: "EU checkouts", By: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !inc.Manual || inc.Severity != SEV1 || inc.Group != "team:payments" || inc.ServiceID != 0 ||
+		inc.Title != "Card payments failing" || inc.DeclaredBy != "alice" || inc.EndedAt != nil {
+		t.Errorf("declared = %+v", inc)
+	}
+	if got, err := s.GetIncident(inc.ID); err != nil || got.Title != inc.Title {
+		t.Errorf("get: %+v, %v", got, err)
+	}
+}
+
+func TestResolveIncident(t *testing.T) {
+	s := NewStore()
+	svc := addTestService(s, "api", "https://api.example.com")
+	auto := &Incident{ID: 7, ServiceID: svc.ID, StartedAt: time.Now().Add(-time.Hour)}
+	s.Incidents[svc.ID] = []*Incident{auto}
+	s.nextIncidentID = 8
+
+	inc, err := s.DeclareIncident(IncidentDeclaration{ServiceID: svc.ID, Severity: SEV3, Title: "slow logins"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.ResolveIncident(inc.ID, "bob")
+	if err != nil || got.EndedAt == nil {
+		t.Fatalf("resolve: %+v, %v", got, err)
+	}
+	for _, c := range []struct {
+		id   int
+		want error
+	}{
+		{inc.ID, ErrIncidentResolved},
+		{auto.ID, ErrIncidentNotManual},
+		{99, ErrIncidentNotFound},
+	} {
+		if _, err := s.ResolveIncident(c.id, "bob"); !errors.Is(err, c.want) {
+			t.Errorf("resolve %d: err = %v, want %v", c.id, err, c.want)
+		}
+	}
+}
+
+func TestOpenIncidents(t *testing.T) {
+	s := NewStore()
+	gold := addTestService(s, "gold", "https://gold.example.com")
+	gold.SLOTargetPercent = 99.95
+	plain := addTestService(s, "plain", "https://plain.example.com")
+	now := time.Now().UTC()
+	ended := now.Add(-time.Minute)
+	s.Incidents[gold.ID] = []*Incident{
+		{ID: 1, ServiceID: gold.ID, StartedAt: now.Add(-3 * time.Hour), EndedAt: &ended, Severity: SEV1},
+		{ID: 2, ServiceID: gold.ID, StartedAt: now.Add(-time.Hour)}, // from before severities: SEV1 by SLO
+	}
+	s.Incidents[plain.ID] = []*Incident{{ID: 3, ServiceID: plain.ID, StartedAt: now.Add(-2 * time.Hour), Severity: SEV3}}
+	s.Incidents[0] = []*Incident{{ID: 4, Group: "team:x", StartedAt: now, Severity: SEV2, Manual: true}}
+
+	ids := func(incs []Incident) (out []int) {
+		for _, inc := range incs {
+			out = append(out, inc.ID)
+		}
+		return out
+	}
+	if got := ids(s.OpenIncidents(nil)); len(got) != 3 || got[0] != 3 || got[1] != 2 || got[2] != 4 {
+		t.Errorf("open = %v, want oldest first [3 2 4]", got)
+	}
+	if got := s.OpenIncidents([]string{SEV1}); len(got) != 1 || got[0].ID != 2 || got[0].Severity != SEV1 {
+		t.Errorf("SEV1 = %+v", got)
+	}
+	if got := ids(s.OpenIncidents([]string{SEV2, SEV3})); len(got) != 2 || got[0] != 3 || got[1] != 4 {
+		t.Errorf("SEV2,SEV3 = %v", got)
+	}
+}
+
+func TestDeclaredIncidentsDontCountAsDowntime(t *testing.T) {
+	end := t0.Add(30 * time.Minute)
+	incs := []*Incident{
+		{StartedAt: t0, EndedAt: &end, Manual: true},
+		{StartedAt: t0.Add(40 * time.Minute), EndedAt: nil},
+	}
+	if got := incidentSpans(incs, sp(0, 60)); len(got) != 1 || got[0] != sp(40, 60) {
+		t.Errorf("spans = %v", got)
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"log"
+	"os"
+	"serverwatcher/notify"
+	"testing"
+)
+
+// TestMain runs the tests in a scratch directory, since saveLocked writes
+// persistenceFile to the working directory.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "serverwatcher-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		log.Fatal(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// addTestService registers an inactive service, so no checker is started.
+func addTestService(s *Store, name, url string) *Service {
+	s.Lock()
+	defer s.Unlock()
+	s.ensureMaps()
+	if s.nextID <= 0 {
+		s.nextID = 1
+	}
+	svc := &Service{ID: s.nextID, Name: name, URL: url}
+	s.services[svc.ID] = svc
+	s.nextID++
+	return svc
+}
+
+// queuedEvents returns the events waiting in the delivery queue, oldest
+// first. s needs a SQL store and at least one channel.
+func queuedEvents(t *testing.T, s *Store) []notify.Event {
+	t.Helper()
+	ds, err := s.PendingDeliveries(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []notify.Event
+	for _, d := range ds {
+		out = append(out, d.Event)
+	}
+	return out
+}
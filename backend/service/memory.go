@@ -0,0 +1,28 @@
+package service
+
+// / --- IN-MEMORY READS --- /
+
+// GetStatuses returns the latest status of every service.
+func (s *Store) GetStatuses() []StatusResult {
+	s.Lock()
+	defer s.Unlock()
+	out := make([]StatusResult, 0, len(s.statuses))
+	for _, st := range s.statuses {
+		out = append(out, st)
+	}
+	return out
+}
+
+// GetHistory returns a copy of the recent in-memory samples for a service.
+// The bool is false if the service is unknown.
+func (s *Store) GetHistory(id int) ([]StatusResult, bool) {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.services[id]; !ok {
+		return nil, false
+	}
+	h := s.histories[id]
+	out := make([]StatusResult, len(h))
+	copy(out, h)
+	return out, true
+}
@@ -20,8 +20,66 @@ CREATE TABLE IF NOT EXISTS incidents(
 CREATE TABLE IF NOT EXISTS silences(
  id INTEGER PRIMARY KEY, service_id INTEGER, tag TEXT, until TEXT, reason TEXT, created_at TEXT
 );
+CREATE TABLE IF NOT EXISTS rollups(
+ service_id INTEGER, resolution TEXT, bucket TEXT,
+ count INTEGER, failures INTEGER,
+ min_ms INTEGER, avg_ms REAL, max_ms INTEGER, p95_ms INTEGER,
+ PRIMARY KEY(service_id, resolution, bucket)
+);
+CREATE TABLE IF NOT EXISTS outbox(
+ id INTEGER PRIMARY KEY AUTOINCREMENT,
+ channel TEXT, event TEXT, attempts INTEGER,
+ next_attempt_at TEXT, last_error TEXT, created_at TEXT
+);
+CREATE TABLE IF NOT EXISTS dead_letters(
+ id INTEGER PRIMARY KEY AUTOINCREMENT,
+ channel TEXT, event TEXT, attempts INTEGER,
+ last_error TEXT, created_at TEXT, dead_at TEXT
+);
+CREATE TABLE IF NOT EXISTS delivery_log(
+ id INTEGER PRIMARY KEY AUTOINCREMENT,
+ delivery_id INTEGER, channel TEXT, attempt INTEGER,
+ event_type TEXT, alert_key TEXT, title TEXT,
+ service_id INTEGER, incident_id INTEGER,
+ status TEXT, error TEXT, latency_ms INTEGER, ts TEXT
+);
 CREATE INDEX IF NOT EXISTS idx_checks_service_ts ON checks(service_id, ts);
+CREATE INDEX IF NOT EXISTS idx_checks_ts ON checks(ts);
 CREATE INDEX IF NOT EXISTS idx_incidents_service_start ON incidents(service_id, started_at);
+CREATE INDEX IF NOT EXISTS idx_rollups_resolution_bucket ON rollups(resolution, bucket);
+CREATE INDEX IF NOT EXISTS idx_outbox_next ON outbox(next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_delivery_log_incident ON delivery_log(incident_id, id);
+CREATE INDEX IF NOT EXISTS idx_delivery_log_service ON delivery_log(service_id, id);
+CREATE INDEX IF NOT EXISTS idx_delivery_log_ts ON delivery_log(ts);
 `)
+	if err != nil {
+		return err
+	}
+	return s.addColumnIfMissing("rollups", "sketch", "TEXT")
+}
+
+// addColumnIfMissing upgrades tables created by older versions.
+func (s *SQLStore) addColumnIfMissing(table, column, decl string) error {
+	rows, err := s.DB.Query(`PRAGMA table_info(` + table + `)`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt any
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+	_, err = s.DB.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + decl)
 	return err
 }
@@ -0,0 +1,357 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrScheduleNotFound = errors.New("schedule not found")
+	ErrScheduleInUse    = errors.New("schedule is in use")
+)
+
+// OnCallPrefix marks a routing or escalation target that pages whoever is
+// on call, e.g. "oncall:backend", instead of a fixed channel.
+const OnCallPrefix = "oncall:"
+
+// Rotation lengths.
+const (
+	RotationDaily  = "daily"
+	RotationWeekly = "weekly"
+)
+
+// Schedule rotates Users through shifts of one day or one week. The first
+// shift starts on StartDate at Handoff local time; each handoff passes on
+// to the next user in the list. Overrides replace the rotation for a
+// time range, the most recently added one winning.
+type Schedule struct {
+	ID        int                 `json:"id"`
+	Name      string              `json:"name"`
+	Team      string              `json:"team,omitempty"` // defaults to Name
+	Timezone  string              `json:"timezone,omitempty"`
+	Rotation  string              `json:"rotation"`  // "daily" or "weekly"
+	StartDate string              `json:"startDate"` // "2026-01-05"; for weekly rotations also the handoff weekday
+	Handoff   string              `json:"handoff"`   // "09:00"
+	Users     []string            `json:"users"`
+	Contacts  map[string][]string `json:"contacts"` // user -> channel names paged for them; required for every user
+	Overrides []ScheduleOverride  `json:"overrides,omitempty"`
+}
+
+type ScheduleOverride struct {
+	User  string    `json:"user"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// OnCall is who covers one schedule at a point in time.
+type OnCall struct {
+	ScheduleID int       `json:"scheduleId"`
+	Schedule   string    `json:"schedule"`
+	Team       string    `json:"team"`
+	User       string    `json:"user"`
+	Channels   []string  `json:"channels"`
+	Override   bool      `json:"override,omitempty"`
+	Until      time.Time `json:"until"` // end of the current shift or override
+}
+
+func (sc *Schedule) team() string {
+	if sc.Team != "" {
+		return sc.Team
+	}
+	return sc.Name
+}
+
+func (sc *Schedule) location() *time.Location {
+	loc, err := time.LoadLocation(sc.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// shift returns the index into Users on duty at t and when that shift ends.
+// Shifts are counted in calendar days so DST changes don't move handoffs.
+func (sc *Schedule) shift(t time.Time) (int, time.Time) {
+	loc := sc.location()
+	start, _ := time.ParseInLocation("2006-01-02", sc.StartDate, loc)
+	handoff, _ := parseClock(sc.Handoff)
+	length := 1
+	if sc.Rotation == RotationWeekly {
+		length = 7
+	}
+
+	lt := t.In(loc)
+	day := time.Date(lt.Year(), lt.Month(), lt.Day(), 0, 0, 0, 0, time.UTC)
+	if lt.Hour()*60+lt.Minute() < handoff {
+		day = day.AddDate(0, 0, -1)
+	}
+	first := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(day.Sub(first).Hours() / 24)
+	n := days / length
+	if days < 0 && days%length != 0 {
+		n--
+	}
+	idx := n % len(sc.Users)
+	if idx < 0 {
+		idx += len(sc.Users)
+	}
+
+	endDay := first.AddDate(0, 0, (n+1)*length)
+	end := time.Date(endDay.Year(), endDay.Month(), endDay.Day(), handoff/60, handoff%60, 0, 0, loc)
+	return idx, end
+}
+
+func (sc *Schedule) onCall(t time.Time) OnCall {
+	oc := OnCall{ScheduleID: sc.ID, Schedule: sc.Name, Team: sc.team()}
+	for i := len(sc.Overrides) - 1; i >= 0; i-- {
+		o := sc.Overrides[i]
+		if !t.Before(o.Start) && t.Before(o.End) {
+			oc.User, oc.Override, oc.Until = o.User, true, o.End
+			break
+		}
+	}
+	if !oc.Override {
+		var idx int
+		idx, oc.Until = sc.shift(t)
+		oc.User = sc.Users[idx]
+	}
+	oc.Channels = append([]string{}, sc.Contacts[oc.User]...)
+	return oc
+}
+
+// onCallLocked lists who is on call at t for every schedule of a team (or
+// the schedule of that name).
+func (s *Store) onCallLocked(team string, t time.Time) []OnCall {
+	out := []OnCall{}
+	for _, sc := range s.schedules {
+		if team == "" || sc.team() == team || sc.Name == team {
+			out = append(out, sc.onCall(t))
+		}
+	}
+	return out
+}
+
+// OnCallAt answers "who is on call for team X at time T"; an empty team
+// lists every schedule.
+func (s *Store) OnCallAt(team string, t time.Time) []OnCall {
+	s.Lock()
+	defer s.Unlock()
+	return s.onCallLocked(team, t)
+}
+
+// targetChannelsLocked expands a routing/escalation target: a channel name
+// stays as is, "oncall:<team>" becomes the channels of whoever is on call.
+func (s *Store) targetChannelsLocked(target string, t time.Time) []string {
+	team, ok := strings.CutPrefix(target, OnCallPrefix)
+	if !ok {
+		return []string{target}
+	}
+	var out []string
+	for _, oc := range s.onCallLocked(team, t) {
+		out = append(out, oc.Channels...)
+	}
+	return out
+}
+
+// validateTargetLocked checks a channel name or on-call target.
+func (s *Store) validateTargetLocked(target string) error {
+	if team, ok := strings.CutPrefix(target, OnCallPrefix); ok {
+		for _, sc := range s.schedules {
+			if sc.team() == team || sc.Name == team {
+				return nil
+			}
+		}
+		return fmt.Errorf("no schedule for team %q", team)
+	}
+	if _, ok := s.channels[target]; !ok {
+		return fmt.Errorf("unknown channel %q", target)
+	}
+	return nil
+}
+
+// / --- CRUD --- /
+
+func (s *Store) ListSchedules() []Schedule {
+	s.Lock()
+	defer s.Unlock()
+	out := make([]Schedule, 0, len(s.schedules))
+	for _, sc := range s.schedules {
+		out = append(out, *sc)
+	}
+	return out
+}
+
+func (s *Store) GetSchedule(id int) (Schedule, error) {
+	s.Lock()
+	defer s.Unlock()
+	for _, sc := range s.schedules {
+		if sc.ID == id {
+			return *sc, nil
+		}
+	}
+	return Schedule{}, ErrScheduleNotFound
+}
+
+func (s *Store) CreateSchedule(sc Schedule) (Schedule, error) {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.validateScheduleLocked(&sc); err != nil {
+		return Schedule{}, err
+	}
+	s.nextScheduleID++
+	sc.ID = s.nextScheduleID
+	s.schedules = append(s.schedules, &sc)
+	return sc, s.saveLocked()
+}
+
+// UpdateSchedule replaces a schedule. Renaming its team is refused while
+// routes or escalation steps still target the old "oncall:<team>".
+func (s *Store) UpdateSchedule(id int, sc Schedule) (Schedule, error) {
+	s.Lock()
+	defer s.Unlock()
+	for _, cur := range s.schedules {
+		if cur.ID != id {
+			continue
+		}
+		if err := s.validateScheduleLocked(&sc); err != nil {
+			return Schedule{}, err
+		}
+		sc.ID = id
+		old := *cur
+		*cur = sc
+		if err := s.checkOnCallRefsLocked(); err != nil {
+			*cur = old
+			return Schedule{}, err
+		}
+		return sc, s.saveLocked()
+	}
+	return Schedule{}, ErrScheduleNotFound
+}
+
+// DeleteSchedule removes a schedule unless routes or escalation steps
+// still target its team and no other schedule covers it.
+func (s *Store) DeleteSchedule(id int) error {
+	s.Lock()
+	defer s.Unlock()
+	for i, sc := range s.schedules {
+		if sc.ID != id {
+			continue
+		}
+		old := s.schedules
+		s.schedules = slices.Delete(slices.Clone(old), i, i+1)
+		if err := s.checkOnCallRefsLocked(); err != nil {
+			s.schedules = old
+			return err
+		}
+		return s.saveLocked()
+	}
+	return ErrScheduleNotFound
+}
+
+// checkOnCallRefsLocked reports the first "oncall:" target of a route or
+// escalation step that no schedule serves.
+func (s *Store) checkOnCallRefsLocked() error {
+	for _, r := range s.routes {
+		for _, c := range r.Channels {
+			if strings.HasPrefix(c, OnCallPrefix) {
+				if err := s.validateTargetLocked(c); err != nil {
+					return fmt.Errorf("%w: routing rule %q targets %s", ErrScheduleInUse, r.Name, c)
+				}
+			}
+		}
+	}
+	for _, p := range s.escalations {
+		for _, st := range p.Steps {
+			for _, c := range st.Channels {
+				if strings.HasPrefix(c, OnCallPrefix) {
+					if err := s.validateTargetLocked(c); err != nil {
+						return fmt.Errorf("%w: escalation policy %q targets %s", ErrScheduleInUse, p.Name, c)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// AddScheduleOverride hands a time range to another user.
+func (s *Store) AddScheduleOverride(id int, o ScheduleOverride) (Schedule, error) {
+	s.Lock()
+	defer s.Unlock()
+	for _, sc := range s.schedules {
+		if sc.ID != id {
+			continue
+		}
+		o.User = strings.TrimSpace(o.User)
+		if o.User == "" {
+			return Schedule{}, errors.New("user is required")
+		}
+		if len(sc.Contacts[o.User]) == 0 {
+			return Schedule{}, fmt.Errorf("%s has no contacts in this schedule", o.User)
+		}
+		if !o.End.After(o.Start) {
+			return Schedule{}, errors.New("end must be after start")
+		}
+		// expired overrides are dropped on the way
+		now := time.Now()
+		sc.Overrides = slices.DeleteFunc(sc.Overrides, func(x ScheduleOverride) bool { return x.End.Before(now) })
+		sc.Overrides = append(sc.Overrides, o)
+		return *sc, s.saveLocked()
+	}
+	return Schedule{}, ErrScheduleNotFound
+}
+
+func (s *Store) validateScheduleLocked(sc *Schedule) error {
+	sc.Name = strings.TrimSpace(sc.Name)
+	sc.Team = strings.TrimSpace(sc.Team)
+	if sc.Name == "" {
+		return errors.New("name is required")
+	}
+	if _, err := time.LoadLocation(sc.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q", sc.Timezone)
+	}
+	if sc.Rotation == "" {
+		sc.Rotation = RotationWeekly
+	}
+	if sc.Rotation != RotationDaily && sc.Rotation != RotationWeekly {
+		return fmt.Errorf("invalid rotation %q (daily, weekly)", sc.Rotation)
+	}
+	if _, err := time.Parse("2006-01-02", sc.StartDate); err != nil {
+		return fmt.Errorf("invalid startDate %q (YYYY-MM-DD)", sc.StartDate)
+	}
+	if sc.Handoff == "" {
+		sc.Handoff = "09:00"
+	}
+	if _, err := parseClock(sc.Handoff); err != nil {
+		return err
+	}
+	if len(sc.Users) == 0 {
+		return errors.New("at least one user is required")
+	}
+	for user, chans := range sc.Contacts {
+		for _, c := range chans {
+			if _, ok := s.channels[c]; !ok {
+				return fmt.Errorf("%s: unknown channel %q", user, c)
+			}
+		}
+	}
+	// whoever can be on call must be pageable, or routes and escalation
+	// steps targeting the schedule would silently page nobody
+	for _, u := range sc.Users {
+		if len(sc.Contacts[u]) == 0 {
+			return fmt.Errorf("%s has no contacts", u)
+		}
+	}
+	for _, o := range sc.Overrides {
+		if o.User == "" || !o.End.After(o.Start) {
+			return errors.New("override needs a user and end after start")
+		}
+		if len(sc.Contacts[o.User]) == 0 {
+			return fmt.Errorf("override user %s has no contacts", o.User)
+		}
+	}
+	return nil
+}
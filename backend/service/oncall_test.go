@@ -0,0 +1,257 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"serverwatcher/notify"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleShift(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	// 2026-03-02 is a Monday
+	weekly := &Schedule{Rotation: RotationWeekly, StartDate: "2026-03-02", Handoff: "09:00", Timezone: "Europe/Berlin",
+		Users: []string{"alice", "bob", "carol"}}
+	daily := &Schedule{Rotation: RotationDaily, StartDate: "2026-03-02", Handoff: "18:30", Users: []string{"alice", "bob"}}
+	at := func(loc *time.Location, mon, day, h, m int) time.Time {
+		return time.Date(2026, time.Month(mon), day, h, m, 0, 0, loc)
+	}
+	for _, c := range []struct {
+		name  string
+		sc    *Schedule
+		t     time.Time
+		user  string
+		until time.Time
+	}{
+		{"weekly: first shift", weekly, at(berlin, 3, 2, 9, 0), "alice", at(berlin, 3, 9, 9, 0)},
+		{"weekly: still alice before the handoff", weekly, at(berlin, 3, 9, 8, 59), "alice", at(berlin, 3, 9, 9, 0)},
+		{"weekly: second shift", weekly, at(berlin, 3, 9, 9, 0), "bob", at(berlin, 3, 16, 9, 0)},
+		{"weekly: wraps around", weekly, at(berlin, 3, 25, 12, 0), "alice", at(berlin, 3, 30, 9, 0)},
+		// summer time starts 2026-03-29: the handoff stays at 09:00 local
+		{"weekly: across summer time", weekly, at(berlin, 3, 30, 9, 0), "bob", at(berlin, 4, 6, 9, 0)},
+		{"weekly: before the start date", weekly, at(berlin, 2, 25, 12, 0), "carol", at(berlin, 3, 2, 9, 0)},
+		{"daily: evening handoff", daily, at(time.UTC, 3, 2, 18, 30), "alice", at(time.UTC, 3, 3, 18, 30)},
+		{"daily: the next morning", daily, at(time.UTC, 3, 3, 8, 0), "alice", at(time.UTC, 3, 3, 18, 30)},
+		{"daily: the next evening", daily, at(time.UTC, 3, 3, 20, 0), "bob", at(time.UTC, 3, 4, 18, 30)},
+		{"daily: before the first handoff", daily, at(time.UTC, 3, 2, 12, 0), "bob", at(time.UTC, 3, 2, 18, 30)},
+	} {
+		oc := c.sc.onCall(c.t.UTC())
+		if oc.User != c.user || !oc.Until.Equal(c.until) || oc.Override {
+			t.Errorf("%s: %s until %v, want %s until %v", c.name, oc.User, oc.Until, c.user, c.until)
+		}
+	}
+}
+
+func TestScheduleOverrides(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	sc := &Schedule{Name: "backend", Rotation: RotationWeekly, StartDate: "2026-03-02", Handoff: "09:00",
+		Users:    []string{"alice", "bob"},
+		Contacts: map[string][]string{"alice": {"alice-sms"}, "dave": {"dave-phone", "dave-chat"}},
+		Overrides: []ScheduleOverride{
+			{User: "carol", Start: start.Add(24 * time.Hour), End: start.Add(72 * time.Hour)},
+			// added later, so it wins where they overlap
+			{User: "dave", Start: start.Add(48 * time.Hour), End: start.Add(50 * time.Hour)},
+		},
+	}
+	for _, c := range []struct {
+		at       time.Duration
+		user     string
+		override bool
+		until    time.Duration
+	}{
+		{12 * time.Hour, "alice", false, 7 * 24 * time.Hour},
+		{24 * time.Hour, "carol", true, 72 * time.Hour},
+		{49 * time.Hour, "dave", true, 50 * time.Hour},
+		{50 * time.Hour, "carol", true, 72 * time.Hour},
+		{72 * time.Hour, "alice", false, 7 * 24 * time.Hour},
+	} {
+		oc := sc.onCall(start.Add(c.at))
+		if oc.User != c.user || oc.Override != c.override || !oc.Until.Equal(start.Add(c.until)) {
+			t.Errorf("+%v: %+v, want %s until +%v", c.at, oc, c.user, c.until)
+		}
+	}
+	if oc := sc.onCall(start.Add(49 * time.Hour)); !reflect.DeepEqual(oc.Channels, []string{"dave-phone", "dave-chat"}) {
+		t.Errorf("dave's channels = %v", oc.Channels)
+	}
+	if oc := sc.onCall(start.Add(30 * time.Hour)); oc.Channels == nil || len(oc.Channels) != 0 {
+		t.Errorf("carol has no contacts, got %#v", oc.Channels)
+	}
+}
+
+// onCallStore has one schedule for team "backend" rotating alice and bob
+// daily from 2026-03-02 09:00 UTC, each with their own channel.
+func onCallStore(t *testing.T) *Store {
+	t.Helper()
+	s := NewStore()
+	var chs []notify.Channel
+	for _, name := range []string{"alice-sms", "bob-sms", "ops"} {
+		chs = append(chs, notify.Channel{Name: name, Notifier: notify.Webhook{URL: "http://127.0.0.1:1"}})
+	}
+	s.SetNotifiers(chs)
+	_, err := s.CreateSchedule(Schedule{
+		Name: "backend primary", Team: "backend", Rotation: RotationDaily, StartDate: "2026-03-02",
+		Users:    []string{"alice", "bob"},
+		Contacts: map[string][]string{"alice": {"alice-sms"}, "bob": {"bob-sms"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestOnCallTargets(t *testing.T) {
+	s := onCallStore(t)
+	day2 := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	if got := s.OnCallAt("backend", day2); len(got) != 1 || got[0].User != "bob" || got[0].Schedule != "backend primary" {
+		t.Errorf("on call = %+v", got)
+	}
+	if got := s.OnCallAt("frontend", day2); len(got) != 0 {
+		t.Errorf("frontend on call = %+v", got)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	for _, c := range []struct {
+		targets []string
+		want    []string
+	}{
+		{[]string{"oncall:backend"}, []string{"bob-sms"}},
+		{[]string{"oncall:backend primary", "ops", "bob-sms"}, []string{"bob-sms", "ops"}},
+		{[]string{"oncall:frontend", "gone"}, nil},
+	} {
+		if got := s.resolveTargetsLocked(c.targets, day2); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v -> %v, want %v", c.targets, got, c.want)
+		}
+	}
+	if err := s.validateTargetLocked("oncall:frontend"); err == nil {
+		t.Error("oncall target without a schedule accepted")
+	}
+	if err := s.validateTargetLocked("oncall:backend"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEscalationPagesOnCall(t *testing.T) {
+	start := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	s, inc := escalationStore(t, start)
+	s.SetNotifiers([]notify.Channel{
+		{Name: "alice-sms", Notifier: notify.Webhook{URL: "http://127.0.0.1:1"}},
+		{Name: "bob-sms", Notifier: notify.Webhook{URL: "http://127.0.0.1:1"}},
+	})
+	_, err := s.CreateSchedule(Schedule{Name: "backend", Rotation: RotationDaily, StartDate: "2026-03-02",
+		Users: []string{"alice", "bob"}, Contacts: map[string][]string{"alice": {"alice-sms"}, "bob": {"bob-sms"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.CreateEscalationPolicy(EscalationPolicy{Name: "p", ServiceIDs: []int{inc.ServiceID},
+		Steps: []EscalationStep{{AfterMin: 0, Channels: []string{"oncall:backend"}}, {AfterMin: 24 * 60, Channels: []string{"oncall:backend"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.escalate(start)
+	s.escalate(start.Add(24 * time.Hour)) // bob took over at 09:00
+	if got := strings.Join(queuedChannels(t, s), ","); got != "alice-sms,bob-sms" {
+		t.Errorf("paged %s", got)
+	}
+}
+
+func TestScheduleValidates(t *testing.T) {
+	s := onCallStore(t)
+	base := Schedule{Name: "s", StartDate: "2026-03-02", Users: []string{"alice"},
+		Contacts: map[string][]string{"alice": {"alice-sms"}, "bob": {"bob-sms"}}}
+	for _, c := range []struct {
+		edit func(sc *Schedule)
+		want string
+	}{
+		{func(sc *Schedule) { sc.Name = " " }, "name is required"},
+		{func(sc *Schedule) { sc.Rotation = "monthly" }, `invalid rotation "monthly"`},
+		{func(sc *Schedule) { sc.StartDate = "03/02/2026" }, "invalid startDate"},
+		{func(sc *Schedule) { sc.Timezone = "Nowhere/Town" }, "invalid timezone"},
+		{func(sc *Schedule) { sc.Handoff = "9" }, `invalid time "9"`},
+		{func(sc *Schedule) { sc.Users = nil }, "at least one user"},
+		{func(sc *Schedule) { sc.Contacts = map[string][]string{"alice": {"sms"}} }, `alice: unknown channel "sms"`},
+		{func(sc *Schedule) { sc.Users = []string{"alice", "carol"} }, "carol has no contacts"},
+		{func(sc *Schedule) {
+			sc.Overrides = []ScheduleOverride{{User: "carol", Start: time.Now(), End: time.Now().Add(time.Hour)}}
+		}, "override user carol has no contacts"},
+	} {
+		sc := base
+		c.edit(&sc)
+		if _, err := s.CreateSchedule(sc); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("err = %v, want %q", err, c.want)
+		}
+	}
+	sc, err := s.CreateSchedule(base)
+	if err != nil || sc.Rotation != RotationWeekly || sc.Handoff != "09:00" {
+		t.Errorf("defaults: %+v, %v", sc, err)
+	}
+
+	now := time.Now()
+	if _, err := s.AddScheduleOverride(sc.ID, ScheduleOverride{User: "bob", Start: now, End: now}); err == nil {
+		t.Error("empty override accepted")
+	}
+	old := ScheduleOverride{User: "bob", Start: now.Add(-48 * time.Hour), End: now.Add(-24 * time.Hour)}
+	if _, err := s.AddScheduleOverride(sc.ID, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddScheduleOverride(sc.ID, ScheduleOverride{User: "carol", Start: now, End: now.Add(time.Hour)}); err == nil {
+		t.Error("override for a user without contacts accepted")
+	}
+	got, err := s.AddScheduleOverride(sc.ID, ScheduleOverride{User: " bob ", Start: now, End: now.Add(time.Hour)})
+	if err != nil || len(got.Overrides) != 1 || got.Overrides[0].User != "bob" {
+		t.Errorf("expired override kept: %+v, %v", got.Overrides, err)
+	}
+	if _, err := s.AddScheduleOverride(99, old); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("unknown schedule: err = %v", err)
+	}
+}
+
+func TestChannelInUseBySchedule(t *testing.T) {
+	s := NewStore()
+	c, err := s.CreateChannel(NotificationChannel{Name: "alice-sms", Type: "webhook", Settings: map[string]string{"url": "http://127.0.0.1:1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.CreateSchedule(Schedule{Name: "backend", StartDate: "2026-03-02", Users: []string{"alice"},
+		Contacts: map[string][]string{"alice": {"alice-sms"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteChannel(c.ID); !errors.Is(err, ErrChannelInUse) || !strings.Contains(err.Error(), `schedule "backend"`) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestScheduleInUse(t *testing.T) {
+	s := onCallStore(t)
+	if err := s.SetRoutes([]RouteRule{{Name: "backend pages", Channels: []string{"oncall:backend"}}}); err != nil {
+		t.Fatal(err)
+	}
+	sc := s.ListSchedules()[0]
+	renamed := sc
+	renamed.Team = "platform"
+	if _, err := s.UpdateSchedule(sc.ID, renamed); !errors.Is(err, ErrScheduleInUse) || !strings.Contains(err.Error(), `"backend pages"`) {
+		t.Errorf("rename: err = %v", err)
+	}
+	if err := s.DeleteSchedule(sc.ID); !errors.Is(err, ErrScheduleInUse) {
+		t.Errorf("delete: err = %v", err)
+	}
+	if got, _ := s.GetSchedule(sc.ID); got.Team != "backend" {
+		t.Errorf("refused update was kept: team %q", got.Team)
+	}
+
+	// a second schedule for the team takes over the target
+	backup := sc
+	backup.Name = "backend secondary"
+	if _, err := s.CreateSchedule(backup); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteSchedule(sc.ID); err != nil {
+		t.Errorf("delete with a second schedule: %v", err)
+	}
+}
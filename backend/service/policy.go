@@ -10,6 +10,17 @@ type IncidentPolicy struct {
 	OpenSeconds          int `json:"openSeconds"`
 	CloseConsecutiveOKs  int `json:"closeConsecutiveOKs"`
 	AlertCooldownSec     int `json:"alertCooldownSec"`
+
+	// reminders for incidents that stay open; 0 interval disables them
+	ReminderIntervalSec int `json:"reminderIntervalSec"`
+	MaxReminders        int `json:"maxReminders"` // 0 = no cap
+
+	// grouping window for DOWN/UP bursts on chat and webhook channels;
+	// PagerDuty, Opsgenie and email always get per-incident alerts. 0
+	// disables grouping
+	GroupWindowSec int    `json:"groupWindowSec"`
+	GroupByTag     string `json:"groupByTag"`     // tag key to group by, e.g. "env" for env:prod; "" groups all
+	GroupMinEvents int    `json:"groupMinEvents"` // smaller batches go out one by one (default 3)
 }
 
 func defaultPolicy() IncidentPolicy {
@@ -0,0 +1,370 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"serverwatcher/notify"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrIncidentOpen       = errors.New("incident is still open")
+	ErrPostmortemNotFound = errors.New("incident has no postmortem")
+	ErrPostmortemExists   = errors.New("incident already has a postmortem")
+)
+
+// Postmortem statuses.
+const (
+	PostmortemDraft  = "draft"
+	PostmortemReview = "review"
+	PostmortemFinal  = "final"
+)
+
+// how much check history around an incident goes into a draft
+const postmortemMargin = 15 * time.Minute
+
+// Postmortem is the write-up of a resolved incident. Drafts come
+// pre-filled from the incident, its checks and its alert deliveries.
+type Postmortem struct {
+	Status      string          `json:"status"`
+	Summary     string          `json:"summary"`
+	Impact      string          `json:"impact,omitempty"`
+	RootCause   string          `json:"rootCause,omitempty"`
+	ActionItems []ActionItem    `json:"actionItems,omitempty"`
+	Timeline    []TimelineEntry `json:"timeline,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+}
+
+type ActionItem struct {
+	Text  string `json:"text"`
+	Owner string `json:"owner,omitempty"`
+	Due   string `json:"due,omitempty"` // "2026-11-01"
+	Done  bool   `json:"done,omitempty"`
+}
+
+type TimelineEntry struct {
+	At   time.Time `json:"at"`
+	Text string    `json:"text"`
+}
+
+// GetPostmortem returns the incident's postmortem.
+func (s *Store) GetPostmortem(id int) (Postmortem, error) {
+	s.Lock()
+	defer s.Unlock()
+	inc := s.incidentLocked(id)
+	if inc == nil {
+		return Postmortem{}, ErrIncidentNotFound
+	}
+	if inc.Postmortem == nil {
+		return Postmortem{}, ErrPostmortemNotFound
+	}
+	return *inc.Postmortem, nil
+}
+
+// CreatePostmortem attaches a generated draft to a resolved incident.
+func (s *Store) CreatePostmortem(id int) (Postmortem, error) {
+	pm, err := s.DraftPostmortem(id)
+	if err != nil {
+		return Postmortem{}, err
+	}
+	s.Lock()
+	defer s.Unlock()
+	inc := s.incidentLocked(id)
+	if inc == nil {
+		return Postmortem{}, ErrIncidentNotFound
+	}
+	if inc.Postmortem != nil {
+		return Postmortem{}, ErrPostmortemExists
+	}
+	inc.Postmortem = &pm
+	return pm, s.saveLocked()
+}
+
+// UpdatePostmortem replaces the editable fields.
+func (s *Store) UpdatePostmortem(id int, pm Postmortem) (Postmortem, error) {
+	if pm.Status == "" {
+		pm.Status = PostmortemDraft
+	}
+	if !slices.Contains([]string{PostmortemDraft, PostmortemReview, PostmortemFinal}, pm.Status) {
+		return Postmortem{}, fmt.Errorf("invalid status %q (draft, review, final)", pm.Status)
+	}
+	for i := range pm.ActionItems {
+		a := &pm.ActionItems[i]
+		a.Text = strings.TrimSpace(a.Text)
+		a.Owner = strings.TrimSpace(a.Owner)
+		if a.Text == "" {
+			return Postmortem{}, fmt.Errorf("action item %d: text is required", i+1)
+		}
+		if a.Due != "" {
+			if _, err := time.Parse("2006-01-02", a.Due); err != nil {
+				return Postmortem{}, fmt.Errorf("action item %d: invalid due %q (YYYY-MM-DD)", i+1, a.Due)
+			}
+		}
+	}
+	sort.SliceStable(pm.Timeline, func(i, j int) bool { return pm.Timeline[i].At.Before(pm.Timeline[j].At) })
+
+	s.Lock()
+	defer s.Unlock()
+	inc := s.incidentLocked(id)
+	if inc == nil {
+		return Postmortem{}, ErrIncidentNotFound
+	}
+	if inc.Postmortem == nil {
+		return Postmortem{}, ErrPostmortemNotFound
+	}
+	pm.CreatedAt = inc.Postmortem.CreatedAt
+	pm.UpdatedAt = time.Now().UTC()
+	inc.Postmortem = &pm
+	return pm, s.saveLocked()
+}
+
+// DraftPostmortem builds, without saving, a postmortem for a resolved
+// incident from its timeline, the check history around it and the
+// delivery log of its alerts.
+func (s *Store) DraftPostmortem(id int) (Postmortem, error) {
+	s.Lock()
+	p := s.incidentLocked(id)
+	if p == nil {
+		s.Unlock()
+		return Postmortem{}, ErrIncidentNotFound
+	}
+	if p.EndedAt == nil {
+		s.Unlock()
+		return Postmortem{}, ErrIncidentOpen
+	}
+	inc := *p
+	subject := s.subjectLocked(&inc)
+	var scope []Service // services whose checks are relevant
+	for _, svc := range s.services {
+		if svc.ID == inc.ServiceID || (inc.ServiceID == 0 && slices.Contains(svc.Tags, inc.Group)) {
+			scope = append(scope, *svc)
+		}
+	}
+	s.Unlock()
+	sort.Slice(scope, func(i, j int) bool { return scope[i].ID < scope[j].ID })
+
+	name := fmt.Sprintf("service %d", inc.ServiceID)
+	if subject != nil {
+		name = subject.Name
+	}
+	now := time.Now().UTC()
+	pm := Postmortem{
+		Status:    PostmortemDraft,
+		Impact:    inc.Impact,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Timeline:  incidentTimeline(inc),
+	}
+
+	var checkLines []string
+	for _, svc := range scope {
+		tl, line := s.checkTimeline(svc, inc)
+		pm.Timeline = append(pm.Timeline, tl...)
+		if line != "" {
+			checkLines = append(checkLines, line)
+		}
+	}
+	if entries, err := s.DeliveryLog(DeliveryLogQuery{IncidentID: inc.ID, Limit: 500}); err == nil {
+		pm.Timeline = append(pm.Timeline, deliveryTimeline(entries)...)
+	}
+	sort.SliceStable(pm.Timeline, func(i, j int) bool { return pm.Timeline[i].At.Before(pm.Timeline[j].At) })
+
+	what := "was down"
+	if inc.Title != "" {
+		what = "had an incident: " + inc.Title
+	}
+	pm.Summary = fmt.Sprintf("%s %s for %s", name, what, notify.HumanDuration(inc.EndedAt.Sub(inc.StartedAt)))
+	if inc.Severity != "" {
+		pm.Summary += " (" + inc.Severity + ")"
+	}
+	pm.Summary += ", from " + inc.StartedAt.Format("2006-01-02 15:04") + " to " + inc.EndedAt.Format("15:04 MST") + "."
+	for _, l := range checkLines {
+		pm.Summary += " " + l
+	}
+	return pm, nil
+}
+
+// incidentTimeline lists what the incident record itself knows.
+func incidentTimeline(inc Incident) []TimelineEntry {
+	var out []TimelineEntry
+	add := func(at time.Time, format string, args ...any) {
+		out = append(out, TimelineEntry{At: at, Text: fmt.Sprintf(format, args...)})
+	}
+	switch {
+	case inc.Manual && inc.DeclaredBy != "":
+		add(inc.StartedAt, "Incident declared by %s: %s", inc.DeclaredBy, inc.Title)
+	case inc.Manual:
+		add(inc.StartedAt, "Incident declared: %s", inc.Title)
+	default:
+		add(inc.StartedAt, "Incident opened: %s", inc.Reason)
+	}
+	if inc.LastEscalatedAt != nil {
+		add(*inc.LastEscalatedAt, "Escalation step %d paged", inc.Escalations)
+	}
+	if inc.LastReminderAt != nil {
+		add(*inc.LastReminderAt, "Reminder %d sent", inc.Reminders)
+	}
+	if inc.AcknowledgedAt != nil {
+		if inc.AcknowledgedBy != "" {
+			add(*inc.AcknowledgedAt, "Acknowledged by %s", inc.AcknowledgedBy)
+		} else {
+			add(*inc.AcknowledgedAt, "Acknowledged")
+		}
+	}
+	for _, n := range inc.Notes {
+		if n.Author != "" {
+			add(n.At, "Note from %s: %s", n.Author, n.Text)
+		} else {
+			add(n.At, "Note: %s", n.Text)
+		}
+	}
+	add(*inc.EndedAt, "Resolved after %s", notify.HumanDuration(inc.EndedAt.Sub(inc.StartedAt)))
+	return out
+}
+
+// checkTimeline turns the checks around the incident into OK/FAIL
+// transitions plus a one-line summary for the draft.
+func (s *Store) checkTimeline(svc Service, inc Incident) ([]TimelineEntry, string) {
+	from := inc.StartedAt.Add(-postmortemMargin)
+	to := minTime(inc.EndedAt.Add(postmortemMargin), time.Now().UTC())
+
+	var out []TimelineEntry
+	var total, fails, slowest int
+	var reasons []string
+	prev := ""
+	q := HistoryQuery{ServiceID: svc.ID, From: from, To: to, Limit: MaxHistoryLimit}
+	for {
+		checks, next, err := s.QueryHistory(q)
+		if err != nil {
+			return nil, ""
+		}
+		for _, c := range checks {
+			at, _ := time.Parse(time.RFC3339, c.CheckedAt)
+			total++
+			if c.Status == "FAIL" {
+				fails++
+				if c.Reason != "" && !slices.Contains(reasons, c.Reason) {
+					reasons = append(reasons, c.Reason)
+				}
+			}
+			slowest = max(slowest, c.ResponseMs)
+			if c.Status != prev && prev != "" {
+				if c.Status == "FAIL" {
+					out = append(out, TimelineEntry{At: at, Text: fmt.Sprintf("%s check failed: %s", svc.Name, c.Reason)})
+				} else {
+					out = append(out, TimelineEntry{At: at, Text: fmt.Sprintf("%s check passed again", svc.Name)})
+				}
+			}
+			prev = c.Status
+		}
+		if next == "" {
+			break
+		}
+		q.Cursor = next
+	}
+	if total == 0 {
+		return nil, ""
+	}
+	line := fmt.Sprintf("%s: %d of %d checks failed between %s and %s, slowest response %dms",
+		svc.Name, fails, total, from.Format("15:04"), to.Format("15:04"), slowest)
+	if len(reasons) > 0 {
+		line += "; failure reasons: " + strings.Join(reasons, ", ")
+	}
+	return out, line + "."
+}
+
+// deliveryTimeline keeps final outcomes: successful sends and dead
+// letters, not the retries in between.
+func deliveryTimeline(entries []DeliveryLogEntry) []TimelineEntry {
+	var out []TimelineEntry
+	for _, e := range entries {
+		switch e.Status {
+		case DeliverySent:
+			out = append(out, TimelineEntry{At: e.At, Text: fmt.Sprintf("%s alert sent to %s", e.EventType, e.Channel)})
+		case DeliveryDead:
+			out = append(out, TimelineEntry{At: e.At, Text: fmt.Sprintf("%s alert to %s failed after %d attempts: %s", e.EventType, e.Channel, e.Attempt, e.Error)})
+		}
+	}
+	return out
+}
+
+// Markdown renders the postmortem as a standalone document.
+func (pm Postmortem) Markdown(inc Incident, name string) string {
+	var b strings.Builder
+	title := name
+	if inc.Title != "" {
+		title = inc.Title
+	}
+	fmt.Fprintf(&b, "# Postmortem: %s\n\n", title)
+	fmt.Fprintf(&b, "- **Incident:** #%d (%s)\n", inc.ID, name)
+	if inc.Severity != "" {
+		fmt.Fprintf(&b, "- **Severity:** %s\n", inc.Severity)
+	}
+	fmt.Fprintf(&b, "- **Status:** %s\n", pm.Status)
+	fmt.Fprintf(&b, "- **Started:** %s\n", inc.StartedAt.Format(time.RFC3339))
+	if inc.EndedAt != nil {
+		fmt.Fprintf(&b, "- **Resolved:** %s (%s)\n", inc.EndedAt.Format(time.RFC3339), notify.HumanDuration(inc.EndedAt.Sub(inc.StartedAt)))
+	}
+	if inc.Assignee != "" {
+		fmt.Fprintf(&b, "- **Owner:** %s\n", inc.Assignee)
+	}
+
+	section := func(h, text string) {
+		if text = strings.TrimSpace(text); text == "" {
+			text = "_TBD_"
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n%s\n", h, text)
+	}
+	section("Summary", pm.Summary)
+	section("Impact", pm.Impact)
+	section("Root cause", pm.RootCause)
+
+	b.WriteString("\n## Timeline\n\n")
+	if len(pm.Timeline) == 0 {
+		b.WriteString("_TBD_\n")
+	}
+	for _, t := range pm.Timeline {
+		fmt.Fprintf(&b, "- %s: %s\n", t.At.UTC().Format("2006-01-02 15:04:05Z"), t.Text)
+	}
+
+	b.WriteString("\n## Action items\n\n")
+	if len(pm.ActionItems) == 0 {
+		b.WriteString("_None yet_\n")
+	} else {
+		b.WriteString("| | Action | Owner | Due |\n|---|---|---|---|\n")
+		for _, a := range pm.ActionItems {
+			box := "[ ]"
+			if a.Done {
+				box = "[x]"
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", box, mdCell(a.Text), mdCell(a.Owner), a.Due)
+		}
+	}
+	return b.String()
+}
+
+// ExportPostmortem renders the incident's postmortem as Markdown.
+func (s *Store) ExportPostmortem(id int) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+	inc := s.incidentLocked(id)
+	if inc == nil {
+		return "", ErrIncidentNotFound
+	}
+	if inc.Postmortem == nil {
+		return "", ErrPostmortemNotFound
+	}
+	name := fmt.Sprintf("service %d", inc.ServiceID)
+	if svc := s.subjectLocked(inc); svc != nil {
+		name = svc.Name
+	}
+	return inc.Postmortem.Markdown(*inc, name), nil
+}
+
+func mdCell(v string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(v)
+}
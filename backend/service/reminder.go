@@ -0,0 +1,75 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"serverwatcher/notify"
+	"time"
+)
+
+// StartReminders re-notifies open incidents every ReminderIntervalSec of
+// the policy, up to MaxReminders times.
+func (s *Store) StartReminders() {
+	go func() {
+		t := time.NewTicker(30 * time.Second)
+		defer t.Stop()
+		for now := range t.C {
+			s.sendReminders(now.UTC())
+		}
+	}()
+}
+
+func (s *Store) sendReminders(now time.Time) {
+	s.Lock()
+	p := s.policy
+	if p.ReminderIntervalSec <= 0 {
+		s.Unlock()
+		return
+	}
+	interval := time.Duration(p.ReminderIntervalSec) * time.Second
+	var events []notify.Event
+	for _, inc := range s.openIncidentsLocked() {
+		svc := s.subjectLocked(inc)
+		if svc == nil {
+			continue
+		}
+		if p.MaxReminders > 0 && inc.Reminders >= p.MaxReminders {
+			continue
+		}
+		last := inc.StartedAt
+		if inc.LastReminderAt != nil {
+			last = *inc.LastReminderAt
+		}
+		if now.Sub(last) < interval {
+			continue
+		}
+		// silenced or in cooldown: skip this round, try again next tick
+		if s.isSilencedLocked(svc) || !s.canNotify(inc.ServiceID, now) {
+			continue
+		}
+		inc.Reminders++
+		inc.LastReminderAt = &now
+		inc.Alerted = true
+
+		e := s.newEventLocked(notify.EventDown, notifySeverity(inc.Severity), svc, now)
+		e.IncidentID = inc.ID
+		e.AlertKey = incidentKey(inc.ID)
+		e.StartedAt = inc.StartedAt
+		e.Reason = inc.Reason
+		e.Reminder = inc.Reminders
+		e.Summary = fmt.Sprintf("Still down for %s (reminder %d)", notify.HumanDuration(now.Sub(inc.StartedAt)), inc.Reminders)
+		events = append(events, e)
+	}
+	var err error
+	if len(events) > 0 {
+		err = s.saveLocked()
+	}
+	s.Unlock()
+
+	if err != nil {
+		log.Println("reminders: save:", err)
+	}
+	for _, e := range events {
+		s.broadcast(e)
+	}
+}
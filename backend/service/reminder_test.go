@@ -0,0 +1,83 @@
+package service
+
+import (
+	"serverwatcher/notify"
+	"testing"
+	"time"
+)
+
+func TestSendReminders(t *testing.T) {
+	s := NewStore()
+	s.SetSQLStore(openTestSQL(t))
+	s.SetNotifiers([]notify.Channel{{Name: "ops", Notifier: notify.Webhook{URL: "http://127.0.0.1:1"}}})
+	s.policy.ReminderIntervalSec = 3600
+	s.policy.MaxReminders = 2
+	s.policy.AlertCooldownSec = 0
+	svc := addTestService(s, "api", "https://api.example.com")
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	inc := &Incident{ID: 4, ServiceID: svc.ID, StartedAt: start, Reason: "timeout"}
+	s.Incidents[svc.ID] = []*Incident{inc}
+	s.openIncident[svc.ID] = inc
+
+	for _, c := range []struct {
+		at   time.Duration // since the incident started
+		sent int           // reminders sent so far
+	}{
+		{59 * time.Minute, 0},
+		{time.Hour, 1},
+		// the interval counts from the last reminder, not from the start
+		{time.Hour + 30*time.Minute, 1},
+		{2*time.Hour + 10*time.Minute, 2},
+		{10 * time.Hour, 2}, // capped
+	} {
+		s.sendReminders(start.Add(c.at))
+		if inc.Reminders != c.sent {
+			t.Errorf("after %v: %d reminders, want %d", c.at, inc.Reminders, c.sent)
+		}
+	}
+	if want := start.Add(2*time.Hour + 10*time.Minute); inc.LastReminderAt == nil || !inc.LastReminderAt.Equal(want) {
+		t.Errorf("last reminder at %v, want %v", inc.LastReminderAt, want)
+	}
+
+	events := queuedEvents(t, s)
+	if len(events) != 2 {
+		t.Fatalf("queued %d events, want 2", len(events))
+	}
+	e := events[1]
+	if e.Type != notify.EventDown || e.Reminder != 2 || e.AlertKey != "incident-4" || e.Reason != "timeout" ||
+		!e.StartedAt.Equal(start) {
+		t.Errorf("reminder = %+v", e)
+	}
+	if got := e.Title(); got != "[DOWN] api (still down after 2h10m0s)" {
+		t.Errorf("title = %q", got)
+	}
+}
+
+func TestSendRemindersSkips(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		name  string
+		setup func(s *Store, svc *Service)
+	}{
+		{"disabled", func(s *Store, _ *Service) { s.policy.ReminderIntervalSec = 0 }},
+		{"silenced", func(s *Store, svc *Service) {
+			// silences are checked against the wall clock
+			s.silences = []*Silence{{ID: 1, ServiceID: &svc.ID, CreatedAt: start, Until: time.Now().Add(time.Hour)}}
+		}},
+		{"cooldown", func(s *Store, svc *Service) {
+			s.policy.AlertCooldownSec = 3600
+			s.lastAlertAt[svc.ID] = start.Add(90 * time.Minute)
+		}},
+	} {
+		s := NewStore()
+		s.policy.ReminderIntervalSec = 3600
+		svc := addTestService(s, "api", "https://api.example.com")
+		inc := &Incident{ID: 1, ServiceID: svc.ID, StartedAt: start}
+		s.openIncident[svc.ID] = inc
+		c.setup(s, svc)
+		s.sendReminders(start.Add(2 * time.Hour))
+		if inc.Reminders != 0 {
+			t.Errorf("%s: reminder sent", c.name)
+		}
+	}
+}
@@ -1,15 +1,140 @@
 package service
 
-import "time"
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
 
-func (s *SQLStore) StartRetention(days int) {
+// RetentionPolicy says how long each history tier, and the notification
+// delivery log, is kept, in days.
+type RetentionPolicy struct {
+	RawDays         int `json:"rawDays"`
+	MinuteDays      int `json:"minuteDays"`
+	HourDays        int `json:"hourDays"`
+	DayDays         int `json:"dayDays"`
+	DeliveryLogDays int `json:"deliveryLogDays"`
+}
+
+func DefaultRetention() RetentionPolicy {
+	return RetentionPolicy{
+		RawDays:    7,
+		MinuteDays: 30,
+		HourDays:   365,
+		DayDays:    5 * 365,
+
+		DeliveryLogDays: 30,
+	}
+}
+
+// RetentionFromEnv reads SERVERWATCHER_RETENTION_{RAW,MINUTE,HOUR,DAY}_DAYS
+// and SERVERWATCHER_RETENTION_DELIVERY_LOG_DAYS, falling back to DefaultRetention for anything unset or invalid.
+func RetentionFromEnv() RetentionPolicy {
+	p := DefaultRetention()
+	read := func(key string, dst *int) {
+		if v := os.Getenv(key); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				*dst = n
+			}
+		}
+	}
+	read("SERVERWATCHER_RETENTION_RAW_DAYS", &p.RawDays)
+	read("SERVERWATCHER_RETENTION_MINUTE_DAYS", &p.MinuteDays)
+	read("SERVERWATCHER_RETENTION_HOUR_DAYS", &p.HourDays)
+	read("SERVERWATCHER_RETENTION_DAY_DAYS", &p.DayDays)
+	read("SERVERWATCHER_RETENTION_DELIVERY_LOG_DAYS", &p.DeliveryLogDays)
+	return p.normalize()
+}
+
+// normalize keeps raw checks for at least 2 days, since every tier
+// (including 1d) is rolled up from raw samples.
+func (p RetentionPolicy) normalize() RetentionPolicy {
+	d := DefaultRetention()
+	if p.RawDays < 2 {
+		p.RawDays = 2
+	}
+	if p.MinuteDays <= 0 {
+		p.MinuteDays = d.MinuteDays
+	}
+	if p.HourDays <= 0 {
+		p.HourDays = d.HourDays
+	}
+	if p.DayDays <= 0 {
+		p.DayDays = d.DayDays
+	}
+	if p.DeliveryLogDays <= 0 {
+		p.DeliveryLogDays = d.DeliveryLogDays
+	}
+	return p
+}
+
+// Keep returns how long a tier is retained.
+func (p RetentionPolicy) Keep(res Resolution) time.Duration {
+	days := p.RawDays
+	switch res {
+	case ResMinute:
+		days = p.MinuteDays
+	case ResHour:
+		days = p.HourDays
+	case ResDay:
+		days = p.DayDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// pruneEvery is how often each tier is pruned; fine tiers grow fastest.
+func pruneEvery(res Resolution) time.Duration {
+	switch res {
+	case ResRaw, ResMinute:
+		return time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+// StartRetention prunes every history tier on its own schedule, and the
+// delivery log hourly.
+func (s *SQLStore) StartRetention(p RetentionPolicy) {
+	p = p.normalize()
+	s.mu.Lock()
+	s.retention = p
+	s.mu.Unlock()
+
+	for _, res := range []Resolution{ResRaw, ResMinute, ResHour, ResDay} {
+		go func(res Resolution) {
+			t := time.NewTicker(pruneEvery(res))
+			defer t.Stop()
+			for range t.C {
+				if err := s.prune(res, p.Keep(res)); err != nil {
+					log.Printf("retention: prune %s: %v", res, err)
+				}
+			}
+		}(res)
+	}
 	go func() {
-		t := time.NewTicker(24 * time.Hour)
+		t := time.NewTicker(time.Hour)
 		defer t.Stop()
 		for range t.C {
-			cut := time.Now().Add(-time.Duration(days) * 24 * time.Hour).Format(time.RFC3339)
-			_, _ = s.DB.Exec(`DELETE FROM checks WHERE ts < ?`, cut)
-			// optionally compress incidents older than N months into rollups
+			if err := s.pruneDeliveryLog(time.Duration(p.DeliveryLogDays) * 24 * time.Hour); err != nil {
+				log.Printf("retention: prune delivery log: %v", err)
+			}
 		}
 	}()
 }
+
+func (s *SQLStore) pruneDeliveryLog(keep time.Duration) error {
+	cut := time.Now().UTC().Add(-keep).Format(queueTimeLayout)
+	_, err := s.DB.Exec(`DELETE FROM delivery_log WHERE ts < ?`, cut)
+	return err
+}
+
+func (s *SQLStore) prune(res Resolution, keep time.Duration) error {
+	cut := time.Now().UTC().Add(-keep).Format(time.RFC3339)
+	if res == ResRaw {
+		_, err := s.DB.Exec(`DELETE FROM checks WHERE ts < ?`, cut)
+		return err
+	}
+	_, err := s.DB.Exec(`DELETE FROM rollups WHERE resolution = ? AND bucket < ?`, string(res), cut)
+	return err
+}
@@ -0,0 +1,396 @@
+package service
+
+import (
+	"database/sql"
+	"log"
+	"sort"
+	"time"
+)
+
+// Resolution identifies a history tier: raw checks or a rollup bucket size.
+type Resolution string
+
+const (
+	ResRaw    Resolution = "raw"
+	ResMinute Resolution = "1m"
+	ResHour   Resolution = "1h"
+	ResDay    Resolution = "1d"
+)
+
+var rollupTiers = []Resolution{ResMinute, ResHour, ResDay}
+
+// Step is the bucket width of a tier (zero for raw).
+func (r Resolution) Step() time.Duration {
+	switch r {
+	case ResMinute:
+		return time.Minute
+	case ResHour:
+		return time.Hour
+	case ResDay:
+		return 24 * time.Hour
+	}
+	return 0
+}
+
+// maxWindow is the widest window a tier is used for before switching
+// to a coarser one, so a query never scans more than ~10k rows.
+func (r Resolution) maxWindow() time.Duration {
+	switch r {
+	case ResRaw:
+		return 24 * time.Hour
+	case ResMinute:
+		return 7 * 24 * time.Hour
+	case ResHour:
+		return 90 * 24 * time.Hour
+	}
+	return 0 // unbounded
+}
+
+// Rollup is the aggregate of one bucket of checks.
+// Latency stats only cover OK checks, like Analytics.AvgResponseMs.
+type Rollup struct {
+	ServiceID  int        `json:"serviceId"`
+	Resolution Resolution `json:"resolution"`
+	Bucket     time.Time  `json:"bucket"`
+	Count      int        `json:"count"`
+	Failures   int        `json:"failures"`
+	MinMs      int        `json:"minMs"`
+	AvgMs      float64    `json:"avgMs"`
+	MaxMs      int        `json:"maxMs"`
+	P95Ms      int        `json:"p95Ms"`
+
+	Sketch *LatencySketch `json:"sketch,omitempty"` // OK latencies, for percentiles over many buckets
+}
+
+// CheckStats aggregates checks over a window, whatever tier they came from.
+type CheckStats struct {
+	Checks     int
+	Failures   int
+	SumOKMs    float64
+	Latency    *LatencySketch // OK latencies
+	Resolution Resolution
+}
+
+func newCheckStats(res Resolution) CheckStats {
+	return CheckStats{Resolution: res, Latency: NewLatencySketch()}
+}
+
+func (c CheckStats) AvgOKMs() int {
+	ok := c.Checks - c.Failures
+	if ok <= 0 {
+		return 0
+	}
+	return int(c.SumOKMs / float64(ok))
+}
+
+// StartRollups aggregates completed buckets of raw checks into every
+// rollup tier, once a minute.
+func (s *SQLStore) StartRollups() {
+	go func() {
+		s.rollupAll(time.Now().UTC())
+		t := time.NewTicker(time.Minute)
+		defer t.Stop()
+		for now := range t.C {
+			s.rollupAll(now.UTC())
+		}
+	}()
+}
+
+func (s *SQLStore) rollupAll(now time.Time) {
+	for _, res := range rollupTiers {
+		if err := s.rollup(res, now); err != nil {
+			log.Printf("rollup %s: %v", res, err)
+		}
+	}
+}
+
+// rollup (re)computes every completed bucket of a tier since the last run,
+// and the buckets late checks were inserted into since. Buckets are built
+// from raw checks so p95 stays exact; upserts make it safe to recompute a
+// bucket.
+func (s *SQLStore) rollup(res Resolution, now time.Time) error {
+	step := res.Step()
+	end := now.Truncate(step)
+
+	start, err := s.rollupStart(res)
+	if err != nil || start.IsZero() {
+		return err
+	}
+	s.mu.Lock()
+	late, hasLate := s.lateFrom[res]
+	delete(s.lateFrom, res)
+	s.mu.Unlock()
+	if hasLate && late.Before(start) {
+		start = late.Truncate(step)
+	}
+	if !start.Before(end) {
+		return nil
+	}
+	// a late check noted after this point re-marks the tier; on failure
+	// the taken mark is put back
+	done := false
+	defer func() {
+		if hasLate && !done {
+			s.mu.Lock()
+			s.markLateLocked(res, late)
+			s.mu.Unlock()
+		}
+	}()
+
+	rows, err := s.DB.Query(`SELECT service_id, ts, status, latency_ms FROM checks
+WHERE ts >= ? AND ts < ? ORDER BY service_id, ts`,
+		start.Format(time.RFC3339), end.Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	type key struct {
+		sid    int
+		bucket time.Time
+	}
+	groups := make(map[key][]StatusResult)
+	for rows.Next() {
+		var r StatusResult
+		if err := rows.Scan(&r.ID, &r.CheckedAt, &r.Status, &r.ResponseMs); err != nil {
+			rows.Close()
+			return err
+		}
+		t, err := time.Parse(time.RFC3339, r.CheckedAt)
+		if err != nil {
+			continue
+		}
+		k := key{r.ID, t.Truncate(step)}
+		groups[k] = append(groups[k], r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO rollups
+(service_id, resolution, bucket, count, failures, min_ms, avg_ms, max_ms, p95_ms, sketch)
+VALUES(?,?,?,?,?,?,?,?,?,?)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for k, samples := range groups {
+		r := aggregate(k.sid, res, k.bucket, samples)
+		if _, err := stmt.Exec(r.ServiceID, string(r.Resolution), r.Bucket.Format(time.RFC3339),
+			r.Count, r.Failures, r.MinMs, r.AvgMs, r.MaxMs, r.P95Ms, encodeSketch(r.Sketch)); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if end.After(s.rolledUntil[res]) {
+		s.rolledUntil[res] = end
+	}
+	s.mu.Unlock()
+	done = true
+	return nil
+}
+
+// markLateLocked notes a check at t in a bucket of res that was already
+// rolled up, e.g. one timestamped when a slow probe started, so the next
+// run recomputes from there. s.mu must be held.
+func (s *SQLStore) markLateLocked(res Resolution, t time.Time) {
+	if f, ok := s.lateFrom[res]; !ok || t.Before(f) {
+		s.lateFrom[res] = t
+	}
+}
+
+// rollupStart is where the next run of a tier should begin: after the last
+// bucket rolled in this process, else after the newest stored bucket, else
+// at the oldest raw check.
+func (s *SQLStore) rollupStart(res Resolution) (time.Time, error) {
+	s.mu.Lock()
+	t, ok := s.rolledUntil[res]
+	s.mu.Unlock()
+	if ok {
+		return t, nil
+	}
+
+	var last sql.NullString
+	if err := s.DB.QueryRow(`SELECT MAX(bucket) FROM rollups WHERE resolution = ?`, string(res)).Scan(&last); err != nil {
+		return time.Time{}, err
+	}
+	if last.Valid {
+		b, err := time.Parse(time.RFC3339, last.String)
+		if err != nil {
+			return time.Time{}, err
+		}
+		// recompute the newest bucket in case it was written mid-flight
+		return b, nil
+	}
+
+	var first sql.NullString
+	if err := s.DB.QueryRow(`SELECT MIN(ts) FROM checks`).Scan(&first); err != nil {
+		return time.Time{}, err
+	}
+	if !first.Valid {
+		return time.Time{}, nil
+	}
+	f, err := time.Parse(time.RFC3339, first.String)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return f.Truncate(res.Step()), nil
+}
+
+func aggregate(sid int, res Resolution, bucket time.Time, samples []StatusResult) Rollup {
+	r := Rollup{ServiceID: sid, Resolution: res, Bucket: bucket, Count: len(samples), Sketch: NewLatencySketch()}
+	lat := make([]int, 0, len(samples))
+	sum := 0
+	for _, v := range samples {
+		if v.Status != "OK" {
+			r.Failures++
+			continue
+		}
+		lat = append(lat, v.ResponseMs)
+		sum += v.ResponseMs
+		r.Sketch.Add(v.ResponseMs)
+	}
+	if len(lat) == 0 {
+		return r
+	}
+	sort.Ints(lat)
+	r.MinMs = lat[0]
+	r.MaxMs = lat[len(lat)-1]
+	r.AvgMs = float64(sum) / float64(len(lat))
+	r.P95Ms = percentile(lat, 95)
+	return r
+}
+
+// percentile uses nearest-rank on an already sorted slice.
+func percentile(sorted []int, p float64) int {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted))+0.999999) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// pickResolution returns the finest tier that still holds data for the
+// window start and isn't too fine for the window width.
+func (s *SQLStore) pickResolution(start, end, now time.Time) Resolution {
+	s.mu.Lock()
+	p := s.retention
+	s.mu.Unlock()
+	for _, res := range []Resolution{ResRaw, ResMinute, ResHour} {
+		covers := !start.Before(now.Add(-p.Keep(res)))
+		if covers && end.Sub(start) <= res.maxWindow() {
+			return res
+		}
+	}
+	return ResDay
+}
+
+// WindowStats aggregates a service's checks in [start, end), reading
+// rollups of the picked tier for the buckets that lie wholly inside the
+// window and raw checks for the partial buckets at either edge and the
+// part not rolled up yet. Once raw checks of the head are pruned, its
+// whole bucket is counted instead, as nothing finer is left. Bounds are
+// compared as UTC text, so they are formatted in UTC whatever zone the
+// caller uses.
+func (s *SQLStore) WindowStats(id int, start, end time.Time) (CheckStats, error) {
+	start, end = start.UTC(), end.UTC()
+	now := time.Now().UTC()
+	res := s.pickResolution(start, end, now)
+	st := newCheckStats(res)
+	if res == ResRaw {
+		return st, s.addRawStats(&st, id, start, end)
+	}
+
+	s.mu.Lock()
+	until := s.rolledUntil[res]
+	rawKeep := s.retention.Keep(ResRaw)
+	s.mu.Unlock()
+
+	step := res.Step()
+	head := start.Truncate(step)
+	if head.Before(start) && !start.Before(now.Add(-rawKeep)) {
+		head = head.Add(step)
+	}
+	tail := minTime(until, end).Truncate(step)
+	if !head.Before(tail) {
+		return st, s.addRawStats(&st, id, start, end)
+	}
+	if head.After(start) {
+		if err := s.addRawStats(&st, id, start, head); err != nil {
+			return st, err
+		}
+	}
+
+	rows, err := s.DB.Query(`SELECT count, failures, avg_ms, sketch
+FROM rollups WHERE service_id = ? AND resolution = ? AND bucket >= ? AND bucket < ?`,
+		id, string(res), head.Format(time.RFC3339), tail.Format(time.RFC3339))
+	if err != nil {
+		return st, err
+	}
+	for rows.Next() {
+		var cnt, fails int
+		var avg float64
+		var sk sql.NullString
+		if err := rows.Scan(&cnt, &fails, &avg, &sk); err != nil {
+			rows.Close()
+			return st, err
+		}
+		st.Checks += cnt
+		st.Failures += fails
+		st.SumOKMs += avg * float64(cnt-fails)
+		st.Latency.Merge(decodeSketch(sk.String))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return st, err
+	}
+	return st, s.addRawStats(&st, id, tail, end)
+}
+
+// addRawStats adds a service's raw checks in [from, to) to st.
+func (s *SQLStore) addRawStats(st *CheckStats, id int, from, to time.Time) error {
+	if !from.Before(to) {
+		return nil
+	}
+	rows, err := s.DB.Query(`SELECT status, latency_ms FROM checks
+WHERE service_id = ? AND ts >= ? AND ts < ?`,
+		id, from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var ms int
+		if err := rows.Scan(&status, &ms); err != nil {
+			return err
+		}
+		st.add(status, ms)
+	}
+	return rows.Err()
+}
+
+func (c *CheckStats) add(status string, ms int) {
+	c.Checks++
+	if status != "OK" {
+		c.Failures++
+		return
+	}
+	c.SumOKMs += float64(ms)
+	c.Latency.Add(ms)
+}
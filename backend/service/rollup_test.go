@@ -0,0 +1,231 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openTestSQL returns a migrated SQLite store in a temporary directory.
+func openTestSQL(t *testing.T) *SQLStore {
+	t.Helper()
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "sw.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.DB.Close() })
+	if err := db.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func insertCheck(t *testing.T, db *SQLStore, id int, at time.Time, status string, ms int) {
+	t.Helper()
+	err := db.InsertCheck(StatusResult{ID: id, CheckedAt: at.UTC().Format(time.RFC3339), Status: status, ResponseMs: ms})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRollupAggregatesBuckets(t *testing.T) {
+	db := openTestSQL(t)
+	b := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	insertCheck(t, db, 1, b.Add(5*time.Second), "OK", 100)
+	insertCheck(t, db, 1, b.Add(15*time.Second), "FAIL", 0)
+	insertCheck(t, db, 1, b.Add(25*time.Second), "OK", 300)
+	insertCheck(t, db, 1, b.Add(65*time.Second), "OK", 50)
+	insertCheck(t, db, 2, b.Add(5*time.Second), "FAIL", 0)
+
+	db.rollupAll(b.Add(2 * time.Minute))
+
+	var count, failures, minMs, maxMs int
+	var avg float64
+	err := db.DB.QueryRow(`SELECT count, failures, min_ms, avg_ms, max_ms FROM rollups
+WHERE service_id = 1 AND resolution = '1m' AND bucket = ?`, b.Format(time.RFC3339)).
+		Scan(&count, &failures, &minMs, &avg, &maxMs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 || failures != 1 || minMs != 100 || avg != 200 || maxMs != 300 {
+		t.Errorf("1m bucket = count %d, failures %d, min %d, avg %v, max %d", count, failures, minMs, avg, maxMs)
+	}
+
+	// the hour isn't over yet, so only the minute tier has rolled
+	var n int
+	db.DB.QueryRow(`SELECT COUNT(*) FROM rollups WHERE resolution = '1m'`).Scan(&n)
+	if n != 3 {
+		t.Errorf("got %d minute buckets, want 3", n)
+	}
+	db.DB.QueryRow(`SELECT COUNT(*) FROM rollups WHERE resolution != '1m'`).Scan(&n)
+	if n != 0 {
+		t.Errorf("got %d coarser buckets before they completed", n)
+	}
+	if got := db.rolledUntil[ResMinute]; !got.Equal(b.Add(2 * time.Minute)) {
+		t.Errorf("rolledUntil = %v", got)
+	}
+}
+
+func TestRollupStartResumesFromStoredBuckets(t *testing.T) {
+	db := openTestSQL(t)
+	b := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if start, err := db.rollupStart(ResMinute); err != nil || !start.IsZero() {
+		t.Fatalf("empty db: start = %v, %v", start, err)
+	}
+	insertCheck(t, db, 1, b.Add(90*time.Second), "OK", 10)
+	if start, _ := db.rollupStart(ResMinute); !start.Equal(b.Add(time.Minute)) {
+		t.Errorf("from raw: start = %v", start)
+	}
+	if start, _ := db.rollupStart(ResHour); !start.Equal(b) {
+		t.Errorf("hour tier from raw: start = %v", start)
+	}
+
+	db.rollupAll(b.Add(5 * time.Minute))
+	restarted := &SQLStore{DB: db.DB, retention: DefaultRetention(), rolledUntil: map[Resolution]time.Time{}}
+	if start, _ := restarted.rollupStart(ResMinute); !start.Equal(b.Add(time.Minute)) {
+		t.Errorf("after restart: start = %v, want the newest stored bucket", start)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	lat := []int{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+	for _, c := range []struct {
+		p    float64
+		want int
+	}{{50, 50}, {90, 90}, {95, 100}, {99, 100}, {100, 100}, {0, 10}} {
+		if got := percentile(lat, c.p); got != c.want {
+			t.Errorf("p%v = %d, want %d", c.p, got, c.want)
+		}
+	}
+	if got := percentile(nil, 95); got != 0 {
+		t.Errorf("empty p95 = %d", got)
+	}
+}
+
+func TestPickResolution(t *testing.T) {
+	db := openTestSQL(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	for _, c := range []struct {
+		start, end time.Time
+		want       Resolution
+	}{
+		{now.Add(-time.Hour), now, ResRaw},
+		{now.Add(-day), now, ResRaw},
+		{now.Add(-2 * day), now, ResMinute},
+		{now.Add(-8 * day), now.Add(-7*day + time.Hour), ResMinute}, // raw is gone, but the window is narrow
+		{now.Add(-30 * day), now, ResHour},
+		{now.Add(-200 * day), now, ResDay},
+		{now.Add(-400 * day), now.Add(-399 * day), ResDay},
+	} {
+		if got := db.pickResolution(c.start, c.end, now); got != c.want {
+			t.Errorf("[%v, %v) = %s, want %s", now.Sub(c.start), now.Sub(c.end), got, c.want)
+		}
+	}
+}
+
+func TestWindowStatsCombinesRollupsAndRaw(t *testing.T) {
+	db := openTestSQL(t)
+	now := time.Now().UTC().Truncate(time.Minute)
+	old := now.Add(-30 * time.Hour)
+	insertCheck(t, db, 1, old.Add(10*time.Second), "OK", 100)
+	insertCheck(t, db, 1, old.Add(20*time.Second), "FAIL", 0)
+	insertCheck(t, db, 1, old.Add(70*time.Second), "OK", 300)
+	insertCheck(t, db, 1, now.Add(-30*time.Second), "OK", 200)
+	insertCheck(t, db, 2, old.Add(10*time.Second), "FAIL", 0)
+
+	// the newest minute isn't rolled up yet and is read from raw checks
+	db.rollup(ResMinute, now.Add(-time.Minute))
+
+	st, err := db.WindowStats(1, now.Add(-48*time.Hour), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Resolution != ResMinute || st.Checks != 4 || st.Failures != 1 || st.AvgOKMs() != 200 {
+		t.Errorf("stats = %+v", st)
+	}
+
+	// a narrow window is answered from raw checks alone
+	st, _ = db.WindowStats(1, now.Add(-time.Hour), now)
+	if st.Resolution != ResRaw || st.Checks != 1 || st.Failures != 0 {
+		t.Errorf("raw stats = %+v", st)
+	}
+}
+
+func TestWindowStatsPartialBuckets(t *testing.T) {
+	db := openTestSQL(t)
+	now := time.Now().UTC().Truncate(time.Minute)
+	start := now.Add(-30*time.Hour - 30*time.Second)
+	end := now.Add(-2*time.Hour + 30*time.Second)
+	// outside the window, in the buckets it starts and ends in
+	insertCheck(t, db, 1, start.Add(-10*time.Second), "FAIL", 0)
+	insertCheck(t, db, 1, end.Add(10*time.Second), "FAIL", 0)
+	// inside it, in the same buckets and one whole bucket
+	insertCheck(t, db, 1, start.Add(10*time.Second), "OK", 100)
+	insertCheck(t, db, 1, end.Add(-10*time.Second), "OK", 300)
+	insertCheck(t, db, 1, now.Add(-10*time.Hour), "OK", 200)
+	db.rollup(ResMinute, now)
+
+	st, err := db.WindowStats(1, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Resolution != ResMinute || st.Checks != 3 || st.Failures != 0 || st.AvgOKMs() != 200 {
+		t.Errorf("stats = %+v, want the 3 OK checks inside the window", st)
+	}
+}
+
+func TestRollupPicksUpLateChecks(t *testing.T) {
+	db := openTestSQL(t)
+	b := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	insertCheck(t, db, 1, b.Add(10*time.Second), "OK", 100)
+	db.rollupAll(b.Add(2 * time.Minute))
+
+	// a slow check stamped when it started lands in a rolled bucket
+	insertCheck(t, db, 1, b.Add(50*time.Second), "FAIL", 0)
+	if got := db.lateFrom[ResMinute]; !got.Equal(b.Add(50 * time.Second)) {
+		t.Fatalf("lateFrom = %v", got)
+	}
+	db.rollupAll(b.Add(3 * time.Minute))
+
+	var count, failures int
+	err := db.DB.QueryRow(`SELECT count, failures FROM rollups
+WHERE service_id = 1 AND resolution = '1m' AND bucket = ?`, b.Format(time.RFC3339)).Scan(&count, &failures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || failures != 1 {
+		t.Errorf("re-rolled bucket = count %d, failures %d, want 2 and 1", count, failures)
+	}
+	if _, ok := db.lateFrom[ResMinute]; ok {
+		t.Error("late mark kept after the re-roll")
+	}
+	if got := db.rolledUntil[ResMinute]; !got.Equal(b.Add(3 * time.Minute)) {
+		t.Errorf("rolledUntil = %v", got)
+	}
+}
+
+func TestWindowStatsBoundsInUTC(t *testing.T) {
+	db := openTestSQL(t)
+	berlin := time.FixedZone("CET", 3600)
+	at := time.Now().UTC().Add(-2 * time.Hour).Truncate(time.Minute)
+	insertCheck(t, db, 1, at, "OK", 10)
+
+	st, err := db.WindowStats(1, at.In(berlin), at.Add(time.Minute).In(berlin))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Checks != 1 {
+		t.Errorf("window given in CET counted %d checks, want 1", st.Checks)
+	}
+}
+
+func TestRetentionNormalize(t *testing.T) {
+	p := RetentionPolicy{RawDays: 1}.normalize()
+	if p.RawDays != 2 || p.MinuteDays != 30 || p.HourDays != 365 || p.DayDays != 5*365 {
+		t.Errorf("normalize = %+v", p)
+	}
+	if got := p.Keep(ResHour); got != 365*24*time.Hour {
+		t.Errorf("Keep(1h) = %v", got)
+	}
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"fmt"
+	"serverwatcher/notify"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AllChannels in a rule's channel list stands for every active channel.
+const AllChannels = "*"
+
+// RouteRule sends matching events to a set of channels. Every matcher that
+// is set must match; an empty matcher matches anything. Rules are checked
+// in order: a matching rule stops the walk unless Continue is set.
+type RouteRule struct {
+	Name       string             `json:"name"`
+	Tags       []string           `json:"tags,omitempty"`       // any of these service tags
+	ServiceIDs []int              `json:"serviceIds,omitempty"` // any of these services
+	Severities []notify.Severity  `json:"severities,omitempty"`
+	EventTypes []notify.EventType `json:"eventTypes,omitempty"`
+	Hours      *RouteHours        `json:"hours,omitempty"`
+	Channels   []string           `json:"channels"` // channel names, "oncall:<team>" or "*"; empty drops the event
+	Continue   bool               `json:"continue,omitempty"`
+}
+
+// RouteHours matches events by local time of day, e.g. business hours,
+// or everything outside them with Outside set. End before Start wraps
+// past midnight.
+type RouteHours struct {
+	Timezone string   `json:"timezone,omitempty"` // IANA name, default UTC
+	Days     []string `json:"days,omitempty"`     // "mon".."sun"; empty = every day
+	Start    string   `json:"start"`              // "09:00"
+	End      string   `json:"end"`                // "17:30"
+	Outside  bool     `json:"outside,omitempty"`
+}
+
+// RouteDecision is where one event goes.
+type RouteDecision struct {
+	MatchedRules []string `json:"matchedRules"`
+	Channels     []string `json:"channels"`
+	Default      bool     `json:"default"` // no rule matched: every channel
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func parseClock(v string) (int, error) {
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (HH:MM)", v)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (h *RouteHours) validate() error {
+	if _, err := time.LoadLocation(h.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q", h.Timezone)
+	}
+	for _, d := range h.Days {
+		if _, ok := weekdays[strings.ToLower(d)]; !ok {
+			return fmt.Errorf("invalid day %q (mon..sun)", d)
+		}
+	}
+	if _, err := parseClock(h.Start); err != nil {
+		return err
+	}
+	_, err := parseClock(h.End)
+	return err
+}
+
+func (h *RouteHours) matches(t time.Time) bool {
+	loc, err := time.LoadLocation(h.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	start, _ := parseClock(h.Start)
+	end, _ := parseClock(h.End)
+	min := t.Hour()*60 + t.Minute()
+
+	// a window wrapping midnight belongs to the day it starts on
+	day := t.Weekday()
+	var in bool
+	if start <= end {
+		in = min >= start && min < end
+	} else {
+		in = min >= start || min < end
+		if min < end {
+			day = (day + 6) % 7
+		}
+	}
+	if in && len(h.Days) > 0 {
+		in = false
+		for _, d := range h.Days {
+			if weekdays[strings.ToLower(d)] == day {
+				in = true
+				break
+			}
+		}
+	}
+	return in != h.Outside
+}
+
+func (r *RouteRule) matches(e notify.Event) bool {
+	if len(r.Tags) > 0 && !anyTag(r.Tags, e.Tags) {
+		return false
+	}
+	if len(r.ServiceIDs) > 0 && !slices.Contains(r.ServiceIDs, e.ServiceID) {
+		return false
+	}
+	if len(r.Severities) > 0 && !slices.Contains(r.Severities, e.Severity) {
+		return false
+	}
+	if len(r.EventTypes) > 0 && !slices.Contains(r.EventTypes, e.Type) {
+		return false
+	}
+	if r.Hours != nil && !r.Hours.matches(e.OccurredAt) {
+		return false
+	}
+	return true
+}
+
+// SetServiceTags replaces a service's tags, e.g. "team:payments", that
+// routing rules, silences, escalation policies and grouping match on.
+func (s *Store) SetServiceTags(id int, tags []string) error {
+	var clean []string
+	for _, t := range tags {
+		if t = strings.TrimSpace(t); t != "" && !slices.Contains(clean, t) {
+			clean = append(clean, t)
+		}
+	}
+	s.Lock()
+	defer s.Unlock()
+	svc, ok := s.services[id]
+	if !ok {
+		return fmt.Errorf("service not found")
+	}
+	svc.Tags = clean
+	return s.saveLocked()
+}
+
+func anyTag(want, have []string) bool {
+	for _, w := range want {
+		if slices.Contains(have, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRoutes returns the routing rules in evaluation order.
+func (s *Store) GetRoutes() []RouteRule {
+	s.Lock()
+	defer s.Unlock()
+	return append([]RouteRule{}, s.routes...)
+}
+
+// SetRoutes validates and replaces all routing rules.
+func (s *Store) SetRoutes(rules []RouteRule) error {
+	s.Lock()
+	defer s.Unlock()
+	for i := range rules {
+		r := &rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		// a typo would make a rule that silently never matches
+		for _, sev := range r.Severities {
+			if !sev.Valid() {
+				return fmt.Errorf("%s: unknown severity %q", r.Name, sev)
+			}
+		}
+		for _, t := range r.EventTypes {
+			if !t.Valid() {
+				return fmt.Errorf("%s: unknown event type %q", r.Name, t)
+			}
+		}
+		if r.Hours != nil {
+			if err := r.Hours.validate(); err != nil {
+				return fmt.Errorf("%s: %v", r.Name, err)
+			}
+		}
+		for _, c := range r.Channels {
+			if c == AllChannels {
+				continue
+			}
+			if err := s.validateTargetLocked(c); err != nil {
+				return fmt.Errorf("%s: %v", r.Name, err)
+			}
+		}
+	}
+	s.routes = rules
+	return s.saveLocked()
+}
+
+// Route decides which channels an event goes to.
+func (s *Store) Route(e notify.Event) RouteDecision {
+	s.Lock()
+	defer s.Unlock()
+	return s.routeLocked(e)
+}
+
+func (s *Store) routeLocked(e notify.Event) RouteDecision {
+	d := RouteDecision{MatchedRules: []string{}, Channels: []string{}}
+	seen := map[string]bool{}
+	add := func(name string) {
+		if _, ok := s.channels[name]; ok && !seen[name] {
+			seen[name] = true
+			d.Channels = append(d.Channels, name)
+		}
+	}
+	for i := range s.routes {
+		r := &s.routes[i]
+		if !r.matches(e) {
+			continue
+		}
+		d.MatchedRules = append(d.MatchedRules, r.Name)
+		for _, c := range r.Channels {
+			if c == AllChannels {
+				for _, name := range s.channelOrder {
+					add(name)
+				}
+				continue
+			}
+			for _, name := range s.targetChannelsLocked(c, e.OccurredAt) {
+				add(name)
+			}
+		}
+		if !r.Continue {
+			return d
+		}
+	}
+	if len(d.MatchedRules) == 0 {
+		d.Default = true
+		for _, name := range s.channelOrder {
+			add(name)
+		}
+	}
+	return d
+}
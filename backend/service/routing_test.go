@@ -0,0 +1,176 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"serverwatcher/notify"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRouteHoursMatches(t *testing.T) {
+	// 2026-03-02 is a Monday
+	at := func(day, h, m int) time.Time { return time.Date(2026, 3, day, h, m, 0, 0, time.UTC) }
+	business := &RouteHours{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:30"}
+	night := &RouteHours{Days: []string{"fri"}, Start: "22:00", End: "06:00"}
+	for _, c := range []struct {
+		name string
+		h    *RouteHours
+		t    time.Time
+		want bool
+	}{
+		{"business: Monday morning", business, at(2, 9, 0), true},
+		{"business: end is exclusive", business, at(2, 17, 30), false},
+		{"business: before start", business, at(2, 8, 59), false},
+		{"business: Saturday", business, at(7, 12, 0), false},
+		{"night: Friday evening", night, at(6, 23, 0), true},
+		{"night: early Saturday belongs to Friday", night, at(7, 5, 59), true},
+		{"night: Saturday at 6 is over", night, at(7, 6, 0), false},
+		{"night: early Friday belongs to Thursday", night, at(6, 3, 0), false},
+		{"night: Saturday evening", night, at(7, 23, 0), false},
+		{"outside business: Saturday", &RouteHours{Days: business.Days, Start: "09:00", End: "17:30", Outside: true}, at(7, 12, 0), true},
+		{"outside business: Monday noon", &RouteHours{Days: business.Days, Start: "09:00", End: "17:30", Outside: true}, at(2, 12, 0), false},
+		// 08:30 UTC is 09:30 in Berlin (CET)
+		{"timezone", &RouteHours{Timezone: "Europe/Berlin", Start: "09:00", End: "10:00"}, at(2, 8, 30), true},
+		{"every day", &RouteHours{Start: "00:00", End: "23:59"}, at(8, 12, 0), true},
+	} {
+		if got := c.h.matches(c.t); got != c.want {
+			t.Errorf("%s: matches(%s) = %v", c.name, c.t.Format("Mon 15:04"), got)
+		}
+	}
+}
+
+func TestRouteHoursValidate(t *testing.T) {
+	for _, h := range []RouteHours{
+		{Start: "9am", End: "17:00"},
+		{Start: "09:00", End: "25:00"},
+		{Start: "09:00", End: "17:00", Days: []string{"monday"}},
+		{Start: "09:00", End: "17:00", Timezone: "Mars/Olympus"},
+	} {
+		if err := h.validate(); err == nil {
+			t.Errorf("%+v accepted", h)
+		}
+	}
+	if err := (&RouteHours{Timezone: "America/New_York", Days: []string{"SAT"}, Start: "22:00", End: "06:00"}).validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+// routingStore has channels "ops", "pager" and "email" and no rules.
+func routingStore(t *testing.T) *Store {
+	t.Helper()
+	s := NewStore()
+	var chs []notify.Channel
+	for _, name := range []string{"ops", "pager", "email"} {
+		chs = append(chs, notify.Channel{Name: name, Notifier: notify.Webhook{URL: "http://127.0.0.1:1"}})
+	}
+	s.SetNotifiers(chs)
+	return s
+}
+
+func TestRoute(t *testing.T) {
+	s := routingStore(t)
+	err := s.SetRoutes([]RouteRule{
+		{Name: "payments pages", Tags: []string{"team:payments"}, Severities: []notify.Severity{notify.SeverityCritical},
+			Channels: []string{"pager"}, Continue: true},
+		{Name: "payments", Tags: []string{"team:payments"}, Channels: []string{"ops", "pager"}},
+		{Name: "service 7 muted", ServiceIDs: []int{7}},
+		{Name: "recoveries", EventTypes: []notify.EventType{notify.EventUp}, Channels: []string{AllChannels}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := func(id int, tags []string, sev notify.Severity, typ notify.EventType) notify.Event {
+		return notify.Event{ServiceID: id, Tags: tags, Severity: sev, Type: typ}
+	}
+	payments := []string{"team:payments"}
+	for _, c := range []struct {
+		name string
+		e    notify.Event
+		want RouteDecision
+	}{
+		{"critical: continue into the next rule, deduped", ev(1, payments, notify.SeverityCritical, notify.EventDown),
+			RouteDecision{MatchedRules: []string{"payments pages", "payments"}, Channels: []string{"pager", "ops"}}},
+		{"warning skips the paging rule", ev(1, payments, notify.SeverityWarning, notify.EventDown),
+			RouteDecision{MatchedRules: []string{"payments"}, Channels: []string{"ops", "pager"}}},
+		{"no channels drops the event", ev(7, nil, notify.SeverityCritical, notify.EventDown),
+			RouteDecision{MatchedRules: []string{"service 7 muted"}, Channels: []string{}}},
+		{"* is every channel", ev(2, nil, notify.SeverityInfo, notify.EventUp),
+			RouteDecision{MatchedRules: []string{"recoveries"}, Channels: []string{"ops", "pager", "email"}}},
+		{"no rule matches: every channel", ev(2, nil, notify.SeverityCritical, notify.EventDown),
+			RouteDecision{MatchedRules: []string{}, Channels: []string{"ops", "pager", "email"}, Default: true}},
+	} {
+		if got := s.Route(c.e); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %+v, want %+v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSetRoutesValidates(t *testing.T) {
+	s := routingStore(t)
+	for _, c := range []struct {
+		rule RouteRule
+		want string
+	}{
+		{RouteRule{Channels: []string{"sms"}}, `rule 1: unknown channel "sms"`},
+		{RouteRule{Name: "typo", Severities: []notify.Severity{"critcal"}}, `typo: unknown severity "critcal"`},
+		{RouteRule{Name: "lowercase", EventTypes: []notify.EventType{"down"}}, `lowercase: unknown event type "down"`},
+		{RouteRule{Name: "hours", Hours: &RouteHours{Start: "9", End: "17:00"}}, `hours: invalid time "9"`},
+	} {
+		err := s.SetRoutes([]RouteRule{c.rule})
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("err = %v, want %q", err, c.want)
+		}
+	}
+	if len(s.GetRoutes()) != 0 {
+		t.Error("invalid rules were stored")
+	}
+}
+
+func TestChannelInUseByRoutes(t *testing.T) {
+	s := routingStore(t)
+	c, err := s.CreateChannel(NotificationChannel{Name: "hooks", Type: "webhook", Settings: map[string]string{"url": "http://127.0.0.1:1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetRoutes([]RouteRule{{Name: "all to hooks", Channels: []string{"hooks"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.DeleteChannel(c.ID); !errors.Is(err, ErrChannelInUse) || !strings.Contains(err.Error(), `"all to hooks"`) {
+		t.Errorf("delete: err = %v", err)
+	}
+	renamed := NotificationChannel{Name: "webhooks", Type: "webhook", Settings: map[string]string{"url": "http://127.0.0.1:1"}}
+	if _, err := s.UpdateChannel(c.ID, renamed); !errors.Is(err, ErrChannelInUse) {
+		t.Errorf("rename: err = %v", err)
+	}
+	// settings can still change under the same name
+	if _, err := s.UpdateChannel(c.ID, NotificationChannel{Name: "hooks", Type: "webhook", Settings: map[string]string{"url": "http://127.0.0.1:2"}}); err != nil {
+		t.Errorf("update in place: %v", err)
+	}
+
+	if err := s.SetRoutes(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteChannel(c.ID); err != nil {
+		t.Errorf("delete after the route is gone: %v", err)
+	}
+}
+
+func TestSetServiceTags(t *testing.T) {
+	s := NewStore()
+	svc := addTestService(s, "api", "https://api.example.com")
+	if err := s.SetServiceTags(svc.ID, []string{" team:api ", "env:prod", "", "team:api"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"team:api", "env:prod"}; !reflect.DeepEqual(svc.Tags, want) {
+		t.Errorf("tags = %q, want %q", svc.Tags, want)
+	}
+	if err := s.SetServiceTags(svc.ID, nil); err != nil || svc.Tags != nil {
+		t.Errorf("clearing: tags %q, %v", svc.Tags, err)
+	}
+	if err := s.SetServiceTags(99, nil); err == nil {
+		t.Error("unknown service accepted")
+	}
+}
@@ -3,8 +3,10 @@ package service
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"serverwatcher/notify"
+	"strings"
 	"sync"
 	"time"
 )
@@ -18,7 +20,8 @@ type Service struct {
 	Name     string        `json:"name"`
 	URL      string        `json:"url"`
 	Interval time.Duration `json:"interval"` // check interval in seconds
-	Active   bool          `json:"active"`
+	Active   bool          `json:"active"`   // false while paused
+	Pauses   []Pause       `json:"pauses,omitempty"`
 
 	TimeoutMs      int `json:"timeoutMs"`      // default 2500
 	Retries        int `json:"retries"`        // default 1
@@ -28,10 +31,21 @@ type Service struct {
 	ExpectedStatus int    `json:"expectedStatus"`     // default 200
 	Contains       string `json:"contains,omitempty"` // optional substring in body
 
-	// SLO (used later)
+	// SLO
 	SLOTargetPercent float64  `json:"sloTargetPercent,omitempty"` // e.g., 99.9
+	SLIKind          string   `json:"sliKind,omitempty"`          // "time" (default) or "events"
+	LatencySLOMs     int      `json:"latencySloMs,omitempty"`     // e.g., 800: a check is "fast" at or under this
+	LatencySLOTarget float64  `json:"latencySloTarget,omitempty"` // e.g., 99: percent of OK checks that must be fast
 	Public           bool     `json:"public,omitempty"`           // show on status page
 	Tags             []string `json:"tags,omitempty"`             // team/env
+	Severity         string   `json:"severity,omitempty"`         // SEV1..SEV4 of its automatic incidents; derived when empty
+}
+
+// Pause is a time the service wasn't checked; End is nil while paused.
+// Paused time doesn't count against the SLO.
+type Pause struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
 }
 
 // StatusResult represents the latest status of a monitored service
@@ -41,7 +55,8 @@ type StatusResult struct {
 	URL        string `json:"url"`
 	Status     string `json:"status"` // "OK" or "FAIL"
 	ResponseMs int    `json:"responseMs"`
-	CheckedAt  string `json:"checkedAt"` // RFC3339
+	CheckedAt  string `json:"checkedAt"`        // RFC3339
+	Reason     string `json:"reason,omitempty"` // why the last attempt failed
 }
 
 type Incident struct {
@@ -49,7 +64,42 @@ type Incident struct {
 	ServiceID int        `json:"serviceId"`
 	StartedAt time.Time  `json:"startedAt"`
 	EndedAt   *time.Time `json:"endedAt,omitempty"`
-	DurationS int        `json:"durationS"` // filled when closed
+	DurationS int        `json:"durationS"`          // filled when closed
+	Reason    string     `json:"reason,omitempty"`   // failure reason of the check that opened it
+	Severity  string     `json:"severity,omitempty"` // SEV1 (worst) .. SEV4
+
+	// manually declared incidents; Group is a service tag, set instead of
+	// ServiceID when the incident covers several services
+	Manual     bool   `json:"manual,omitempty"`
+	Group      string `json:"group,omitempty"`
+	Title      string `json:"title,omitempty"`
+	Impact     string `json:"impact,omitempty"`
+	DeclaredBy string `json:"declaredBy,omitempty"`
+
+	Alerted bool `json:"alerted,omitempty"` // a DOWN went out, so the UP must follow
+
+	Reminders      int        `json:"reminders,omitempty"` // "still down" reminders sent
+	LastReminderAt *time.Time `json:"lastReminderAt,omitempty"`
+
+	Escalations     int        `json:"escalations,omitempty"` // escalation steps fired so far
+	LastEscalatedAt *time.Time `json:"lastEscalatedAt,omitempty"`
+
+	// channels that got a DOWN for this incident; its UP and ACK go there
+	// too, so pages opened outside routing get closed
+	AlertedChannels []string `json:"alertedChannels,omitempty"`
+
+	AcknowledgedAt *time.Time     `json:"acknowledgedAt,omitempty"`
+	AcknowledgedBy string         `json:"acknowledgedBy,omitempty"`
+	Assignee       string         `json:"assignee,omitempty"`
+	Notes          []IncidentNote `json:"notes,omitempty"`
+
+	Postmortem *Postmortem `json:"postmortem,omitempty"`
+}
+
+type IncidentNote struct {
+	At     time.Time `json:"at"`
+	Author string    `json:"author,omitempty"`
+	Text   string    `json:"text"`
 }
 
 type Analytics struct {
@@ -59,9 +109,14 @@ type Analytics struct {
 	Checks        int     `json:"checks"`
 	UptimePercent float64 `json:"uptimePercent"`
 	AvgResponseMs int     `json:"avgResponseMs"`
+	P50Ms         int     `json:"p50Ms"` // latency percentiles of OK checks (±2%)
+	P90Ms         int     `json:"p90Ms"`
+	P95Ms         int     `json:"p95Ms"`
+	P99Ms         int     `json:"p99Ms"`
 	FailCount     int     `json:"failCount"`
 	IncidentCount int     `json:"incidentCount"`
 	MTTRSeconds   int     `json:"mttrSeconds"`
+	Resolution    string  `json:"resolution"` // history tier the sample stats came from
 }
 
 // / / --- STORE --- /
@@ -79,8 +134,24 @@ type Store struct {
 	nextIncidentID int
 	stopChans      map[int]chan struct{}
 
-	notifiers          []notify.Notifier
+	channels           map[string]notify.Channel // active, by name
+	channelOrder       []string
+	envChannels        []notify.Channel
+	channelConfigs     []*NotificationChannel
+	nextChannelID      int
+	routes             []RouteRule
+	batches            map[string]*eventBatch // grouping window, by channel/group/type
+	digest             DigestConfig
+	escalations        []*EscalationPolicy
+	nextEscalationID   int
+	schedules          []*Schedule
+	nextScheduleID     int
+	deliveryWake       chan struct{}
+	deliveryBusy       map[string]bool    // channels with a running delivery worker
+	deliveryLog        []DeliveryLogEntry // attempts without SQLite, oldest first
+	nextDeliveryLogID  int64
 	lastNotifiedStatus map[int]string
+	dashboardURL       string // base URL linked from alerts
 
 	failStreak  map[int]int
 	okStreak    map[int]int
@@ -92,7 +163,10 @@ type Store struct {
 	silences      []*Silence
 	nextSilenceID int
 
-	
+	burnAlerts      []*BurnAlert
+	nextBurnAlertID int
+
+	db *SQLStore // long-term check history; nil keeps everything in memory
 }
 
 type storeData struct {
@@ -107,16 +181,42 @@ type storeData struct {
 
 	// (We intentionally DO NOT persist streaks/cooldowns; they’re runtime-only)
 	Policy IncidentPolicy `json:"policy"`
+
+	Silences      []*Silence `json:"silences"`
+	NextSilenceID int        `json:"nextSilenceId"`
+
+	BurnAlerts      []*BurnAlert `json:"burnAlerts"`
+	NextBurnAlertID int          `json:"nextBurnAlertId"`
+
+	Channels      []*NotificationChannel `json:"channels"`
+	NextChannelID int                    `json:"nextChannelId"`
+
+	Routes []RouteRule  `json:"routes"`
+	Digest DigestConfig `json:"digest"`
+
+	Escalations      []*EscalationPolicy `json:"escalations"`
+	NextEscalationID int                 `json:"nextEscalationId"`
+
+	Schedules      []*Schedule `json:"schedules"`
+	NextScheduleID int         `json:"nextScheduleId"`
 }
 
 func NewStore() *Store {
 	s := &Store{
-		nextID: 1, nextIncidentID: 1,
+		nextID: 1, nextIncidentID: 1, nextBurnAlertID: 1,
 	}
 	s.ensureMaps()
 	return s
 }
 
+// SetSQLStore makes the store record every check in SQLite and answer
+// analytics from its raw checks and rollups.
+func (s *Store) SetSQLStore(db *SQLStore) {
+	s.Lock()
+	defer s.Unlock()
+	s.db = db
+}
+
 func (s *Store) SetPolicy(p IncidentPolicy) {
 	s.Lock()
 	defer s.Unlock()
@@ -148,6 +248,9 @@ func (s *Store) ensureMaps() {
 	if s.lastNotifiedStatus == nil {
 		s.lastNotifiedStatus = make(map[int]string)
 	}
+	if s.deliveryBusy == nil {
+		s.deliveryBusy = make(map[string]bool)
+	}
 
 	if s.failStreak == nil {
 		s.failStreak = make(map[int]int)
@@ -167,6 +270,12 @@ func (s *Store) ensureMaps() {
 func (s *Store) SaveToFile() error {
 	s.Lock()
 	defer s.Unlock()
+	return s.saveLocked()
+}
+
+// saveLocked writes the store to a temp file and renames it into place,
+// so readers never see a half-written file. Caller must hold the lock.
+func (s *Store) saveLocked() error {
 	data := storeData{
 		Services:       s.services,
 		Histories:      s.histories,
@@ -176,13 +285,37 @@ func (s *Store) SaveToFile() error {
 		NextID:         s.nextID,
 		NextIncidentID: s.nextIncidentID,
 		Policy:         s.policy,
+		Silences:       s.silences,
+		NextSilenceID:  s.nextSilenceID,
+
+		BurnAlerts:      s.burnAlerts,
+		NextBurnAlertID: s.nextBurnAlertID,
+
+		Channels:      s.channelConfigs,
+		NextChannelID: s.nextChannelID,
+
+		Routes: s.routes,
+		Digest: s.digest,
+
+		Escalations:      s.escalations,
+		NextEscalationID: s.nextEscalationID,
+
+		Schedules:      s.schedules,
+		NextScheduleID: s.nextScheduleID,
 	}
-	f, err := os.Create(persistenceFile)
+	tmp := persistenceFile + ".tmp"
+	f, err := os.Create(tmp)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	return json.NewEncoder(f).Encode(&data)
+	if err := json.NewEncoder(f).Encode(&data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, persistenceFile)
 }
 
 func (s *Store) LoadFromFile() error {
@@ -207,21 +340,39 @@ func (s *Store) LoadFromFile() error {
 	s.nextID = data.NextID
 	s.nextIncidentID = data.NextIncidentID
 	s.policy = data.Policy
-	s.policy = data.Policy
+	s.silences = data.Silences
+	s.nextSilenceID = data.NextSilenceID
+	s.burnAlerts = data.BurnAlerts
+	s.nextBurnAlertID = data.NextBurnAlertID
+	s.channelConfigs = data.Channels
+	s.nextChannelID = data.NextChannelID
+	s.routes = data.Routes
+	s.digest = data.Digest
+	s.escalations = data.Escalations
+	s.nextEscalationID = data.NextEscalationID
+	s.schedules = data.Schedules
+	s.nextScheduleID = data.NextScheduleID
+	s.applyChannelsLocked()
+	if s.nextBurnAlertID <= 0 {
+		s.nextBurnAlertID = 1
+	}
 	if s.policy == (IncidentPolicy{}) {
 		s.policy = defaultPolicy()
 	}
 	s.ensureMaps()
 
-	// rebuild openIncident: last incident with nil EndedAt
+	// rebuild openIncident: last check incident with nil EndedAt
+	// (declared incidents are closed by hand, not by checks)
 	s.openIncident = make(map[int]*Incident)
 	for sid, incs := range s.Incidents {
-		if len(incs) == 0 {
-			continue
-		}
-		last := incs[len(incs)-1]
-		if last.EndedAt == nil {
-			s.openIncident[sid] = last
+		for i := len(incs) - 1; i >= 0; i-- {
+			if incs[i].Manual {
+				continue
+			}
+			if incs[i].EndedAt == nil {
+				s.openIncident[sid] = incs[i]
+			}
+			break
 		}
 	}
 	return nil
@@ -285,6 +436,10 @@ func (s *Store) UpdateService(id int, name, url string, interval int, timeoutMs,
 	// restart checker with new config
 	if stopChan, ok := s.stopChans[id]; ok {
 		close(stopChan)
+		delete(s.stopChans, id)
+	}
+	if !svc.Active {
+		return nil // picked up on resume
 	}
 	stopChan := make(chan struct{})
 	s.stopChans[id] = stopChan
@@ -307,31 +462,23 @@ func (s *Store) GetIncidents(id int) ([]*Incident, bool) {
 
 // time-weighted analytics
 func (s *Store) ComputeAnalytics(id int, hours int) Analytics {
+	windowEnd := time.Now().UTC()
+	return s.AnalyticsBetween(id, windowEnd.Add(-time.Duration(hours)*time.Hour), windowEnd)
+}
+
+// AnalyticsBetween is ComputeAnalytics over [windowStart, windowEnd).
+func (s *Store) AnalyticsBetween(id int, windowStart, windowEnd time.Time) Analytics {
 	s.Lock()
 	hist := s.histories[id]
 	incs := s.Incidents[id]
 	s.Unlock()
 
-	windowEnd := time.Now().UTC()
-	windowStart := windowEnd.Add(-time.Duration(hours) * time.Hour)
 	windowDur := windowEnd.Sub(windowStart).Seconds()
 	if windowDur <= 0 {
 		windowDur = 1
 	}
 
-	downSeconds := 0.0
-	for _, inc := range incs {
-		incStart := inc.StartedAt
-		incEnd := windowEnd
-		if inc.EndedAt != nil {
-			incEnd = *inc.EndedAt
-		}
-		start := maxTime(incStart, windowStart)
-		end := minTime(incEnd, windowEnd)
-		if end.After(start) {
-			downSeconds += end.Sub(start).Seconds()
-		}
-	}
+	downSeconds := totalSpans(incidentSpans(incs, span{windowStart, windowEnd})).Seconds()
 	uptimePercent := 100.0 * (1.0 - (downSeconds / windowDur))
 	if uptimePercent < 0 {
 		uptimePercent = 0
@@ -341,35 +488,12 @@ func (s *Store) ComputeAnalytics(id int, hours int) Analytics {
 	}
 
 	// sample-based avg/failed count
-	filtered := make([]StatusResult, 0, len(hist))
-	for _, v := range hist {
-		t, _ := time.Parse(time.RFC3339, v.CheckedAt)
-		if t.After(windowStart) {
-			filtered = append(filtered, v)
-		}
-	}
-	checks := len(filtered)
-	avgMs := 0
-	failCount := 0
-	if checks > 0 {
-		sumMs, okCnt := 0, 0
-		for _, v := range filtered {
-			if v.Status == "OK" {
-				okCnt++
-				sumMs += v.ResponseMs
-			} else {
-				failCount++
-			}
-		}
-		if okCnt > 0 {
-			avgMs = sumMs / okCnt
-		}
-	}
+	stats := s.sampleStats(id, hist, windowStart, windowEnd)
 
 	// MTTR & count
 	mttrs, mttrCount := 0, 0
 	for _, inc := range incs {
-		if inc.EndedAt != nil && inc.EndedAt.After(windowStart) {
+		if inc.EndedAt != nil && inc.EndedAt.After(windowStart) && inc.EndedAt.Before(windowEnd) {
 			mttrs += inc.DurationS
 			mttrCount++
 		}
@@ -383,22 +507,84 @@ func (s *Store) ComputeAnalytics(id int, hours int) Analytics {
 		ServiceID:     id,
 		WindowStart:   windowStart.Format(time.RFC3339),
 		WindowEnd:     windowEnd.Format(time.RFC3339),
-		Checks:        checks,
+		Checks:        stats.Checks,
 		UptimePercent: uptimePercent,
-		AvgResponseMs: avgMs,
-		FailCount:     failCount,
+		AvgResponseMs: stats.AvgOKMs(),
+		P50Ms:         stats.Latency.Quantile(0.50),
+		P90Ms:         stats.Latency.Quantile(0.90),
+		P95Ms:         stats.Latency.Quantile(0.95),
+		P99Ms:         stats.Latency.Quantile(0.99),
+		FailCount:     stats.Failures,
 		IncidentCount: mttrCount,
 		MTTRSeconds:   mttr,
+		Resolution:    string(stats.Resolution),
+	}
+}
+
+// sampleStats aggregates checks in the window from SQLite when available,
+// falling back to the bounded in-memory history.
+func (s *Store) sampleStats(id int, hist []StatusResult, windowStart, windowEnd time.Time) CheckStats {
+	s.Lock()
+	db := s.db
+	s.Unlock()
+	if db != nil {
+		st, err := db.WindowStats(id, windowStart, windowEnd)
+		if err == nil {
+			return st
+		}
+		log.Printf("analytics: window stats for service %d: %v", id, err)
+	}
+
+	st := newCheckStats("memory")
+	for _, v := range hist {
+		t, _ := time.Parse(time.RFC3339, v.CheckedAt)
+		if !t.After(windowStart) || !t.Before(windowEnd) {
+			continue
+		}
+		st.add(v.Status, v.ResponseMs)
 	}
+	return st
 }
 
-func (s *Store) SetNotifiers(n []notify.Notifier) { s.notifiers = n }
-func (s *Store) broadcast(title, text string) {
-	for _, n := range s.notifiers {
-		_ = n.Notify(title, text) // ignore errors for now
+// recordCheck appends a raw sample to SQLite, if configured.
+func (s *Store) recordCheck(r StatusResult) {
+	s.Lock()
+	db := s.db
+	s.Unlock()
+	if db == nil {
+		return
+	}
+	if err := db.InsertCheck(r); err != nil {
+		log.Printf("record check for service %d: %v", r.ID, err)
 	}
 }
 
+// SetDashboardURL sets the base URL alerts link back to.
+func (s *Store) SetDashboardURL(u string) {
+	s.Lock()
+	defer s.Unlock()
+	s.dashboardURL = strings.TrimRight(u, "/")
+}
+
+// newEventLocked fills the service part of an alert event.
+// Caller must hold the lock.
+func (s *Store) newEventLocked(t notify.EventType, sev notify.Severity, svc *Service, now time.Time) notify.Event {
+	e := notify.Event{
+		Type:        t,
+		Severity:    sev,
+		ServiceID:   svc.ID,
+		ServiceName: svc.Name,
+		ServiceURL:  svc.URL,
+		Tags:        append([]string(nil), svc.Tags...),
+		StartedAt:   now,
+		OccurredAt:  now,
+	}
+	if s.dashboardURL != "" {
+		e.DashboardURL = fmt.Sprintf("%s/?service=%d", s.dashboardURL, svc.ID)
+	}
+	return e
+}
+
 func maxTime(a, b time.Time) time.Time {
 	if a.After(b) {
 		return a
@@ -419,6 +605,18 @@ func (s *Store) HasService(id int) bool {
 	return ok
 }
 
+// GetService returns a copy of the service, or nil if it doesn't exist.
+func (s *Store) GetService(id int) *Service {
+	s.Lock()
+	defer s.Unlock()
+	svc, ok := s.services[id]
+	if !ok {
+		return nil
+	}
+	c := *svc
+	return &c
+}
+
 // Always returns a slice (possibly empty)
 func (s *Store) GetIncidentsOrEmpty(id int) []*Incident {
 	s.Lock()
@@ -441,36 +639,3 @@ func (s *Store) GetServiceSLOTarget(id int) float64 {
 	}
 	return 99.9
 }
-
-// todo: This is a simple estimator. If you want exact downtime,
-// todo: compute from incident durations within the window instead.
-func (s *Store) EstimateDowntimeSeconds(id, hours int) float64 {
-	// simple: count FAIL samples within window * median interval
-	s.Lock()
-	h := s.histories[id]
-	s.Unlock()
-	if len(h) == 0 {
-		return 0
-	}
-	cut := time.Now().Add(-time.Duration(hours) * time.Hour)
-	fail := 0
-	var intervals []int
-	for _, e := range h {
-		t, _ := time.Parse(time.RFC3339, e.CheckedAt)
-		if t.Before(cut) {
-			continue
-		}
-		if e.Status != "OK" {
-			fail++
-		}
-		intervals = append(intervals, e.ResponseMs) // wrong metric; but we don't store interval per sample
-	}
-	// use configured service interval instead
-	sec := 0.0
-	s.Lock()
-	if svc, ok := s.services[id]; ok && svc.Interval > 0 {
-		sec = float64(int(svc.Interval / time.Second))
-	}
-	s.Unlock()
-	return float64(fail) * sec
-}
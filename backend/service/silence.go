@@ -9,24 +9,37 @@ type Silence struct {
 	Until     time.Time `json:"until"`               // when silence ends
 	Reason    string    `json:"reason,omitempty"`
 	CreatedAt time.Time `json:"createdAt"`
+	Deleted   bool      `json:"deleted,omitempty"` // ended early; kept for past SLO windows
 }
 
 func (s *Store) IsSilenced(svc *Service) bool {
 	s.Lock()
 	defer s.Unlock()
+	return s.isSilencedLocked(svc)
+}
+
+func (s *Store) isSilencedLocked(svc *Service) bool {
 	now := time.Now()
 	for _, sil := range s.silences {
 		if now.After(sil.Until) {
 			continue
 		}
-		if sil.ServiceID != nil && *sil.ServiceID == svc.ID {
+		if sil.appliesTo(svc) {
 			return true
 		}
-		if sil.Tag != "" {
-			for _, t := range svc.Tags {
-				if t == sil.Tag {
-					return true
-				}
+	}
+	return false
+}
+
+// appliesTo reports whether the silence targets svc, by ID or by tag.
+func (sil *Silence) appliesTo(svc *Service) bool {
+	if sil.ServiceID != nil && *sil.ServiceID == svc.ID {
+		return true
+	}
+	if sil.Tag != "" {
+		for _, t := range svc.Tags {
+			if t == sil.Tag {
+				return true
 			}
 		}
 	}
@@ -39,23 +52,33 @@ func (s *Store) NewSilence(sid *int, tag string, until time.Time, reason string)
 	s.nextSilenceID++
 	sl := &Silence{ID: s.nextSilenceID, ServiceID: sid, Tag: tag, Until: until, Reason: reason, CreatedAt: time.Now()}
 	s.silences = append(s.silences, sl)
-	_ = s.SaveToFile()
+	_ = s.saveLocked()
 	return sl
 }
 func (s *Store) ListSilences() []*Silence {
 	s.Lock()
 	defer s.Unlock()
-	out := make([]*Silence, len(s.silences))
-	copy(out, s.silences)
+	out := make([]*Silence, 0, len(s.silences))
+	for _, x := range s.silences {
+		if !x.Deleted {
+			out = append(out, x)
+		}
+	}
 	return out
 }
+
+// DeleteSilence ends a silence now. It stays stored, hidden, so SLO windows
+// that it covered still exclude that maintenance.
 func (s *Store) DeleteSilence(id int) bool {
 	s.Lock()
 	defer s.Unlock()
-	for i, x := range s.silences {
-		if x.ID == id {
-			s.silences = append(s.silences[:i], s.silences[i+1:]...)
-			_ = s.SaveToFile()
+	for _, x := range s.silences {
+		if x.ID == id && !x.Deleted {
+			x.Deleted = true
+			if now := time.Now(); x.Until.After(now) {
+				x.Until = now
+			}
+			_ = s.saveLocked()
 			return true
 		}
 	}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+)
+
+// sketchAlpha is the relative accuracy of quantiles read from a sketch.
+const sketchAlpha = 0.02
+
+var sketchGamma = (1 + sketchAlpha) / (1 - sketchAlpha)
+var sketchLogGamma = math.Log(sketchGamma)
+
+// LatencySketch is a mergeable histogram with logarithmic buckets
+// (DDSketch-style): any quantile it returns is within ±2% of the true
+// value, and sketches of rollup buckets add up into the sketch of the
+// whole window.
+type LatencySketch struct {
+	Bins  map[int]uint64 `json:"b,omitempty"` // bucket index -> count
+	Zeros uint64         `json:"z,omitempty"` // values <= 0ms
+	N     uint64         `json:"n"`
+}
+
+func NewLatencySketch() *LatencySketch {
+	return &LatencySketch{Bins: make(map[int]uint64)}
+}
+
+func sketchIndex(ms int) int {
+	return int(math.Ceil(math.Log(float64(ms)) / sketchLogGamma))
+}
+
+// sketchValue is the representative value of a bucket.
+func sketchValue(i int) float64 {
+	return 2 * math.Pow(sketchGamma, float64(i)) / (sketchGamma + 1)
+}
+
+func (k *LatencySketch) Add(ms int) {
+	k.N++
+	if ms <= 0 {
+		k.Zeros++
+		return
+	}
+	if k.Bins == nil {
+		k.Bins = make(map[int]uint64)
+	}
+	k.Bins[sketchIndex(ms)]++
+}
+
+func (k *LatencySketch) Merge(o *LatencySketch) {
+	if o == nil {
+		return
+	}
+	if k.Bins == nil {
+		k.Bins = make(map[int]uint64)
+	}
+	for i, c := range o.Bins {
+		k.Bins[i] += c
+	}
+	k.Zeros += o.Zeros
+	k.N += o.N
+}
+
+// Quantile returns the q-th quantile (0..1) in ms, 0 for an empty sketch.
+func (k *LatencySketch) Quantile(q float64) int {
+	if k == nil || k.N == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(k.N)))
+	if rank == 0 {
+		rank = 1
+	}
+	seen := k.Zeros
+	if seen >= rank {
+		return 0
+	}
+	for _, i := range k.sortedBins() {
+		seen += k.Bins[i]
+		if seen >= rank {
+			return int(math.Round(sketchValue(i)))
+		}
+	}
+	return 0
+}
+
+// CountAtMost estimates how many values were <= ms. The bucket holding
+// ms is counted whole, so values up to ~4% above ms may be included.
+func (k *LatencySketch) CountAtMost(ms int) uint64 {
+	if k == nil {
+		return 0
+	}
+	n := k.Zeros
+	if ms <= 0 {
+		return n
+	}
+	limit := sketchIndex(ms)
+	for i, c := range k.Bins {
+		if i <= limit {
+			n += c
+		}
+	}
+	return n
+}
+
+func (k *LatencySketch) sortedBins() []int {
+	idx := make([]int, 0, len(k.Bins))
+	for i := range k.Bins {
+		idx = append(idx, i)
+	}
+	sort.Ints(idx)
+	return idx
+}
+
+// encodeSketch / decodeSketch store sketches in SQLite as JSON text.
+func encodeSketch(k *LatencySketch) string {
+	if k == nil {
+		return ""
+	}
+	b, _ := json.Marshal(k)
+	return string(b)
+}
+
+func decodeSketch(v string) *LatencySketch {
+	if v == "" {
+		return nil
+	}
+	var k LatencySketch
+	if err := json.Unmarshal([]byte(v), &k); err != nil {
+		return nil
+	}
+	return &k
+}
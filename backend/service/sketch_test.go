@@ -0,0 +1,129 @@
+package service
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSketchQuantilesWithinAccuracy(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	k := NewLatencySketch()
+	vals := make([]int, 5000)
+	for i := range vals {
+		// long-tailed, like real latencies
+		vals[i] = 20 + int(rng.ExpFloat64()*150)
+		k.Add(vals[i])
+	}
+	sort.Ints(vals)
+	for _, q := range []float64{0.5, 0.9, 0.95, 0.99, 1} {
+		exact := float64(percentile(vals, q*100))
+		got := float64(k.Quantile(q))
+		// ±2% relative, plus rounding to whole milliseconds
+		if math.Abs(got-exact) > exact*sketchAlpha+1 {
+			t.Errorf("q%v = %v, exact %v", q, got, exact)
+		}
+	}
+}
+
+func TestSketchMerge(t *testing.T) {
+	whole, a, b := NewLatencySketch(), NewLatencySketch(), &LatencySketch{}
+	for ms := 0; ms < 1000; ms += 7 {
+		whole.Add(ms)
+		if ms%2 == 0 {
+			a.Add(ms)
+		} else {
+			b.Add(ms) // nil Bins are allocated on first use
+		}
+	}
+	a.Merge(b)
+	a.Merge(nil)
+	for _, q := range []float64{0.1, 0.5, 0.99} {
+		if a.Quantile(q) != whole.Quantile(q) {
+			t.Errorf("q%v: merged %d, whole %d", q, a.Quantile(q), whole.Quantile(q))
+		}
+	}
+	if a.N != whole.N || a.Zeros != 1 {
+		t.Errorf("merged N %d (want %d), zeros %d", a.N, whole.N, a.Zeros)
+	}
+}
+
+func TestSketchEdgeCases(t *testing.T) {
+	var nilSketch *LatencySketch
+	if nilSketch.Quantile(0.5) != 0 || nilSketch.CountAtMost(100) != 0 {
+		t.Error("nil sketch isn't empty")
+	}
+	k := NewLatencySketch()
+	if k.Quantile(0.99) != 0 {
+		t.Error("empty sketch has a p99")
+	}
+	k.Add(0)
+	k.Add(0)
+	k.Add(100)
+	if k.Quantile(0) != 0 || k.Quantile(0.5) != 0 || k.Quantile(1) < 98 || k.Quantile(1) > 102 {
+		t.Errorf("quantiles = %d %d %d", k.Quantile(0), k.Quantile(0.5), k.Quantile(1))
+	}
+	for ms, want := range map[int]uint64{-1: 2, 0: 2, 50: 2, 100: 3, 1000: 3} {
+		if got := k.CountAtMost(ms); got != want {
+			t.Errorf("CountAtMost(%d) = %d, want %d", ms, got, want)
+		}
+	}
+}
+
+func TestSketchEncoding(t *testing.T) {
+	k := NewLatencySketch()
+	k.Add(0)
+	k.Add(120)
+	k.Add(480)
+	got := decodeSketch(encodeSketch(k))
+	if got == nil || got.N != 3 || got.Zeros != 1 || got.Quantile(1) != k.Quantile(1) {
+		t.Errorf("round trip = %+v", got)
+	}
+	if encodeSketch(nil) != "" || decodeSketch("") != nil || decodeSketch("{") != nil {
+		t.Error("empty or broken sketches aren't nil")
+	}
+}
+
+func TestLatencySLO(t *testing.T) {
+	s := NewStore()
+	db := openTestSQL(t)
+	s.SetSQLStore(db)
+	svc := addTestService(s, "api", "https://api.example.com")
+	ms, target := 500, 90.0
+	if err := s.UpdateServiceSLO(svc.ID, SLOUpdate{LatencyMs: &ms, LatencyTarget: &target}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC().Truncate(time.Minute)
+	for i := 1; i <= 10; i++ {
+		ms := 100
+		if i <= 2 {
+			ms = 900
+		}
+		insertCheck(t, db, svc.ID, now.Add(-time.Duration(i)*time.Minute), "OK", ms)
+	}
+	insertCheck(t, db, svc.ID, now.Add(-11*time.Minute), "FAIL", 0) // failures aren't latency events
+
+	r := s.ComputeSLO(svc.ID, 1, "").Latency
+	if r == nil {
+		t.Fatal("no latency report")
+	}
+	if r.TotalEvents != 10 || r.GoodEvents != 8 || r.SLIPercent != 80 || !r.Breached {
+		t.Errorf("report = %+v", r)
+	}
+	if math.Abs(r.BurnRate-2) > 1e-9 || math.Abs(r.BudgetRemaining+100) > 1e-9 {
+		t.Errorf("burn %v, budget %v", r.BurnRate, r.BudgetRemaining)
+	}
+
+	a := s.ComputeAnalytics(svc.ID, 1)
+	if a.P50Ms < 98 || a.P50Ms > 102 || a.P99Ms < 882 || a.P99Ms > 918 {
+		t.Errorf("p50 %d, p99 %d", a.P50Ms, a.P99Ms)
+	}
+
+	off := 0
+	s.UpdateServiceSLO(svc.ID, SLOUpdate{LatencyMs: &off})
+	if r := s.ComputeSLO(svc.ID, 1, "").Latency; r != nil {
+		t.Errorf("latency report without a latency SLO: %+v", r)
+	}
+}
@@ -0,0 +1,340 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SLIKind selects how an SLO is measured.
+//
+//	time:   share of the window the service was not in an incident
+//	events: share of checks that were OK
+type SLIKind string
+
+const (
+	SLITime   SLIKind = "time"
+	SLIEvents SLIKind = "events"
+)
+
+func ParseSLIKind(v string) (SLIKind, bool) {
+	switch SLIKind(v) {
+	case SLITime, SLIEvents:
+		return SLIKind(v), true
+	}
+	return "", false
+}
+
+// SLOReport is the SLO state of a service over a trailing window.
+// Maintenance (silence windows covering the service, and pauses) is
+// excluded from both the window and the downtime/events.
+type SLOReport struct {
+	ServiceID   int       `json:"serviceId"`
+	SLI         SLIKind   `json:"sli"`
+	Target      float64   `json:"target"`
+	WindowHours int       `json:"windowHours"`
+	Analytics   Analytics `json:"analytics"`
+
+	EligibleSec    int `json:"eligibleSec"`    // window minus maintenance
+	MaintenanceSec int `json:"maintenanceSec"` // excluded time
+
+	// time-based SLI
+	AllowedDowntimeSec  int `json:"allowedDowntimeSec"`
+	ObservedDowntimeSec int `json:"observedDowntimeSec"`
+
+	// event-based SLI
+	TotalEvents   int `json:"totalEvents"`
+	GoodEvents    int `json:"goodEvents"`
+	AllowedBadEvt int `json:"allowedBadEvents"`
+
+	SLIPercent      float64 `json:"sliPercent"`
+	BudgetRemaining float64 `json:"budgetRemainingPercent"` // may go negative once exhausted
+	BurnRate        float64 `json:"burnRate"`               // 1.0 == consuming budget at expected pace
+	Breached        bool    `json:"breached"`
+
+	Latency *LatencySLOReport `json:"latency,omitempty"` // only with a latency SLO configured
+}
+
+// LatencySLOReport is the state of a latency SLO such as "99% of checks
+// under 800ms". Only OK checks count; failures burn the availability SLO.
+// Counts come from latency sketches, so checks within ~4% above the
+// threshold may be counted as fast.
+type LatencySLOReport struct {
+	ThresholdMs     int     `json:"thresholdMs"`
+	Target          float64 `json:"target"`
+	TotalEvents     int     `json:"totalEvents"`
+	GoodEvents      int     `json:"goodEvents"`
+	SLIPercent      float64 `json:"sliPercent"`
+	BudgetRemaining float64 `json:"budgetRemainingPercent"`
+	BurnRate        float64 `json:"burnRate"`
+	Breached        bool    `json:"breached"`
+}
+
+// span is a half-open time interval [start, end).
+type span struct{ start, end time.Time }
+
+func (p span) dur() time.Duration { return p.end.Sub(p.start) }
+
+// mergeSpans sorts spans and joins the overlapping ones.
+func mergeSpans(in []span) []span {
+	if len(in) == 0 {
+		return nil
+	}
+	sort.Slice(in, func(i, j int) bool { return in[i].start.Before(in[j].start) })
+	out := []span{in[0]}
+	for _, p := range in[1:] {
+		last := &out[len(out)-1]
+		if !p.start.After(last.end) {
+			last.end = maxTime(last.end, p.end)
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// subtractSpans returns the parts of a not covered by b; both must be merged.
+func subtractSpans(a, b []span) []span {
+	var out []span
+	for _, p := range a {
+		cur := p.start
+		for _, q := range b {
+			if !q.end.After(cur) || !q.start.Before(p.end) {
+				continue
+			}
+			if q.start.After(cur) {
+				out = append(out, span{cur, q.start})
+			}
+			cur = maxTime(cur, q.end)
+		}
+		if cur.Before(p.end) {
+			out = append(out, span{cur, p.end})
+		}
+	}
+	return out
+}
+
+func totalSpans(in []span) time.Duration {
+	var d time.Duration
+	for _, p := range in {
+		d += p.dur()
+	}
+	return d
+}
+
+// clip limits spans to the window and drops empty ones.
+func clip(in []span, w span) []span {
+	var out []span
+	for _, p := range in {
+		p.start = maxTime(p.start, w.start)
+		p.end = minTime(p.end, w.end)
+		if p.end.After(p.start) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// incidentSpans returns incident time within the window, merged.
+// Open incidents run until the window end.
+func incidentSpans(incs []*Incident, w span) []span {
+	out := make([]span, 0, len(incs))
+	for _, inc := range incs {
+		if inc.Manual {
+			continue // downtime is what the checks saw
+		}
+		end := w.end
+		if inc.EndedAt != nil {
+			end = *inc.EndedAt
+		}
+		out = append(out, span{inc.StartedAt, end})
+	}
+	return mergeSpans(clip(out, w))
+}
+
+// maintenanceSpansLocked returns silence windows and pauses covering the
+// service. Caller must hold the lock.
+func (s *Store) maintenanceSpansLocked(svc *Service, w span) []span {
+	var out []span
+	for _, sl := range s.silences {
+		if sl.appliesTo(svc) {
+			out = append(out, span{sl.CreatedAt, sl.Until})
+		}
+	}
+	for _, p := range svc.Pauses {
+		end := w.end
+		if p.End != nil {
+			end = *p.End
+		}
+		out = append(out, span{p.Start, end})
+	}
+	return mergeSpans(clip(out, w))
+}
+
+// ComputeSLO evaluates a service's SLO over the last `hours`, using the
+// service's SLI kind unless kind is set.
+func (s *Store) ComputeSLO(id, hours int, kind SLIKind) SLOReport {
+	a := s.ComputeAnalytics(id, hours)
+	end := time.Now().UTC()
+	r := s.sloOver(id, span{end.Add(-time.Duration(hours) * time.Hour), end}, kind)
+	r.WindowHours = hours
+	r.Analytics = a
+	r.Latency = s.latencySLOOver(id, span{end.Add(-time.Duration(hours) * time.Hour), end})
+	return r
+}
+
+// latencySLOOver evaluates the service's latency SLO over a window,
+// excluding maintenance. Returns nil if none is configured.
+func (s *Store) latencySLOOver(id int, w span) *LatencySLOReport {
+	s.Lock()
+	svc, ok := s.services[id]
+	if !ok || svc.LatencySLOMs <= 0 || svc.LatencySLOTarget <= 0 {
+		s.Unlock()
+		return nil
+	}
+	r := &LatencySLOReport{ThresholdMs: svc.LatencySLOMs, Target: svc.LatencySLOTarget}
+	maint := s.maintenanceSpansLocked(svc, w)
+	hist := s.histories[id]
+	s.Unlock()
+
+	for _, p := range subtractSpans([]span{w}, maint) {
+		st := s.sampleStats(id, hist, p.start, p.end)
+		r.TotalEvents += int(st.Latency.N)
+		r.GoodEvents += int(st.Latency.CountAtMost(r.ThresholdMs))
+	}
+
+	r.SLIPercent = 100
+	if r.TotalEvents > 0 {
+		r.SLIPercent = 100 * float64(r.GoodEvents) / float64(r.TotalEvents)
+	}
+	r.Breached = r.SLIPercent < r.Target
+	bad := float64(r.TotalEvents - r.GoodEvents)
+	allowed := float64(r.TotalEvents) * (1 - r.Target/100)
+	if allowed > 0 {
+		r.BurnRate = bad / allowed
+		r.BudgetRemaining = 100 * (1 - bad/allowed)
+	} else if bad == 0 {
+		r.BudgetRemaining = 100
+	}
+	return r
+}
+
+// sloOver evaluates the SLO over an arbitrary window; Analytics and
+// WindowHours are left for the caller.
+func (s *Store) sloOver(id int, w span, kind SLIKind) SLOReport {
+	s.Lock()
+	target := 99.9
+	var maint []span
+	if svc, ok := s.services[id]; ok {
+		if svc.SLOTargetPercent > 0 {
+			target = svc.SLOTargetPercent
+		}
+		if kind == "" {
+			kind = SLIKind(svc.SLIKind)
+		}
+		maint = s.maintenanceSpansLocked(svc, w)
+	}
+	incs := append([]*Incident(nil), s.Incidents[id]...)
+	hist := s.histories[id]
+	s.Unlock()
+	if _, ok := ParseSLIKind(string(kind)); !ok {
+		kind = SLITime
+	}
+
+	eligible := subtractSpans([]span{w}, maint)
+	r := SLOReport{
+		ServiceID:      id,
+		SLI:            kind,
+		Target:         target,
+		EligibleSec:    int(totalSpans(eligible).Seconds()),
+		MaintenanceSec: int(totalSpans(maint).Seconds()),
+	}
+	budgetFrac := 1 - target/100.0
+
+	// time-based numbers are always reported; events only when asked for
+	down := totalSpans(subtractSpans(incidentSpans(incs, w), maint)).Seconds()
+	allowed := float64(r.EligibleSec) * budgetFrac
+	r.ObservedDowntimeSec = int(down)
+	r.AllowedDowntimeSec = int(allowed)
+
+	bad, allowedBad, sli := down, allowed, 100.0
+	if r.EligibleSec > 0 {
+		sli = 100 * (1 - down/float64(r.EligibleSec))
+	}
+
+	if kind == SLIEvents {
+		for _, p := range eligible {
+			st := s.sampleStats(id, hist, p.start, p.end)
+			r.TotalEvents += st.Checks
+			r.GoodEvents += st.Checks - st.Failures
+		}
+		bad = float64(r.TotalEvents - r.GoodEvents)
+		allowedBad = float64(r.TotalEvents) * budgetFrac
+		r.AllowedBadEvt = int(allowedBad)
+		sli = 100
+		if r.TotalEvents > 0 {
+			sli = 100 * float64(r.GoodEvents) / float64(r.TotalEvents)
+		}
+	}
+
+	r.SLIPercent = sli
+	r.Breached = sli < target
+	if allowedBad > 0 {
+		r.BurnRate = bad / allowedBad
+		r.BudgetRemaining = 100 * (1 - bad/allowedBad)
+	} else if bad == 0 {
+		r.BudgetRemaining = 100
+	}
+	return r
+}
+
+// SLOUpdate changes a service's SLO settings; nil fields keep their value.
+type SLOUpdate struct {
+	TargetPercent *float64 `json:"sloTargetPercent"` // 0 = default 99.9
+	SLIKind       *string  `json:"sliKind"`          // "time", "events" or "" for the default
+	LatencyMs     *int     `json:"latencySloMs"`     // 0 disables the latency SLO
+	LatencyTarget *float64 `json:"latencySloTarget"` // defaults to 99 when a latency SLO is set
+}
+
+// UpdateServiceSLO merges u into the service's availability and latency
+// SLO settings and validates the result.
+func (s *Store) UpdateServiceSLO(id int, u SLOUpdate) error {
+	s.Lock()
+	defer s.Unlock()
+	svc, ok := s.services[id]
+	if !ok {
+		return fmt.Errorf("service not found")
+	}
+	c := *svc
+	if u.TargetPercent != nil {
+		c.SLOTargetPercent = *u.TargetPercent
+	}
+	if u.SLIKind != nil {
+		c.SLIKind = *u.SLIKind
+		if c.SLIKind != "" {
+			if _, ok := ParseSLIKind(c.SLIKind); !ok {
+				return fmt.Errorf("invalid sliKind (time or events)")
+			}
+		}
+	}
+	if u.LatencyMs != nil {
+		c.LatencySLOMs = *u.LatencyMs
+	}
+	if u.LatencyTarget != nil {
+		c.LatencySLOTarget = *u.LatencyTarget
+	}
+	if c.SLOTargetPercent < 0 || c.SLOTargetPercent >= 100 {
+		return fmt.Errorf("sloTargetPercent must be in [0, 100)")
+	}
+	if c.LatencySLOMs < 0 || c.LatencySLOTarget < 0 || c.LatencySLOTarget >= 100 {
+		return fmt.Errorf("latencySloMs must be >= 0 and latencySloTarget in [0, 100)")
+	}
+	if c.LatencySLOMs > 0 && c.LatencySLOTarget == 0 {
+		c.LatencySLOTarget = 99
+	}
+	svc.SLOTargetPercent = c.SLOTargetPercent
+	svc.SLIKind = c.SLIKind
+	svc.LatencySLOMs = c.LatencySLOMs
+	svc.LatencySLOTarget = c.LatencySLOTarget
+	return nil
+}
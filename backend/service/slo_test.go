@@ -0,0 +1,188 @@
+package service
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// sp builds a span from minute offsets to t0.
+func sp(from, to int) span {
+	return span{t0.Add(time.Duration(from) * time.Minute), t0.Add(time.Duration(to) * time.Minute)}
+}
+
+func TestMergeSpans(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		in   []span
+		want []span
+	}{
+		{"empty", nil, nil},
+		{"disjoint, unsorted", []span{sp(20, 30), sp(0, 10)}, []span{sp(0, 10), sp(20, 30)}},
+		{"overlapping", []span{sp(0, 10), sp(5, 15)}, []span{sp(0, 15)}},
+		{"touching", []span{sp(0, 10), sp(10, 20)}, []span{sp(0, 20)}},
+		{"contained", []span{sp(0, 30), sp(5, 10), sp(20, 25)}, []span{sp(0, 30)}},
+		{"chain", []span{sp(40, 50), sp(0, 10), sp(8, 20), sp(19, 30)}, []span{sp(0, 30), sp(40, 50)}},
+	} {
+		if got := mergeSpans(c.in); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSubtractSpans(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		a, b []span
+		want []span
+	}{
+		{"nothing to subtract", []span{sp(0, 60)}, nil, []span{sp(0, 60)}},
+		{"hole in the middle", []span{sp(0, 60)}, []span{sp(10, 20)}, []span{sp(0, 10), sp(20, 60)}},
+		{"head and tail", []span{sp(0, 60)}, []span{sp(-10, 5), sp(50, 70)}, []span{sp(5, 50)}},
+		{"covered", []span{sp(0, 60)}, []span{sp(-1, 61)}, nil},
+		{"several holes", []span{sp(0, 60)}, []span{sp(10, 20), sp(30, 40)}, []span{sp(0, 10), sp(20, 30), sp(40, 60)}},
+		{"across spans", []span{sp(0, 10), sp(20, 30)}, []span{sp(5, 25)}, []span{sp(0, 5), sp(25, 30)}},
+		{"disjoint", []span{sp(0, 10)}, []span{sp(20, 30)}, []span{sp(0, 10)}},
+	} {
+		if got := subtractSpans(c.a, c.b); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestClip(t *testing.T) {
+	got := clip([]span{sp(-20, -10), sp(-5, 5), sp(10, 20), sp(55, 70), sp(60, 80)}, sp(0, 60))
+	want := []span{sp(0, 5), sp(10, 20), sp(55, 60)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestIncidentSpans(t *testing.T) {
+	end := t0.Add(20 * time.Minute)
+	incs := []*Incident{
+		{StartedAt: t0.Add(-10 * time.Minute), EndedAt: &end}, // started before the window
+		{StartedAt: t0.Add(15 * time.Minute), EndedAt: &end},  // overlaps the first
+		{StartedAt: t0.Add(50 * time.Minute)},                 // still open
+	}
+	got := incidentSpans(incs, sp(0, 60))
+	want := []span{sp(0, 20), sp(50, 60)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestComputeSLOTimeExcludesMaintenance(t *testing.T) {
+	s := NewStore()
+	svc := addTestService(s, "api", "https://api.example.com")
+	svc.SLOTargetPercent = 99
+	now := time.Now().UTC()
+	// 30 minutes down, 10 of them during a silence: 20 minutes count,
+	// over the 14 allowed by 99% of the rest of the day
+	ended := now.Add(-2 * time.Hour)
+	s.Incidents[svc.ID] = []*Incident{{ID: 1, ServiceID: svc.ID, StartedAt: ended.Add(-30 * time.Minute), EndedAt: &ended}}
+	s.silences = []*Silence{{ID: 1, ServiceID: &svc.ID, CreatedAt: ended.Add(-10 * time.Minute), Until: ended}}
+
+	r := s.ComputeSLO(svc.ID, 24, "")
+	if r.SLI != SLITime || r.Target != 99 {
+		t.Errorf("sli %s, target %v", r.SLI, r.Target)
+	}
+	if r.MaintenanceSec != 600 || r.EligibleSec != 24*3600-600 {
+		t.Errorf("maintenance %d, eligible %d", r.MaintenanceSec, r.EligibleSec)
+	}
+	if r.ObservedDowntimeSec != 1200 {
+		t.Errorf("downtime = %d, want 1200", r.ObservedDowntimeSec)
+	}
+	if want := int(float64(r.EligibleSec) * 0.01); r.AllowedDowntimeSec != want {
+		t.Errorf("allowed = %d, want %d", r.AllowedDowntimeSec, want)
+	}
+	wantBurn := 1200 / (float64(r.EligibleSec) * 0.01)
+	if math.Abs(r.BurnRate-wantBurn) > 1e-6 || !r.Breached {
+		t.Errorf("burn %v (want %v), breached %v", r.BurnRate, wantBurn, r.Breached)
+	}
+	if math.Abs(r.BudgetRemaining-100*(1-wantBurn)) > 1e-6 {
+		t.Errorf("budget remaining = %v", r.BudgetRemaining)
+	}
+}
+
+func TestComputeSLOEvents(t *testing.T) {
+	s := NewStore()
+	db := openTestSQL(t)
+	s.SetSQLStore(db)
+	svc := addTestService(s, "api", "https://api.example.com")
+	svc.SLIKind = string(SLIEvents)
+	now := time.Now().UTC().Truncate(time.Minute)
+	for i := 1; i <= 10; i++ {
+		status := "OK"
+		if i <= 2 {
+			status = "FAIL"
+		}
+		insertCheck(t, db, svc.ID, now.Add(-time.Duration(i)*time.Minute), status, 10)
+	}
+	// checks during maintenance don't count
+	s.silences = []*Silence{{ID: 1, ServiceID: &svc.ID, CreatedAt: now.Add(-90 * time.Second), Until: now.Add(-30 * time.Second)}}
+
+	r := s.ComputeSLO(svc.ID, 1, "")
+	if r.SLI != SLIEvents || r.TotalEvents != 9 || r.GoodEvents != 8 {
+		t.Fatalf("report = %+v", r)
+	}
+	if math.Abs(r.SLIPercent-100*8.0/9) > 1e-6 || !r.Breached {
+		t.Errorf("sli %v, breached %v", r.SLIPercent, r.Breached)
+	}
+
+	// the time SLI can still be asked for explicitly
+	if r := s.ComputeSLO(svc.ID, 1, SLITime); r.SLI != SLITime || r.SLIPercent != 100 || r.BudgetRemaining != 100 {
+		t.Errorf("time report = %+v", r)
+	}
+}
+
+func TestComputeSLOExcludesPausesAndEndedSilences(t *testing.T) {
+	s := NewStore()
+	svc := addTestService(s, "api", "https://api.example.com")
+	now := time.Now().UTC()
+	resumed := now.Add(-5 * time.Hour)
+	svc.Pauses = []Pause{
+		{Start: now.Add(-6 * time.Hour), End: &resumed},
+		{Start: now.Add(-30 * time.Minute)}, // still paused
+	}
+	sil := s.NewSilence(&svc.ID, "", now.Add(-time.Hour), "deploy")
+	sil.CreatedAt = now.Add(-3 * time.Hour)
+	if !s.DeleteSilence(sil.ID) || len(s.ListSilences()) != 0 {
+		t.Fatal("silence not deleted")
+	}
+
+	r := s.ComputeSLO(svc.ID, 24, "")
+	// 1h + 30m paused, and the 2h the deleted silence covered
+	if want := 3600 + 1800 + 2*3600; r.MaintenanceSec < want-1 || r.MaintenanceSec > want+1 {
+		t.Errorf("maintenance = %ds, want %ds", r.MaintenanceSec, want)
+	}
+}
+
+func TestUpdateServiceSLOMerges(t *testing.T) {
+	s := NewStore()
+	svc := addTestService(s, "api", "https://api.example.com")
+	target, events, ms := 99.5, string(SLIEvents), 300
+	if err := s.UpdateServiceSLO(svc.ID, SLOUpdate{TargetPercent: &target, SLIKind: &events}); err != nil {
+		t.Fatal(err)
+	}
+	// a latency-only update keeps the availability settings
+	if err := s.UpdateServiceSLO(svc.ID, SLOUpdate{LatencyMs: &ms}); err != nil {
+		t.Fatal(err)
+	}
+	if svc.SLOTargetPercent != 99.5 || svc.SLIKind != "events" || svc.LatencySLOMs != 300 || svc.LatencySLOTarget != 99 {
+		t.Errorf("service = %+v", svc)
+	}
+
+	bad, kind, neg := 100.0, "uptime", -1
+	for _, u := range []SLOUpdate{{TargetPercent: &bad}, {SLIKind: &kind}, {LatencyMs: &neg}, {LatencyTarget: &bad}} {
+		if err := s.UpdateServiceSLO(svc.ID, u); err == nil {
+			t.Errorf("%+v accepted", u)
+		}
+	}
+	if svc.SLOTargetPercent != 99.5 || svc.LatencySLOMs != 300 {
+		t.Errorf("rejected update changed the service: %+v", svc)
+	}
+}
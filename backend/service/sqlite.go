@@ -2,12 +2,19 @@ package service
 
 import (
 	"database/sql"
+	"sync"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type SQLStore struct {
 	DB *sql.DB
+
+	mu          sync.Mutex
+	retention   RetentionPolicy
+	rolledUntil map[Resolution]time.Time // end of the last rolled-up bucket per tier
+	lateFrom    map[Resolution]time.Time // oldest check inserted behind rolledUntil
 }
 
 func OpenSQLite(path string) (*SQLStore, error) {
@@ -15,5 +22,29 @@ func OpenSQLite(path string) (*SQLStore, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &SQLStore{DB: db}, nil
+	return &SQLStore{
+		DB:          db,
+		retention:   DefaultRetention(),
+		rolledUntil: make(map[Resolution]time.Time),
+		lateFrom:    make(map[Resolution]time.Time),
+	}, nil
+}
+
+// InsertCheck stores one raw check sample.
+func (s *SQLStore) InsertCheck(r StatusResult) error {
+	_, err := s.DB.Exec(`INSERT OR REPLACE INTO checks(service_id, ts, status, latency_ms) VALUES(?,?,?,?)`,
+		r.ID, r.CheckedAt, r.Status, r.ResponseMs)
+	if err != nil {
+		return err
+	}
+	if t, err := time.Parse(time.RFC3339, r.CheckedAt); err == nil {
+		s.mu.Lock()
+		for res, until := range s.rolledUntil {
+			if t.Before(until) {
+				s.markLateLocked(res, t)
+			}
+		}
+		s.mu.Unlock()
+	}
+	return nil
 }